@@ -5,13 +5,19 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
 	"syscall"
 
 	"golang.org/x/mod/semver"
 
 	"github.com/emenda-labs/emenda/core/cli"
 	golangdriver "github.com/emenda-labs/emenda/drivers/golang"
+	"github.com/emenda-labs/emenda/drivers/golang/astdiff"
+	"github.com/emenda-labs/emenda/drivers/golang/snapshot"
 	"github.com/emenda-labs/emenda/pkg/gomod"
+	"github.com/emenda-labs/emenda/pkg/goproxy"
+	"github.com/emenda-labs/emenda/pkg/modcache"
 )
 
 const version = "0.1.0"
@@ -20,45 +26,103 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	goDriver := golangdriver.NewDriver()
-
 	runUpgradeGo := func(ctx context.Context, opts cli.UpgradeGoOptions) error {
+		driverOpts := golangdriver.Options{
+			Strict:       opts.Strict,
+			UpstreamRepo: opts.Upstream,
+			Heuristics:   opts.Heuristics,
+			Offline:      opts.Offline,
+			Progress:     newProgressFunc(os.Stderr),
+		}
+		if len(opts.TagKeys) > 0 {
+			driverOpts.TagKeys = append(slices.Clone(astdiff.DefaultTagKeys), opts.TagKeys...)
+		}
+		if len(opts.Synonyms) > 0 {
+			driverOpts.Synonyms = append(slices.Clone(astdiff.DefaultSynonyms), opts.Synonyms...)
+		}
+		if !opts.NoModCache {
+			driverOpts.ModCache = modcache.FromEnv()
+		}
+		if !opts.NoCache {
+			cache, err := openSnapshotCache()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warning: snapshot cache disabled: %v\n", err)
+			}
+			driverOpts.Cache = cache
+		}
+		goSum, err := goproxy.ReadGoSum(filepath.Join(opts.Repo, "go.sum"))
+		if err != nil {
+			return err
+		}
+		driverOpts.GoSum = goSum
+		driverOpts.GoVersion, err = gomod.FindGoVersion(opts.Repo)
+		if err != nil {
+			return err
+		}
+		goDriver := golangdriver.NewDriverWithOptions(driverOpts)
+
 		currentVersion, err := gomod.FindModuleVersion(opts.Repo, opts.Module)
 		if err != nil {
 			return err
 		}
 
-		if currentVersion == opts.To {
-			return fmt.Errorf("module %s is already at %s", opts.Module, opts.To)
+		target, err := goDriver.ResolveVersion(ctx, opts.Module, opts.To)
+		if err != nil {
+			return err
+		}
+
+		if currentVersion == target {
+			return fmt.Errorf("module %s is already at %s", opts.Module, target)
 		}
 
-		if semver.IsValid(currentVersion) && semver.IsValid(opts.To) {
-			if semver.Compare(opts.To, currentVersion) < 0 {
-				fmt.Fprintf(os.Stderr, "warning: target version %s is older than current version %s\n", opts.To, currentVersion)
+		if semver.IsValid(currentVersion) && semver.IsValid(target) {
+			if semver.Compare(target, currentVersion) < 0 {
+				fmt.Fprintf(os.Stderr, "warning: target version %s is older than current version %s\n", target, currentVersion)
 			}
 		}
 
-		fmt.Fprintf(os.Stderr, "Downloading %s@%s...\n", opts.Module, currentVersion)
-		oldPath, oldCleanup, err := goDriver.FetchSource(ctx, opts.Module, currentVersion)
-		if err != nil {
-			return fmt.Errorf("fetching old version: %w", err)
+		fmt.Printf("Module:          %s\n", opts.Module)
+		fmt.Printf("Current version: %s\n", currentVersion)
+		if target != opts.To {
+			fmt.Printf("Target version:  %s (resolved from %s)\n", target, opts.To)
+		} else {
+			fmt.Printf("Target version:  %s\n", target)
 		}
-		defer oldCleanup()
+		fmt.Println()
 
-		fmt.Fprintf(os.Stderr, "Downloading %s@%s...\n", opts.Module, opts.To)
-		newPath, newCleanup, err := goDriver.FetchSource(ctx, opts.Module, opts.To)
+		spec, err := goDriver.ComputeVersionChanges(ctx, opts.Module, currentVersion, target)
 		if err != nil {
-			return fmt.Errorf("fetching new version: %w", err)
+			return fmt.Errorf("computing changes: %w", err)
 		}
-		defer newCleanup()
 
-		fmt.Printf("Module:          %s\n", opts.Module)
-		fmt.Printf("Current version: %s\n", currentVersion)
-		fmt.Printf("Target version:  %s\n", opts.To)
-		fmt.Printf("Old source:      %s\n", oldPath)
-		fmt.Printf("New source:      %s\n", newPath)
+		for _, d := range spec.Diagnostics {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", d)
+		}
+
+		fmt.Printf("Changes:         %d\n", len(spec.Changes))
+		for _, c := range spec.Changes {
+			line := fmt.Sprintf("  %-18s %s.%s (%s)", c.Kind, c.Package, c.Symbol, c.Confidence)
+			if c.Severity != "" {
+				line += " [" + string(c.Severity) + "]"
+			}
+			if c.LowTrust {
+				line += " [low-trust]"
+			}
+			if n := len(c.Affected); n > 0 {
+				line += fmt.Sprintf(" affects %d symbol(s)", n)
+			}
+			fmt.Println(line)
+		}
 		fmt.Println()
 
+		if len(spec.Advisories) > 0 {
+			fmt.Printf("Advisories:      %d\n", len(spec.Advisories))
+			for _, a := range spec.Advisories {
+				fmt.Printf("  %-18s %s.%s: %s\n", a.Kind, a.Package, a.Symbol, a.Detail)
+			}
+			fmt.Println()
+		}
+
 		if opts.DryRun {
 			fmt.Println("[dry-run] No changes applied.")
 		} else {
@@ -77,3 +141,12 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// openSnapshotCache opens the snapshot cache in the default location.
+func openSnapshotCache() (*snapshot.Cache, error) {
+	dir, err := snapshot.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Open(dir)
+}
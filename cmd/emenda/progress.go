@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emenda-labs/emenda/pkg/goproxy"
+)
+
+// progressInterval limits how often the progress line is redrawn.
+const progressInterval = 100 * time.Millisecond
+
+// progressLine renders concurrent zip downloads on a single terminal line.
+type progressLine struct {
+	mu       sync.Mutex
+	out      io.Writer
+	active   map[string]goproxy.Progress
+	order    []string
+	lastDraw time.Time
+}
+
+// newProgressFunc returns a progress renderer writing to out, or nil when out
+// is not a terminal.
+func newProgressFunc(out *os.File) goproxy.ProgressFunc {
+	info, err := out.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	l := &progressLine{out: out, active: make(map[string]goproxy.Progress)}
+	return l.update
+}
+
+func (l *progressLine) update(p goproxy.Progress) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := p.Module + "@" + p.Version
+	if p.Done {
+		delete(l.active, key)
+		l.order = slices.DeleteFunc(l.order, func(k string) bool { return k == key })
+	} else {
+		if _, ok := l.active[key]; !ok {
+			l.order = append(l.order, key)
+		}
+		l.active[key] = p
+		if time.Since(l.lastDraw) < progressInterval {
+			return
+		}
+	}
+	l.lastDraw = time.Now()
+
+	if len(l.order) == 0 {
+		fmt.Fprint(l.out, "\r\033[K")
+		return
+	}
+	parts := make([]string, 0, len(l.order))
+	for _, k := range l.order {
+		parts = append(parts, k+" "+formatProgress(l.active[k]))
+	}
+	fmt.Fprintf(l.out, "\r\033[Kdownloading %s", strings.Join(parts, ", "))
+}
+
+// formatProgress renders downloaded and total sizes in megabytes.
+func formatProgress(p goproxy.Progress) string {
+	const mb = 1024 * 1024
+	if p.Total < 0 {
+		return fmt.Sprintf("%.1f MB", float64(p.Downloaded)/mb)
+	}
+	return fmt.Sprintf("%.1f/%.1f MB", float64(p.Downloaded)/mb, float64(p.Total)/mb)
+}
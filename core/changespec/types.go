@@ -1,5 +1,7 @@
 package changespec
 
+import "fmt"
+
 // ChangeKind represents the type of breaking API change.
 type ChangeKind string
 
@@ -9,6 +11,8 @@ const (
 	ChangeKindRemoved          ChangeKind = "removed"
 	ChangeKindTypeChanged      ChangeKind = "type_changed"
 	ChangeKindPackageMoved     ChangeKind = "package_moved"
+	ChangeKindTagChanged       ChangeKind = "tag_changed"
+	ChangeKindParamsSwapped    ChangeKind = "params_swapped"
 )
 
 // ConfidenceLevel indicates how confident the differ is that a change was correctly classified.
@@ -20,17 +24,143 @@ const (
 	ConfidenceLow    ConfidenceLevel = "low"
 )
 
+// Severity indicates how a change breaks consumers. Changes that make consumer
+// code fail to build have no severity: the empty value is the default.
+type Severity string
+
+const (
+	// SeverityBehavioral changes still compile but alter runtime behavior,
+	// e.g. a renamed json struct tag changes every serialized payload.
+	SeverityBehavioral Severity = "behavioral"
+
+	// SeverityInfo changes do not break consumers but warrant a migration,
+	// e.g. a type moved to another package with a compatibility alias left behind.
+	SeverityInfo Severity = "info"
+
+	// SeverityWarning changes compile and may be intended, but likely make
+	// existing call sites wrong, e.g. same-typed parameters that swapped names.
+	SeverityWarning Severity = "warning"
+)
+
 // Change represents a single breaking API change between two versions.
 type Change struct {
-	Kind         ChangeKind `json:"kind"`
+	Kind ChangeKind `json:"kind"`
 	// Symbol is the changed symbol name. For methods, uses Receiver.Method format (e.g. Client.Do).
-	Symbol       string     `json:"symbol"`
-	Package      string     `json:"package"`
-	OldSignature string     `json:"old_signature,omitempty"`
-	NewSignature string     `json:"new_signature,omitempty"`
-	NewName      string     `json:"new_name,omitempty"`
-	NewPackage   string     `json:"new_package,omitempty"`
+	Symbol       string          `json:"symbol"`
+	Package      string          `json:"package"`
+	OldSignature string          `json:"old_signature,omitempty"`
+	NewSignature string          `json:"new_signature,omitempty"`
+	NewName      string          `json:"new_name,omitempty"`
+	NewPackage   string          `json:"new_package,omitempty"`
 	Confidence   ConfidenceLevel `json:"confidence"`
+	// Severity is empty for compile-breaking changes, which is the default.
+	Severity Severity `json:"severity,omitempty"`
+	// Detail is a short human-readable explanation, e.g. `json: name "user_id" -> "userId"`.
+	Detail string `json:"detail,omitempty"`
+	// OldLocation and NewLocation point at the declaration in each version.
+	OldLocation *Location `json:"old_location,omitempty"`
+	NewLocation *Location `json:"new_location,omitempty"`
+	// Doc is an excerpt of the declaration's doc comment, preferring the new version.
+	Doc string `json:"doc,omitempty"`
+	// LowTrust is set when diagnostics (parse failures, skipped directories,
+	// unsupported constructs) affect the symbol, so the change may be an artifact.
+	LowTrust bool `json:"low_trust,omitempty"`
+	// Affected lists exported symbols of the old version that reference the
+	// changed type, directly or through other module types.
+	Affected []Affected `json:"affected,omitempty"`
+}
+
+// Affected is an exported symbol impacted by a change to a type it references.
+type Affected struct {
+	Symbol  string `json:"symbol"`
+	Package string `json:"package"`
+	// Kind is the symbol kind, e.g. "func", "method" or "field".
+	Kind string `json:"kind"`
+	// Via is the chain of fully qualified type names the change propagates
+	// through, starting at the changed type and ending at the type the
+	// symbol references directly.
+	Via []string `json:"via"`
+}
+
+// Location is a source position relative to the module root.
+type Location struct {
+	// File uses forward slashes, e.g. "client/client.go".
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// String formats the location as file:line:column.
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Column)
+}
+
+// DiagnosticKind classifies a problem encountered while reading module source.
+type DiagnosticKind string
+
+const (
+	DiagnosticParseError  DiagnosticKind = "parse_error"
+	DiagnosticSkippedDir  DiagnosticKind = "skipped_dir"
+	DiagnosticUnsupported DiagnosticKind = "unsupported_construct"
+	// DiagnosticGoVersion marks a new version whose go directive is newer than
+	// the consuming module's; upgrading to it raises the consumer's go line.
+	DiagnosticGoVersion DiagnosticKind = "go_version"
+	// DiagnosticHistory marks an upstream repository whose history could not be
+	// read; renames were detected without its evidence.
+	DiagnosticHistory DiagnosticKind = "history_unavailable"
+)
+
+// Diagnostic records source that could not be fully analyzed.
+type Diagnostic struct {
+	Kind DiagnosticKind `json:"kind"`
+	// Version is the module version the diagnostic was raised for.
+	Version string `json:"version,omitempty"`
+	Package string `json:"package,omitempty"`
+	// Symbol is set when the diagnostic affects a single symbol.
+	Symbol string `json:"symbol,omitempty"`
+	// File is relative to the module root.
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// String formats the diagnostic as "kind version file:line: message".
+func (d Diagnostic) String() string {
+	loc := d.File
+	if loc == "" {
+		loc = d.Package
+	}
+	if d.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", loc, d.Line)
+	}
+	if d.Version != "" {
+		return fmt.Sprintf("%s %s %s: %s", d.Kind, d.Version, loc, d.Message)
+	}
+	return fmt.Sprintf("%s %s: %s", d.Kind, loc, d.Message)
+}
+
+// AdvisoryKind classifies a behavioral risk found by heuristics.
+type AdvisoryKind string
+
+const (
+	AdvisoryInitAdded      AdvisoryKind = "init_added"
+	AdvisoryDefaultChanged AdvisoryKind = "default_changed"
+	AdvisoryEnvReadAdded   AdvisoryKind = "env_read_added"
+	AdvisoryGoroutineAdded AdvisoryKind = "goroutine_added"
+	AdvisoryPanicAdded     AdvisoryKind = "panic_added"
+)
+
+// Advisory is a possible behavioral change found by heuristics over function
+// bodies. Advisories do not block an upgrade and are never applied; they point
+// reviewers at code worth checking.
+type Advisory struct {
+	Kind AdvisoryKind `json:"kind"`
+	// Symbol is the responsible symbol, "init" for package init functions.
+	Symbol  string `json:"symbol"`
+	Package string `json:"package"`
+	Detail  string `json:"detail"`
+	// Location points at the symbol in the new version.
+	Location *Location `json:"location,omitempty"`
 }
 
 // ChangeSpec is the full set of breaking changes between two module versions.
@@ -39,6 +169,11 @@ type ChangeSpec struct {
 	OldVersion string   `json:"old_version"`
 	NewVersion string   `json:"new_version"`
 	Changes    []Change `json:"changes"`
+	// Diagnostics lists source problems from both versions that may make
+	// individual changes unreliable.
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+	// Advisories lists behavioral risks found by the optional heuristics pass.
+	Advisories []Advisory `json:"advisories,omitempty"`
 }
 
 // ApplyResult reports which changes were successfully applied and which failed.
@@ -11,10 +11,23 @@ import (
 
 // UpgradeGoOptions holds the parsed flags for "upgrade go".
 type UpgradeGoOptions struct {
-	Module string
-	To     string
-	Repo   string
-	DryRun bool
+	Module     string
+	To         string
+	Repo       string
+	DryRun     bool
+	Strict     bool
+	NoCache    bool
+	Upstream   string
+	Heuristics bool
+	NoModCache bool
+	Offline    bool
+
+	// TagKeys holds the extra struct tag keys from --tag-keys.
+	TagKeys []string
+
+	// Synonyms holds the extra synonym groups from --synonyms, each a list of
+	// interchangeable words used when matching renamed functions.
+	Synonyms [][]string
 }
 
 // UpgradeGoRunFunc is the function signature for the upgrade go command handler.
@@ -24,12 +37,18 @@ type UpgradeGoRunFunc func(ctx context.Context, opts UpgradeGoOptions) error
 // NewUpgradeGoCmd creates the "upgrade go" subcommand.
 func NewUpgradeGoCmd(runFunc UpgradeGoRunFunc) *cobra.Command {
 	var opts UpgradeGoOptions
+	var synonyms []string
 
 	cmd := &cobra.Command{
 		Use:   "go",
 		Short: "Upgrade a Go module dependency",
 		Long:  "Upgrade a Go module to a new version and fix breaking API changes.",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
+			groups, err := parseSynonymGroups(synonyms)
+			if err != nil {
+				return err
+			}
+			opts.Synonyms = groups
 			return validateUpgradeGoFlags(opts)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -37,10 +56,18 @@ func NewUpgradeGoCmd(runFunc UpgradeGoRunFunc) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&opts.Module, "module", "", "Go module path to upgrade (required)")
-	cmd.Flags().StringVar(&opts.To, "to", "", "Target version to upgrade to (required)")
+	cmd.Flags().StringVar(&opts.Module, "module", "", "Go module path to upgrade, including modules nested in another module's repository such as example.com/foo/otel (required)")
+	cmd.Flags().StringVar(&opts.To, "to", "", "Target version or query: an exact version, latest, a v1 or v1.4 prefix, a branch or a commit (required)")
 	cmd.Flags().StringVar(&opts.Repo, "repo", "", "Path to the repository (required)")
 	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Show what would change without applying")
+	cmd.Flags().BoolVar(&opts.Strict, "strict", false, "Fail if either version has source that cannot be fully parsed")
+	cmd.Flags().StringVar(&opts.Upstream, "upstream", "", "Path to a local clone of the module's upstream repository to mine for renames")
+	cmd.Flags().BoolVar(&opts.Heuristics, "heuristics", false, "Scan function bodies for behavioral risks such as new init functions, env reads and panics")
+	cmd.Flags().StringSliceVar(&opts.TagKeys, "tag-keys", nil, "Extra struct tag keys to compare, e.g. mapstructure,toml; added to json, yaml, xml, protobuf and db")
+	cmd.Flags().StringArrayVar(&synonyms, "synonyms", nil, "Comma-separated words to treat as interchangeable when matching renamed functions, e.g. Get,Fetch; repeatable, added to the built-in groups")
+	cmd.Flags().BoolVar(&opts.NoModCache, "no-modcache", false, "Do not read module source from the Go module cache (GOMODCACHE)")
+	cmd.Flags().BoolVar(&opts.Offline, "offline", false, "Never download; every version must be in the Go module cache or the snapshot cache")
+	cmd.Flags().BoolVar(&opts.NoCache, "no-cache", false, "Ignore the snapshot cache and re-download and re-parse both versions")
 
 	cmd.MarkFlagRequired("module")
 	cmd.MarkFlagRequired("to")
@@ -56,9 +83,6 @@ func validateUpgradeGoFlags(opts UpgradeGoOptions) error {
 	if opts.To == "" {
 		return fmt.Errorf("--to is required")
 	}
-	if !strings.HasPrefix(opts.To, "v") {
-		return fmt.Errorf("--to version must start with 'v' (e.g. v2.3.0)")
-	}
 
 	info, err := os.Stat(opts.Repo)
 	if err != nil {
@@ -71,5 +95,33 @@ func validateUpgradeGoFlags(opts UpgradeGoOptions) error {
 		return fmt.Errorf("repo path is not a directory: %s", opts.Repo)
 	}
 
+	if opts.Upstream != "" {
+		info, err := os.Stat(opts.Upstream)
+		if err != nil {
+			return fmt.Errorf("cannot access upstream repository: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("upstream repository is not a directory: %s", opts.Upstream)
+		}
+	}
+
 	return nil
 }
+
+// parseSynonymGroups splits each --synonyms value into a group of words.
+func parseSynonymGroups(values []string) ([][]string, error) {
+	var groups [][]string
+	for _, v := range values {
+		var group []string
+		for _, w := range strings.Split(v, ",") {
+			if w = strings.TrimSpace(w); w != "" {
+				group = append(group, w)
+			}
+		}
+		if len(group) < 2 {
+			return nil, fmt.Errorf("--synonyms %q: a synonym group needs at least two words", v)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
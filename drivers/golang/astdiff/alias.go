@@ -0,0 +1,190 @@
+package astdiff
+
+import (
+	"go/ast"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+// maxAliasChain bounds alias resolution so alias cycles in malformed source terminate.
+const maxAliasChain = 16
+
+// fileImports maps the local import names of a file to their import paths.
+// Dot and blank imports are skipped.
+func fileImports(file *ast.File) map[string]string {
+	imports := make(map[string]string, len(file.Imports))
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := importName(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name == "_" || name == "." {
+			continue
+		}
+		imports[name] = path
+	}
+	return imports
+}
+
+// importName guesses the package name of an import path without loading it,
+// following the common conventions: the last path element, skipping a major
+// version suffix (foo/v2) and stripping gopkg.in style suffixes (yaml.v3).
+func importName(path string) string {
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && isMajorVersion(name) {
+		name = elems[len(elems)-2]
+	}
+	if i := strings.Index(name, ".v"); i > 0 && isMajorVersion(name[i+1:]) {
+		name = name[:i]
+	}
+	return strings.TrimPrefix(name, "go-")
+}
+
+// isMajorVersion reports whether s has the form vN for a decimal N.
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// aliasTarget resolves the target of an alias declaration in package pkgPath to
+// a fully qualified name. Returns an empty string for targets that are not plain
+// named types (e.g. *T, []T or instantiated generics).
+func aliasTarget(expr ast.Expr, pkgPath string, imports map[string]string) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if types.Universe.Lookup(e.Name) != nil {
+			return e.Name
+		}
+		return pkgPath + "." + e.Name
+	case *ast.SelectorExpr:
+		pkg, ok := e.X.(*ast.Ident)
+		if !ok {
+			return ""
+		}
+		path, ok := imports[pkg.Name]
+		if !ok {
+			return ""
+		}
+		return path + "." + e.Sel.Name
+	case *ast.ParenExpr:
+		return aliasTarget(e.X, pkgPath, imports)
+	}
+	return ""
+}
+
+// splitQualified splits "import/path.Name" into its package path and name.
+// Unqualified names return an empty package path.
+func splitQualified(qualified string) (pkg, name string) {
+	slash := strings.LastIndex(qualified, "/")
+	dot := strings.LastIndex(qualified, ".")
+	if dot < 0 || dot < slash {
+		return "", qualified
+	}
+	return qualified[:dot], qualified[dot+1:]
+}
+
+// resolveAlias follows an alias chain through byKey to the first non-alias type
+// declared in the same symbol set. Returns false when the chain leaves the set.
+func resolveAlias(byKey map[symbolKey]*symbols.Symbol, sym *symbols.Symbol) (symbolKey, bool) {
+	for range maxAliasChain {
+		pkg, name := splitQualified(sym.AliasOf)
+		if pkg == "" {
+			return symbolKey{}, false
+		}
+
+		key, target, ok := lookupType(byKey, pkg, name)
+		if !ok {
+			return symbolKey{}, false
+		}
+		if target.AliasOf == "" {
+			return key, true
+		}
+		sym = target
+	}
+	return symbolKey{}, false
+}
+
+// resolvedSignature returns the signature of the type sym stands for: the
+// signature of its target when sym is an alias resolvable within byKey, else
+// its own.
+func resolvedSignature(byKey map[symbolKey]*symbols.Symbol, sym *symbols.Symbol) string {
+	if sym.AliasOf == "" {
+		return sym.Signature
+	}
+	if key, ok := resolveAlias(byKey, sym); ok {
+		return byKey[key].Signature
+	}
+	return sym.Signature
+}
+
+// expandAliases makes the members of aliased types visible under the alias
+// name, so they match the members of the types they stand for. The aliases
+// keep their own signatures; resolvedSignature compares their targets.
+// Returns the keys of the synthesized member entries.
+func expandAliases(byKey map[symbolKey]*symbols.Symbol) map[symbolKey]struct{} {
+	type resolved struct {
+		alias  symbolKey
+		target symbolKey
+	}
+	var aliases []resolved
+	for key, sym := range byKey {
+		if sym.AliasOf == "" {
+			continue
+		}
+		if target, ok := resolveAlias(byKey, sym); ok {
+			aliases = append(aliases, resolved{alias: key, target: target})
+		}
+	}
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	// Index members by parent type so each alias expansion is a map lookup.
+	members := make(map[nameKey][]*symbols.Symbol)
+	for key, sym := range byKey {
+		if key.kind != symbols.SymbolMethod && key.kind != symbols.SymbolField {
+			continue
+		}
+		dot := strings.Index(key.name, ".")
+		if dot < 0 {
+			continue
+		}
+		parent := nameKey{pkg: key.pkg, name: key.name[:dot]}
+		members[parent] = append(members[parent], sym)
+	}
+
+	synthetic := make(map[symbolKey]struct{})
+	for _, a := range aliases {
+		for _, member := range members[nameKey{pkg: a.target.pkg, name: a.target.name}] {
+			memberName := member.Name[strings.Index(member.Name, ".")+1:]
+			key := symbolKey{pkg: a.alias.pkg, kind: member.Kind, name: a.alias.name + "." + memberName}
+			if _, exists := byKey[key]; exists {
+				continue
+			}
+			copied := *member
+			copied.Package = a.alias.pkg
+			copied.Name = key.name
+			if copied.Receiver != "" {
+				copied.Receiver = a.alias.name
+			}
+			byKey[key] = &copied
+			synthetic[key] = struct{}{}
+		}
+	}
+	return synthetic
+}
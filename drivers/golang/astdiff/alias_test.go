@@ -0,0 +1,234 @@
+package astdiff
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/emenda-labs/emenda/core/changespec"
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+func TestImportName(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"context", "context"},
+		{"net/http", "http"},
+		{"github.com/acme/foo/v2", "foo"},
+		{"gopkg.in/yaml.v3", "yaml"},
+		{"github.com/mattn/go-sqlite3", "sqlite3"},
+		{"v2", "v2"},
+	}
+	for _, tt := range tests {
+		if got := importName(tt.path); got != tt.want {
+			t.Errorf("importName(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSplitQualified(t *testing.T) {
+	tests := []struct {
+		in       string
+		pkg, obj string
+	}{
+		{"github.com/acme/foo/bar.Config", "github.com/acme/foo/bar", "Config"},
+		{"gopkg.in/yaml.v3.Node", "gopkg.in/yaml.v3", "Node"},
+		{"context.Context", "context", "Context"},
+		{"int", "", "int"},
+	}
+	for _, tt := range tests {
+		pkg, obj := splitQualified(tt.in)
+		if pkg != tt.pkg || obj != tt.obj {
+			t.Errorf("splitQualified(%q) = (%q, %q), want (%q, %q)", tt.in, pkg, obj, tt.pkg, tt.obj)
+		}
+	}
+}
+
+func TestParseExports_AliasTargets(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeFile(filepath.Join(dir, "go.mod"), "module github.com/acme/alias\n"); err != nil {
+		t.Fatal(err)
+	}
+	src := `package alias
+
+import (
+	"context"
+	cfg "github.com/acme/alias/config"
+)
+
+type Config = cfg.Config
+type Ctx = context.Context
+type Local = Other
+type Count = int
+type Ptr = *Other
+type Other struct{}
+`
+	if err := writeFile(filepath.Join(dir, "alias.go"), src); err != nil {
+		t.Fatal(err)
+	}
+
+	syms, _, err := ParseExports(context.Background(), dir, "github.com/acme/alias")
+	if err != nil {
+		t.Fatalf("ParseExports: %v", err)
+	}
+
+	want := map[string]string{
+		"Config": "github.com/acme/alias/config.Config",
+		"Ctx":    "context.Context",
+		"Local":  "github.com/acme/alias.Other",
+		"Count":  "int",
+		"Ptr":    "",
+		"Other":  "",
+	}
+	for _, s := range syms.Entries {
+		exp, ok := want[s.Name]
+		if !ok {
+			continue
+		}
+		if s.AliasOf != exp {
+			t.Errorf("%s AliasOf = %q, want %q", s.Name, s.AliasOf, exp)
+		}
+		delete(want, s.Name)
+	}
+	for name := range want {
+		t.Errorf("missing symbol %s", name)
+	}
+}
+
+func TestDiffExports_MovedWithAlias(t *testing.T) {
+	old := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolType, Name: "Config", Package: "mod", Signature: "struct{Host string}"},
+		{Kind: symbols.SymbolField, Name: "Config.Host", Package: "mod", Signature: "string"},
+		{Kind: symbols.SymbolMethod, Name: "Config.Validate", Package: "mod", Receiver: "Config", Signature: "() error"},
+	})
+	new := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolType, Name: "Config", Package: "mod", Signature: "= config.Config", AliasOf: "mod/config.Config"},
+		{Kind: symbols.SymbolType, Name: "Config", Package: "mod/config", Signature: "struct{Host string}"},
+		{Kind: symbols.SymbolField, Name: "Config.Host", Package: "mod/config", Signature: "string"},
+		{Kind: symbols.SymbolMethod, Name: "Config.Validate", Package: "mod/config", Receiver: "Config", Signature: "() error"},
+	})
+	changes := DiffExports(old, new, nil, nil)
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d: %+v", len(changes), changes)
+	}
+	c := changes[0]
+	if c.Kind != changespec.ChangeKindPackageMoved {
+		t.Errorf("kind = %q, want package_moved", c.Kind)
+	}
+	if c.Severity != changespec.SeverityInfo {
+		t.Errorf("severity = %q, want info", c.Severity)
+	}
+	if c.NewPackage != "mod/config" {
+		t.Errorf("new_package = %q, want mod/config", c.NewPackage)
+	}
+	if c.NewName != "" {
+		t.Errorf("new_name = %q, want empty", c.NewName)
+	}
+	if c.Detail == "" {
+		t.Error("expected a migration hint in Detail")
+	}
+}
+
+func TestDiffExports_MovedWithAliasAndChanged(t *testing.T) {
+	old := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolType, Name: "Config", Package: "mod", Signature: "struct{Host string}"},
+	})
+	new := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolType, Name: "Config", Package: "mod", Signature: "= config.Settings", AliasOf: "mod/config.Settings"},
+		{Kind: symbols.SymbolType, Name: "Settings", Package: "mod/config", Signature: "struct{Addr string}"},
+	})
+	changes := DiffExports(old, new, nil, nil)
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d: %+v", len(changes), changes)
+	}
+	c := changes[0]
+	if c.Kind != changespec.ChangeKindTypeChanged {
+		t.Errorf("kind = %q, want type_changed", c.Kind)
+	}
+	if c.Severity != "" {
+		t.Errorf("severity = %q, want compile (empty)", c.Severity)
+	}
+	if c.NewSignature != "= config.Settings" {
+		t.Errorf("new_signature = %q, want the alias declaration", c.NewSignature)
+	}
+	if !strings.Contains(c.Detail, "struct{Addr string}") {
+		t.Errorf("detail = %q, want the resolved target signature", c.Detail)
+	}
+}
+
+func TestDiffExports_AliasRemoved(t *testing.T) {
+	old := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolType, Name: "Config", Package: "mod", Signature: "= config.Settings", AliasOf: "mod/config.Settings"},
+		{Kind: symbols.SymbolType, Name: "Settings", Package: "mod/config", Signature: "struct{Host string}"},
+		{Kind: symbols.SymbolField, Name: "Settings.Host", Package: "mod/config", Signature: "string"},
+	})
+	new := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolType, Name: "Settings", Package: "mod/config", Signature: "struct{Host string}"},
+		{Kind: symbols.SymbolField, Name: "Settings.Host", Package: "mod/config", Signature: "string"},
+	})
+	changes := DiffExports(old, new, nil, nil)
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d: %+v", len(changes), changes)
+	}
+	c := changes[0]
+	if c.Kind != changespec.ChangeKindPackageMoved {
+		t.Errorf("kind = %q, want package_moved", c.Kind)
+	}
+	if c.Severity != "" {
+		t.Errorf("severity = %q, want compile (empty)", c.Severity)
+	}
+	if c.NewPackage != "mod/config" || c.NewName != "Settings" {
+		t.Errorf("target = %s.%s, want mod/config.Settings", c.NewPackage, c.NewName)
+	}
+	if c.Confidence != changespec.ConfidenceHigh {
+		t.Errorf("confidence = %q, want high", c.Confidence)
+	}
+}
+
+func TestDiffExports_AliasRetargetedUnchanged(t *testing.T) {
+	// An alias whose resolved target keeps the same shape is not a change.
+	old := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolType, Name: "Config", Package: "mod", Signature: "= a.Config", AliasOf: "mod/a.Config"},
+		{Kind: symbols.SymbolType, Name: "Config", Package: "mod/a", Signature: "struct{Host string}"},
+	})
+	new := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolType, Name: "Config", Package: "mod", Signature: "= b.Config", AliasOf: "mod/b.Config"},
+		{Kind: symbols.SymbolType, Name: "Config", Package: "mod/a", Signature: "= b.Config", AliasOf: "mod/b.Config"},
+		{Kind: symbols.SymbolType, Name: "Config", Package: "mod/b", Signature: "struct{Host string}"},
+	})
+	changes := DiffExports(old, new, nil, nil)
+	for _, c := range changes {
+		if c.Symbol == "Config" && c.Package == "mod" {
+			t.Errorf("unexpected change for mod.Config: %+v", c)
+		}
+	}
+}
+
+func TestDiffExports_AliasRetargetedChanged(t *testing.T) {
+	// The change is reported with the alias declarations, not the targets' shapes.
+	old := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolType, Name: "Config", Package: "mod", Signature: "= a.Config", AliasOf: "mod/a.Config"},
+		{Kind: symbols.SymbolType, Name: "Config", Package: "mod/a", Signature: "struct{Host string}"},
+	})
+	new := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolType, Name: "Config", Package: "mod", Signature: "= b.Config", AliasOf: "mod/b.Config"},
+		{Kind: symbols.SymbolType, Name: "Config", Package: "mod/a", Signature: "struct{Host string}"},
+		{Kind: symbols.SymbolType, Name: "Config", Package: "mod/b", Signature: "struct{Addr string}"},
+	})
+	var found bool
+	for _, c := range DiffExports(old, new, nil, nil) {
+		if c.Symbol != "Config" || c.Package != "mod" {
+			continue
+		}
+		found = true
+		if c.Kind != changespec.ChangeKindTypeChanged || c.OldSignature != "= a.Config" || c.NewSignature != "= b.Config" {
+			t.Errorf("change = %s %q -> %q, want type_changed \"= a.Config\" -> \"= b.Config\"", c.Kind, c.OldSignature, c.NewSignature)
+		}
+	}
+	if !found {
+		t.Error("missing change for mod.Config")
+	}
+}
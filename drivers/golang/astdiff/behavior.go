@@ -0,0 +1,401 @@
+package astdiff
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/emenda-labs/emenda/core/changespec"
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+// Behavior heuristics look for changes that compile but alter what existing
+// callers observe: new init functions, constructors picking up different
+// default constants or starting goroutines, and functions reading new
+// environment variables or gaining panics. Bodies are summarized at parse time
+// (ParseOptions.Behavior) so cached snapshots can be compared without source.
+
+// collectInit records a package init function.
+func (c *packageCollector) collectInit(funcDecl *ast.FuncDecl) {
+	if c.behavior == nil || funcDecl.Recv != nil || funcDecl.Name.Name != "init" {
+		return
+	}
+	c.behavior.Inits = append(c.behavior.Inits, symbols.InitFunc{
+		Package:  c.pkgPath,
+		Location: c.location(funcDecl.Name.Pos()),
+	})
+}
+
+// collectConstValues records the value expression of every package-level
+// constant with an explicit value, exported or not: unexported defaults are
+// as relevant to constructors as exported ones.
+func (c *packageCollector) collectConstValues(genDecl *ast.GenDecl) {
+	if c.behavior == nil {
+		return
+	}
+	for _, spec := range genDecl.Specs {
+		valSpec, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+		for i, name := range valSpec.Names {
+			if name.Name == "_" || i >= len(valSpec.Values) {
+				continue
+			}
+			c.behavior.Consts = append(c.behavior.Consts, symbols.ConstValue{
+				Name:    name.Name,
+				Package: c.pkgPath,
+				Value:   types.ExprString(valSpec.Values[i]),
+			})
+		}
+	}
+}
+
+// collectBehavior summarizes the body of the exported function or method sym.
+func (c *packageCollector) collectBehavior(sym symbols.Symbol, funcDecl *ast.FuncDecl) {
+	body := funcDecl.Body
+	if c.behavior == nil || body == nil {
+		return
+	}
+	fb := symbols.FuncBehavior{Name: sym.Name, Package: sym.Package, Location: sym.Location}
+	locals := localNames(funcDecl)
+	seen := make(map[string]bool)
+	addConst := func(ref string) {
+		if !seen[ref] {
+			seen[ref] = true
+			fb.Consts = append(fb.Consts, ref)
+		}
+	}
+
+	var visit func(ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.GoStmt:
+			fb.Goroutines++
+		case *ast.CallExpr:
+			if id, ok := x.Fun.(*ast.Ident); ok && id.Name == "panic" && len(x.Args) == 1 {
+				fb.Panics = append(fb.Panics, types.ExprString(x.Args[0]))
+			}
+			if c.isEnvRead(x.Fun) && len(x.Args) > 0 {
+				fb.EnvReads = append(fb.EnvReads, envKey(x.Args[0]))
+			}
+		case *ast.SelectorExpr:
+			if pkg, ok := x.X.(*ast.Ident); ok {
+				if path, ok := c.imports[pkg.Name]; ok {
+					if inModule(path, c.module) && x.Sel.IsExported() {
+						addConst(path + "." + x.Sel.Name)
+					}
+					return false
+				}
+			}
+			// Field and method names are not package constants.
+			ast.Inspect(x.X, visit)
+			return false
+		case *ast.KeyValueExpr:
+			// A plain identifier key names a struct field.
+			if _, ok := x.Key.(*ast.Ident); ok {
+				ast.Inspect(x.Value, visit)
+				return false
+			}
+		case *ast.Ident:
+			if c.consts[x.Name] && !locals[x.Name] {
+				addConst(c.pkgPath + "." + x.Name)
+			}
+		}
+		return true
+	}
+	ast.Inspect(body, visit)
+
+	sort.Strings(fb.Consts)
+	c.behavior.Funcs = append(c.behavior.Funcs, fb)
+}
+
+// localNames returns the names the function declares anywhere in its scope:
+// receiver, parameters and results, and the variables, constants and types of
+// its body and function literals. Such a name shadows a package constant in at
+// least part of the body, so it is not counted as a constant reference.
+func localNames(funcDecl *ast.FuncDecl) map[string]bool {
+	names := make(map[string]bool)
+	addFields := func(fields *ast.FieldList) {
+		if fields == nil {
+			return
+		}
+		for _, field := range fields.List {
+			for _, name := range field.Names {
+				names[name.Name] = true
+			}
+		}
+	}
+	addIdents := func(exprs ...ast.Expr) {
+		for _, expr := range exprs {
+			if id, ok := expr.(*ast.Ident); ok {
+				names[id.Name] = true
+			}
+		}
+	}
+
+	addFields(funcDecl.Recv)
+	ast.Inspect(funcDecl, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.FuncType:
+			addFields(x.Params)
+			addFields(x.Results)
+		case *ast.AssignStmt:
+			if x.Tok == token.DEFINE {
+				addIdents(x.Lhs...)
+			}
+		case *ast.RangeStmt:
+			if x.Tok == token.DEFINE {
+				addIdents(x.Key, x.Value)
+			}
+		case *ast.ValueSpec:
+			for _, name := range x.Names {
+				names[name.Name] = true
+			}
+		case *ast.TypeSpec:
+			names[x.Name.Name] = true
+		}
+		return true
+	})
+	return names
+}
+
+// isEnvRead reports whether fun is os.Getenv or os.LookupEnv.
+func (c *packageCollector) isEnvRead(fun ast.Expr) bool {
+	sel, ok := fun.(*ast.SelectorExpr)
+	if !ok || (sel.Sel.Name != "Getenv" && sel.Sel.Name != "LookupEnv") {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && c.imports[pkg.Name] == "os"
+}
+
+// envKey returns the variable name passed to an environment lookup: the
+// string literal's value, or the source text of any other expression.
+func envKey(arg ast.Expr) string {
+	if lit, ok := arg.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		if s, err := strconv.Unquote(lit.Value); err == nil {
+			return s
+		}
+	}
+	return types.ExprString(arg)
+}
+
+// packageConsts returns the names of all package-level constants in files.
+func packageConsts(files []*ast.File) map[string]bool {
+	names := make(map[string]bool)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				if valSpec, ok := spec.(*ast.ValueSpec); ok {
+					for _, name := range valSpec.Names {
+						names[name.Name] = true
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// mergeBehavior appends the summaries of one package to dst.
+func mergeBehavior(dst, src *symbols.Behavior) {
+	if src == nil {
+		return
+	}
+	dst.Inits = append(dst.Inits, src.Inits...)
+	dst.Funcs = append(dst.Funcs, src.Funcs...)
+	dst.Consts = append(dst.Consts, src.Consts...)
+}
+
+// pruneConstRefs drops references that do not name a constant with a recorded
+// value. References into other packages are collected without knowing what
+// they name; only the merged module-wide view can tell.
+func pruneConstRefs(b *symbols.Behavior) {
+	known := make(map[string]bool, len(b.Consts))
+	for _, cv := range b.Consts {
+		known[cv.Package+"."+cv.Name] = true
+	}
+	for i := range b.Funcs {
+		refs := b.Funcs[i].Consts[:0]
+		for _, ref := range b.Funcs[i].Consts {
+			if known[ref] {
+				refs = append(refs, ref)
+			}
+		}
+		if len(refs) == 0 {
+			refs = nil
+		}
+		b.Funcs[i].Consts = refs
+	}
+}
+
+// CompareBehavior returns advisories for behavioral risks between two parsed
+// versions. Both must have been parsed with ParseOptions.Behavior; otherwise it
+// returns nil. Only functions and packages present in both versions are
+// compared, since new API has no existing callers to surprise.
+func CompareBehavior(old, new symbols.Symbols) []changespec.Advisory {
+	if old.Behavior == nil || new.Behavior == nil {
+		return nil
+	}
+	advisories := initsAdded(old, new)
+
+	oldFuncs := make(map[nameKey]symbols.FuncBehavior, len(old.Behavior.Funcs))
+	for _, fb := range old.Behavior.Funcs {
+		oldFuncs[nameKey{pkg: fb.Package, name: fb.Name}] = fb
+	}
+	oldConsts := constValues(old.Behavior)
+	newConsts := constValues(new.Behavior)
+
+	for _, nf := range new.Behavior.Funcs {
+		of, ok := oldFuncs[nameKey{pkg: nf.Package, name: nf.Name}]
+		if !ok {
+			continue
+		}
+		advise := func(kind changespec.AdvisoryKind, detail string) {
+			advisories = append(advisories, changespec.Advisory{
+				Kind:     kind,
+				Symbol:   nf.Name,
+				Package:  nf.Package,
+				Detail:   detail,
+				Location: nf.Location,
+			})
+		}
+
+		if isConstructor(nf.Name) {
+			for _, ref := range nf.Consts {
+				oldValue, okOld := oldConsts[ref]
+				newValue, okNew := newConsts[ref]
+				if okOld && okNew && oldValue != newValue {
+					advise(changespec.AdvisoryDefaultChanged, fmt.Sprintf("uses %s, changed from %s to %s", shortRef(ref, nf.Package), oldValue, newValue))
+				}
+			}
+			if nf.Goroutines > of.Goroutines {
+				advise(changespec.AdvisoryGoroutineAdded, fmt.Sprintf("constructor starts %d goroutine(s), up from %d", nf.Goroutines, of.Goroutines))
+			}
+		}
+		for _, key := range addedStrings(of.EnvReads, nf.EnvReads) {
+			advise(changespec.AdvisoryEnvReadAdded, fmt.Sprintf("reads environment variable %s", key))
+		}
+		for _, arg := range addedStrings(of.Panics, nf.Panics) {
+			advise(changespec.AdvisoryPanicAdded, fmt.Sprintf("new panic(%s)", arg))
+		}
+	}
+	return advisories
+}
+
+// initsAdded reports packages that gained init functions. A package that is new
+// in this version is skipped: nothing imports it yet.
+func initsAdded(old, new symbols.Symbols) []changespec.Advisory {
+	oldPkgs := make(map[string]bool)
+	for _, sym := range old.Entries {
+		oldPkgs[sym.Package] = true
+	}
+	oldCount := make(map[string]int)
+	oldFiles := make(map[[2]string]int)
+	for _, init := range old.Behavior.Inits {
+		oldPkgs[init.Package] = true
+		oldCount[init.Package]++
+		oldFiles[[2]string{init.Package, initFile(init)}]++
+	}
+
+	var pkgs []string
+	newCount := make(map[string]int)
+	first := make(map[string]symbols.InitFunc)
+	for _, init := range new.Behavior.Inits {
+		if newCount[init.Package] == 0 {
+			pkgs = append(pkgs, init.Package)
+		}
+		newCount[init.Package]++
+		// Point at the first init in a file that had fewer of them before.
+		file := [2]string{init.Package, initFile(init)}
+		if oldFiles[file] > 0 {
+			oldFiles[file]--
+		} else if _, ok := first[init.Package]; !ok {
+			first[init.Package] = init
+		}
+	}
+
+	var advisories []changespec.Advisory
+	for _, pkg := range pkgs {
+		if !oldPkgs[pkg] || newCount[pkg] <= oldCount[pkg] {
+			continue
+		}
+		detail := "package gains an init function"
+		if oldCount[pkg] > 0 || newCount[pkg] > 1 {
+			detail = fmt.Sprintf("package has %d init functions, up from %d", newCount[pkg], oldCount[pkg])
+		}
+		advisories = append(advisories, changespec.Advisory{
+			Kind:     changespec.AdvisoryInitAdded,
+			Symbol:   "init",
+			Package:  pkg,
+			Detail:   detail,
+			Location: first[pkg].Location,
+		})
+	}
+	return advisories
+}
+
+// initFile returns the file of an init function, "" if unknown.
+func initFile(init symbols.InitFunc) string {
+	if init.Location == nil {
+		return ""
+	}
+	return init.Location.File
+}
+
+// constValues indexes constant values by qualified name.
+func constValues(b *symbols.Behavior) map[string]string {
+	values := make(map[string]string, len(b.Consts))
+	for _, cv := range b.Consts {
+		values[cv.Package+"."+cv.Name] = cv.Value
+	}
+	return values
+}
+
+// isConstructor reports whether name follows the New/NewXxx constructor convention.
+func isConstructor(name string) bool {
+	rest, ok := strings.CutPrefix(name, "New")
+	if !ok || strings.Contains(name, ".") {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(rest)
+	return rest == "" || unicode.IsUpper(r)
+}
+
+// shortRef drops the package path from a reference into pkg itself and
+// shortens other references to their last path element.
+func shortRef(ref, pkg string) string {
+	if name, ok := strings.CutPrefix(ref, pkg+"."); ok {
+		return name
+	}
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+// addedStrings returns the elements of new not matched by an element of old,
+// counting duplicates, in the order they appear in new.
+func addedStrings(old, new []string) []string {
+	remaining := make(map[string]int, len(old))
+	for _, s := range old {
+		remaining[s]++
+	}
+	var added []string
+	for _, s := range new {
+		if remaining[s] > 0 {
+			remaining[s]--
+			continue
+		}
+		added = append(added, s)
+	}
+	return added
+}
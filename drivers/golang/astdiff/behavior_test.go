@@ -0,0 +1,244 @@
+package astdiff
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+// parseBehavior writes files into a fresh module and parses it with behavior
+// summaries enabled.
+func parseBehavior(t *testing.T, files map[string]string) symbols.Symbols {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module github.com/acme/client\n"
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeFile(path, src); err != nil {
+			t.Fatal(err)
+		}
+	}
+	syms, _, err := ParseExportsWithOptions(context.Background(), dir, "github.com/acme/client", ParseOptions{Behavior: true})
+	if err != nil {
+		t.Fatalf("ParseExportsWithOptions: %v", err)
+	}
+	return syms
+}
+
+func TestParseExports_Behavior(t *testing.T) {
+	syms := parseBehavior(t, map[string]string{
+		"client.go": `package client
+
+import (
+	"os"
+	"time"
+
+	"github.com/acme/client/config"
+)
+
+const (
+	DefaultTimeout = 30 * time.Second
+	maxRetries     = 3
+	modeA          = iota
+)
+
+type Client struct{ timeout time.Duration }
+
+func New() *Client {
+	go func() {}()
+	return &Client{timeout: DefaultTimeout + maxRetries + config.DefaultPort}
+}
+
+func (c *Client) Do(key string) {
+	_ = c.timeout
+	os.Getenv("CLIENT_DEBUG")
+	os.LookupEnv(key)
+	panic("unreachable")
+}
+
+func Retry(maxRetries int) *Client {
+	DefaultTimeout := time.Second
+	return &Client{timeout: DefaultTimeout * time.Duration(maxRetries)}
+}
+
+type Options struct{ maxRetries int }
+
+func NewOptions() Options {
+	return Options{maxRetries: 1}
+}
+
+func init() {}
+`,
+		"config/config.go": "package config\n\nconst DefaultPort = 80\n",
+	})
+	b := syms.Behavior
+	if b == nil {
+		t.Fatal("Behavior is nil")
+	}
+
+	if len(b.Inits) != 1 || b.Inits[0].Package != "github.com/acme/client" || b.Inits[0].Location == nil {
+		t.Errorf("Inits = %+v", b.Inits)
+	}
+	wantConsts := []symbols.ConstValue{
+		{Name: "DefaultTimeout", Package: "github.com/acme/client", Value: "30 * time.Second"},
+		{Name: "maxRetries", Package: "github.com/acme/client", Value: "3"},
+		{Name: "modeA", Package: "github.com/acme/client", Value: "iota"},
+		{Name: "DefaultPort", Package: "github.com/acme/client/config", Value: "80"},
+	}
+	if !reflect.DeepEqual(b.Consts, wantConsts) {
+		t.Errorf("Consts = %+v\nwant %+v", b.Consts, wantConsts)
+	}
+
+	funcs := make(map[string]symbols.FuncBehavior)
+	for _, fb := range b.Funcs {
+		fb.Location = nil
+		funcs[fb.Name] = fb
+	}
+	wantFuncs := map[string]symbols.FuncBehavior{
+		"New": {
+			Name: "New", Package: "github.com/acme/client", Goroutines: 1,
+			Consts: []string{"github.com/acme/client.DefaultTimeout", "github.com/acme/client.maxRetries", "github.com/acme/client/config.DefaultPort"},
+		},
+		"Client.Do": {
+			Name: "Client.Do", Package: "github.com/acme/client",
+			EnvReads: []string{"CLIENT_DEBUG", "key"},
+			Panics:   []string{`"unreachable"`},
+		},
+		"Retry":      {Name: "Retry", Package: "github.com/acme/client"},
+		"NewOptions": {Name: "NewOptions", Package: "github.com/acme/client"},
+	}
+	if !reflect.DeepEqual(funcs, wantFuncs) {
+		t.Errorf("Funcs = %+v\nwant %+v", funcs, wantFuncs)
+	}
+
+	dir := t.TempDir()
+	if err := writeFile(filepath.Join(dir, "go.mod"), "module github.com/acme/client\n"); err != nil {
+		t.Fatal(err)
+	}
+	plain, _, err := ParseExports(context.Background(), dir, "github.com/acme/client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.Behavior != nil {
+		t.Errorf("Behavior collected without ParseOptions.Behavior: %+v", plain.Behavior)
+	}
+}
+
+func TestCompareBehavior(t *testing.T) {
+	old := parseBehavior(t, map[string]string{
+		"client.go": `package client
+
+import (
+	"os"
+	"time"
+
+	"github.com/acme/client/config"
+)
+
+const DefaultTimeout = 30 * time.Second
+
+type Client struct{ timeout time.Duration }
+
+func New() *Client {
+	return &Client{timeout: DefaultTimeout + config.DefaultPort}
+}
+
+func (c *Client) Do(n int) {
+	if n < 0 {
+		panic("negative")
+	}
+	os.Getenv("CLIENT_DEBUG")
+}
+
+func Unchanged() { go func() {}() }
+`,
+		"config/config.go": "package config\n\nconst DefaultPort = 80\n",
+	})
+	new := parseBehavior(t, map[string]string{
+		"client.go": `package client
+
+import (
+	"os"
+	"time"
+
+	"github.com/acme/client/config"
+)
+
+const DefaultTimeout = 5 * time.Second
+
+type Client struct{ timeout time.Duration }
+
+func New() *Client {
+	c := &Client{timeout: DefaultTimeout + config.DefaultPort}
+	go c.loop()
+	return c
+}
+
+func (c *Client) loop() {}
+
+func (c *Client) Do(n int) {
+	if n < 0 {
+		panic("negative")
+	}
+	if n == 0 {
+		panic("zero")
+	}
+	os.Getenv("CLIENT_DEBUG")
+	os.Getenv("CLIENT_PROXY")
+}
+
+func Unchanged() { go func() {}() }
+
+// NewPool is new API: nothing calls it yet.
+func NewPool() { go func() {}() }
+`,
+		"register.go":      "package client\n\nfunc init() {}\n",
+		"config/config.go": "package config\n\nconst DefaultPort = 8080\n",
+		"extra/extra.go":   "package extra\n\nfunc init() {}\n",
+	})
+
+	type advisory struct{ kind, symbol, detail string }
+	var got []advisory
+	for _, a := range CompareBehavior(old, new) {
+		got = append(got, advisory{string(a.Kind), a.Symbol, a.Detail})
+		if a.Location == nil {
+			t.Errorf("%s %s has no location", a.Kind, a.Symbol)
+		}
+	}
+	want := []advisory{
+		{"init_added", "init", "package gains an init function"},
+		{"default_changed", "New", "uses DefaultTimeout, changed from 30 * time.Second to 5 * time.Second"},
+		{"default_changed", "New", "uses config.DefaultPort, changed from 80 to 8080"},
+		{"goroutine_added", "New", "constructor starts 1 goroutine(s), up from 0"},
+		{"env_read_added", "Client.Do", "reads environment variable CLIENT_PROXY"},
+		{"panic_added", "Client.Do", `new panic("zero")`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CompareBehavior =\n%q\nwant\n%q", got, want)
+	}
+
+	if a := CompareBehavior(old, symbols.Symbols{}); a != nil {
+		t.Errorf("CompareBehavior without summaries = %+v, want nil", a)
+	}
+}
+
+func TestIsConstructor(t *testing.T) {
+	for name, want := range map[string]bool{
+		"New":        true,
+		"NewClient":  true,
+		"Newer":      false,
+		"Renew":      false,
+		"Client.New": false,
+	} {
+		if got := isConstructor(name); got != want {
+			t.Errorf("isConstructor(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
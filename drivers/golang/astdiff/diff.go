@@ -1,7 +1,8 @@
 package astdiff
 
 import (
-	"sort"
+	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/emenda-labs/emenda/core/changespec"
@@ -9,7 +10,7 @@ import (
 )
 
 const (
-	// MinNameSimilarity is the minimum normalized Levenshtein similarity for fuzzy rename matching.
+	// MinNameSimilarity is the minimum name similarity for fuzzy rename matching.
 	MinNameSimilarity = 0.7
 
 	// MinParamOverlap is the minimum Jaccard overlap on parameter types for fuzzy rename matching.
@@ -22,6 +23,39 @@ const (
 	ShortNameMinSimilarity = 0.85
 )
 
+// DiffOptions controls optional behavior of DiffExportsWithOptions.
+// The zero value selects the defaults.
+type DiffOptions struct {
+	// Synonyms lists groups of interchangeable words for rename detection
+	// (e.g. {"Get", "Fetch"}). Nil means DefaultSynonyms; an empty non-nil
+	// slice disables synonym matching.
+	Synonyms [][]string
+
+	// Affixes lists name suffixes whose addition or removal only slightly lowers
+	// rename similarity (e.g. "WithContext"). Nil means DefaultAffixes.
+	Affixes []string
+
+	// History is external rename and move evidence, e.g. mined from upstream
+	// commits between the versions. Nil means none.
+	History *History
+}
+
+// synonyms returns the configured synonym groups, falling back to DefaultSynonyms.
+func (o DiffOptions) synonyms() [][]string {
+	if o.Synonyms == nil {
+		return DefaultSynonyms
+	}
+	return o.Synonyms
+}
+
+// affixes returns the configured affixes, falling back to DefaultAffixes.
+func (o DiffOptions) affixes() []string {
+	if o.Affixes == nil {
+		return DefaultAffixes
+	}
+	return o.Affixes
+}
+
 // diffState holds the working state across all diff passes.
 type diffState struct {
 	oldByKey        map[symbolKey]*symbols.Symbol
@@ -31,6 +65,14 @@ type diffState struct {
 	oldSigs         FuncSigMap
 	newSigs         FuncSigMap
 	typeRenames     map[string]string
+	// hiddenRenames maps unexported types reachable from the API, keyed by
+	// package and old name, to their new names (see inferHiddenRenames).
+	hiddenRenames map[nameKey]string
+	names         *nameMatcher
+	history       *historyIndex
+	// oldAliasMembers holds synthesized old-side members of aliased types.
+	// They are lookup-only and never enter the unmatched sets.
+	oldAliasMembers map[symbolKey]struct{}
 	changes         []changespec.Change
 }
 
@@ -54,6 +96,8 @@ type scoredPair struct {
 	newKey  symbolKey
 	score   float64
 	oldName string // for tie-breaking
+	// hint is the upstream history backing the pair, if any.
+	hint *RenameHint
 }
 
 // newDiffState builds indexed lookup maps from old and new symbol sets.
@@ -66,6 +110,9 @@ func newDiffState(old, new symbols.Symbols, oldSigs, newSigs FuncSigMap) *diffSt
 		oldSigs:         oldSigs,
 		newSigs:         newSigs,
 		typeRenames:     make(map[string]string),
+		hiddenRenames:   make(map[nameKey]string),
+		names:           newNameMatcher(DiffOptions{}),
+		history:         newHistoryIndex(nil),
 	}
 	for i := range old.Entries {
 		sym := &old.Entries[i]
@@ -79,19 +126,54 @@ func newDiffState(old, new symbols.Symbols, oldSigs, newSigs FuncSigMap) *diffSt
 		s.newByKey[key] = sym
 		s.unmatchedNewSet[key] = struct{}{}
 	}
+	s.oldAliasMembers = expandAliases(s.oldByKey)
+	expandAliases(s.newByKey)
 	return s
 }
 
 // DiffExports compares two symbol sets and classifies all breaking changes with confidence levels.
-// Runs six passes: exact match, changed, renamed, correlate methods, fuzzy match, leftovers.
+// Type aliases are resolved to their targets first. The passes then run in order:
+//
+//   - tagChanged: struct tag changes on fields, as behavioral changes
+//   - paramSwaps: same-typed parameters that swapped names, as warnings
+//   - aliasMoves: types moved to another package behind a compatibility alias
+//   - inferHiddenRenames: renames of unexported types reachable from the API
+//   - exactMatch: symbols unchanged up to those renames
+//   - correlateHiddenMembers: members of renamed unexported types
+//   - changed: same symbol, different signature
+//   - packageMoves: symbols in packages that upstream history shows moved
+//   - renamed: renames with identical signatures, history breaking ties
+//   - correlateMethods: methods following their receiver's rename
+//   - fuzzyMatch: renames found by name and signature similarity
+//   - leftovers: everything still unmatched, as removed
+//
+// Finally, changes to types list the old-version symbols that reference them
+// in Affected, changes get source locations, and changes to packages with
+// parse diagnostics are marked low trust.
 func DiffExports(old, new symbols.Symbols, oldSigs, newSigs FuncSigMap) []changespec.Change {
+	return DiffExportsWithOptions(old, new, oldSigs, newSigs, DiffOptions{})
+}
+
+// DiffExportsWithOptions is DiffExports with explicit diff options.
+func DiffExportsWithOptions(old, new symbols.Symbols, oldSigs, newSigs FuncSigMap, opts DiffOptions) []changespec.Change {
 	s := newDiffState(old, new, oldSigs, newSigs)
+	s.names = newNameMatcher(opts)
+	s.history = newHistoryIndex(opts.History)
+	s.tagChanged()
+	s.paramSwaps()
+	s.aliasMoves()
+	s.inferHiddenRenames()
 	s.exactMatch()
+	s.correlateHiddenMembers()
 	s.changed()
+	s.packageMoves()
 	s.renamed()
 	s.correlateMethods()
 	s.fuzzyMatch()
 	s.leftovers()
+	s.attachAffected()
+	s.attachLocations()
+	s.annotateLowTrust(old.Diagnostics, new.Diagnostics)
 	return s.changes
 }
 
@@ -100,6 +182,41 @@ func (s *diffState) markMatched(oldKey, newKey symbolKey) {
 	delete(s.unmatchedNewSet, newKey)
 }
 
+// identPattern matches identifiers in rendered signatures.
+var identPattern = regexp.MustCompile(`[\pL_][\pL\pN_]*`)
+
+// sameSignature reports whether two symbols have the same signature, comparing
+// the targets of aliases (see resolvedSignature) and replacing the unexported
+// types renamed in oldSym's package by their new names.
+func (s *diffState) sameSignature(oldSym, newSym *symbols.Symbol) bool {
+	if oldSym.Signature == newSym.Signature {
+		return true
+	}
+	sig := resolvedSignature(s.oldByKey, oldSym)
+	newSig := resolvedSignature(s.newByKey, newSym)
+	if sig == newSig {
+		return true
+	}
+	if len(s.hiddenRenames) == 0 {
+		return false
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range identPattern.FindAllStringIndex(sig, -1) {
+		// Qualified names belong to other packages.
+		if loc[0] > 0 && sig[loc[0]-1] == '.' {
+			continue
+		}
+		if name, ok := s.hiddenRenames[nameKey{pkg: oldSym.Package, name: sig[loc[0]:loc[1]]}]; ok {
+			b.WriteString(sig[last:loc[0]])
+			b.WriteString(name)
+			last = loc[1]
+		}
+	}
+	b.WriteString(sig[last:])
+	return b.String() == newSig
+}
+
 func (s *diffState) emit(c changespec.Change) {
 	s.changes = append(s.changes, c)
 }
@@ -120,6 +237,169 @@ func (s *diffState) unmatchedNew() []symbolKey {
 	return keys
 }
 
+// Tag pass: struct tag changes on fields present in both versions. These do not
+// break compilation, so they are emitted as behavioral changes and do not consume
+// symbols from the unmatched sets.
+func (s *diffState) tagChanged() {
+	for key, oldSym := range s.oldByKey {
+		if key.kind != symbols.SymbolField {
+			continue
+		}
+		if _, synthetic := s.oldAliasMembers[key]; synthetic {
+			continue
+		}
+		newSym, ok := s.newByKey[key]
+		if !ok {
+			continue
+		}
+		if len(oldSym.Tags) == 0 && len(newSym.Tags) == 0 {
+			continue
+		}
+
+		fieldName := oldSym.Name[strings.LastIndex(oldSym.Name, ".")+1:]
+
+		tagKeys := make(map[string]struct{}, len(oldSym.Tags)+len(newSym.Tags))
+		for k := range oldSym.Tags {
+			tagKeys[k] = struct{}{}
+		}
+		for k := range newSym.Tags {
+			tagKeys[k] = struct{}{}
+		}
+
+		for _, tagKey := range sortedKeys(tagKeys) {
+			oldVal, oldOK := oldSym.Tags[tagKey]
+			newVal, newOK := newSym.Tags[tagKey]
+
+			detail := compareTag(tagKey, fieldName, oldVal, newVal, oldOK, newOK)
+			if detail == "" {
+				continue
+			}
+
+			s.emit(changespec.Change{
+				Kind:         changespec.ChangeKindTagChanged,
+				Symbol:       oldSym.Name,
+				Package:      oldSym.Package,
+				OldSignature: renderTag(tagKey, oldVal, oldOK),
+				NewSignature: renderTag(tagKey, newVal, newOK),
+				Confidence:   changespec.ConfidenceHigh,
+				Severity:     changespec.SeverityBehavioral,
+				Detail:       detail,
+			})
+		}
+	}
+}
+
+// Alias pass: types moved to another package with a compatibility alias left
+// behind, and removal of such aliases. A move whose alias resolves to an identical
+// type is source compatible and reported at info severity with a migration hint;
+// removing the alias later is the actual break.
+func (s *diffState) aliasMoves() {
+	for _, oldKey := range s.unmatchedOld() {
+		if oldKey.kind != symbols.SymbolType && oldKey.kind != symbols.SymbolInterface {
+			continue
+		}
+		oldSym := s.oldByKey[oldKey]
+		newKey, newSym, inNew := lookupType(s.newByKey, oldKey.pkg, oldKey.name)
+
+		switch {
+		case inNew && oldSym.AliasOf == "" && newSym.AliasOf != "":
+			s.movedWithAlias(oldKey, newKey, oldSym, newSym)
+		case !inNew && oldSym.AliasOf != "":
+			s.aliasRemoved(oldKey, oldSym)
+		}
+	}
+}
+
+// movedWithAlias handles a declared type replaced by an alias to another package.
+func (s *diffState) movedWithAlias(oldKey, newKey symbolKey, oldSym, newSym *symbols.Symbol) {
+	target := newSym.AliasOf
+	targetKey, resolved := resolveAlias(s.newByKey, newSym)
+	if resolved {
+		target = targetKey.pkg + "." + targetKey.name
+	}
+	targetPkg, targetName := splitQualified(target)
+	if targetPkg == "" || targetPkg == oldSym.Package {
+		// Alias to a predeclared or same-package type is not a move; Pass 2 handles it.
+		return
+	}
+
+	c := changespec.Change{
+		Kind:         changespec.ChangeKindPackageMoved,
+		Symbol:       oldSym.Name,
+		Package:      oldSym.Package,
+		NewPackage:   targetPkg,
+		OldSignature: oldSym.Signature,
+		NewSignature: newSym.Signature,
+		Confidence:   changespec.ConfidenceHigh,
+		Severity:     changespec.SeverityInfo,
+		Detail:       fmt.Sprintf("moved to %s; %s remains as a compatibility alias, migrate before it is removed", target, oldSym.Name),
+	}
+	if targetName != oldSym.Name {
+		c.NewName = targetName
+	}
+
+	switch {
+	case !resolved:
+		// Target lives outside the module; the signature cannot be compared.
+		c.Confidence = changespec.ConfidenceMedium
+	case !s.sameSignature(oldSym, newSym):
+		c.Kind = changespec.ChangeKindTypeChanged
+		c.Severity = ""
+		c.Detail = fmt.Sprintf("moved to %s and changed to %s", target, resolvedSignature(s.newByKey, newSym))
+	}
+
+	s.emit(c)
+	s.markMatched(oldKey, newKey)
+}
+
+// aliasRemoved handles removal of an alias; consumers must switch to its target.
+func (s *diffState) aliasRemoved(oldKey symbolKey, oldSym *symbols.Symbol) {
+	target := oldSym.AliasOf
+	targetKey, resolved := resolveAlias(s.oldByKey, oldSym)
+	if resolved {
+		target = targetKey.pkg + "." + targetKey.name
+	}
+	targetPkg, targetName := splitQualified(target)
+	if targetPkg == "" {
+		return
+	}
+
+	c := changespec.Change{
+		Kind:         changespec.ChangeKindPackageMoved,
+		Symbol:       oldSym.Name,
+		Package:      oldSym.Package,
+		NewPackage:   targetPkg,
+		OldSignature: oldSym.Signature,
+		Confidence:   changespec.ConfidenceHigh,
+		Detail:       fmt.Sprintf("alias removed; use %s", target),
+	}
+	if targetName != oldSym.Name {
+		c.NewName = targetName
+	}
+	if resolved {
+		if _, newTarget, ok := lookupType(s.newByKey, targetPkg, targetName); ok {
+			c.NewSignature = newTarget.Signature
+		} else {
+			// The target itself is gone from the new version.
+			c.Confidence = changespec.ConfidenceLow
+		}
+	}
+
+	s.emit(c)
+	delete(s.unmatchedOldSet, oldKey)
+}
+
+// lookupType finds a type or interface by package and name.
+func lookupType(byKey map[symbolKey]*symbols.Symbol, pkg, name string) (symbolKey, *symbols.Symbol, bool) {
+	for _, kind := range []symbols.SymbolKind{symbols.SymbolType, symbols.SymbolInterface} {
+		key := symbolKey{pkg: pkg, kind: kind, name: name}
+		if sym, ok := byKey[key]; ok {
+			return key, sym, true
+		}
+	}
+	return symbolKey{}, nil, false
+}
+
 // Pass 1: exact matches (same key, same signature) are silently consumed.
 func (s *diffState) exactMatch() {
 	for key := range s.unmatchedOldSet {
@@ -128,7 +408,7 @@ func (s *diffState) exactMatch() {
 			continue
 		}
 		oldSym := s.oldByKey[key]
-		if oldSym.Signature == newSym.Signature {
+		if s.sameSignature(oldSym, newSym) {
 			s.markMatched(key, key)
 		}
 	}
@@ -143,7 +423,7 @@ func (s *diffState) changed() {
 			continue
 		}
 		oldSym := s.oldByKey[key]
-		if oldSym.Signature == newSym.Signature {
+		if s.sameSignature(oldSym, newSym) {
 			continue
 		}
 
@@ -223,37 +503,85 @@ func (s *diffState) renamed() {
 			continue
 		}
 
-		// Collision: more than one on either side, defer to Pass 5.
+		// Collision: more than one on either side. Upstream history may pair
+		// some of them; the rest defer to Pass 5.
 		if len(oldKeys) > 1 || len(newKeys) > 1 {
+			s.renamedByHistory(oldKeys, newKeys)
 			continue
 		}
 
 		oldKey := oldKeys[0]
 		newKey := newKeys[0]
 		oldSym := s.oldByKey[oldKey]
-		newSym := s.newByKey[newKey]
 
 		// Trivial signature guard: skip empty or "()" signatures.
 		if oldSym.Signature == "" || oldSym.Signature == "()" {
 			continue
 		}
 
-		s.emit(changespec.Change{
-			Kind:         changespec.ChangeKindRenamed,
-			Symbol:       oldSym.Name,
-			Package:      oldSym.Package,
-			NewName:      newSym.Name,
-			OldSignature: oldSym.Signature,
-			NewSignature: newSym.Signature,
-			Confidence:   changespec.ConfidenceHigh,
-		})
-		s.markMatched(oldKey, newKey)
+		detail := ""
+		if hint, ok := s.history.lookup(oldSym, s.newByKey[newKey]); ok {
+			detail = hintDetail(hint)
+		}
+		s.emitRename(oldKey, newKey, changespec.ConfidenceHigh, detail)
+	}
+}
 
-		// Track type/interface renames for Pass 4.
-		if oldSym.Kind == symbols.SymbolType || oldSym.Kind == symbols.SymbolInterface {
-			s.typeRenames[oldSym.Name] = newSym.Name
+// renamedByHistory resolves a Pass 3 signature collision with rename hints.
+// Hinted pairs are renames with high confidence; if exactly one old and one new
+// symbol remain afterwards, they are paired by elimination with medium confidence.
+func (s *diffState) renamedByHistory(oldKeys, newKeys []symbolKey) {
+	if sig := s.oldByKey[oldKeys[0]].Signature; sig == "" || sig == "()" {
+		return
+	}
+	pairs := s.resolveCollision(oldKeys, newKeys)
+	if len(pairs) == 0 {
+		return
+	}
+	var hinted []string
+	for _, p := range pairs {
+		hint, _ := s.history.lookup(s.oldByKey[p.oldKey], s.newByKey[p.newKey])
+		s.emitRename(p.oldKey, p.newKey, changespec.ConfidenceHigh, hintDetail(hint))
+		hinted = append(hinted, p.oldName)
+	}
+
+	var restOld, restNew []symbolKey
+	for _, k := range oldKeys {
+		if _, ok := s.unmatchedOldSet[k]; ok {
+			restOld = append(restOld, k)
+		}
+	}
+	for _, k := range newKeys {
+		if _, ok := s.unmatchedNewSet[k]; ok {
+			restNew = append(restNew, k)
 		}
 	}
+	if len(restOld) == 1 && len(restNew) == 1 {
+		detail := fmt.Sprintf("only remaining match after upstream history paired %s", strings.Join(hinted, ", "))
+		s.emitRename(restOld[0], restNew[0], changespec.ConfidenceMedium, detail)
+	}
+}
+
+// emitRename records a same-signature rename and tracks type renames for Pass 4.
+func (s *diffState) emitRename(oldKey, newKey symbolKey, confidence changespec.ConfidenceLevel, detail string) {
+	oldSym := s.oldByKey[oldKey]
+	newSym := s.newByKey[newKey]
+	s.emit(changespec.Change{
+		Kind:         changespec.ChangeKindRenamed,
+		Symbol:       oldSym.Name,
+		Package:      oldSym.Package,
+		NewName:      newSym.Name,
+		OldSignature: oldSym.Signature,
+		NewSignature: newSym.Signature,
+		Confidence:   confidence,
+		Detail:       detail,
+	})
+	s.markMatched(oldKey, newKey)
+
+	// Track type/interface renames for Pass 4.
+	if oldSym.Kind == symbols.SymbolType || oldSym.Kind == symbols.SymbolInterface {
+		s.typeRenames[oldSym.Name] = newSym.Name
+	}
 }
 
 // Pass 4: correlate methods and fields whose receiver/parent type was renamed.
@@ -291,7 +619,7 @@ func (s *diffState) correlateMethods() {
 
 		newSym := s.newByKey[expectedNewKey]
 
-		if oldSym.Signature == newSym.Signature {
+		if s.sameSignature(oldSym, newSym) {
 			s.emit(changespec.Change{
 				Kind:         changespec.ChangeKindRenamed,
 				Symbol:       oldSym.Name,
@@ -339,44 +667,13 @@ func (s *diffState) fuzzyMatch() {
 		return
 	}
 
-	// Build candidate pairs.
-	var candidates []scoredPair
-	for _, oldKey := range oldFuncKeys {
-		oldSym := s.oldByKey[oldKey]
-		oldSig := s.oldSigs[oldKey]
-
-		for _, newKey := range newFuncKeys {
-			newSym := s.newByKey[newKey]
-			newSig := s.newSigs[newKey]
-
-			nameSim := nameSimilarity(oldSym.Name, newSym.Name)
-			overlap := paramOverlap(oldSig, newSig)
-
-			// Apply short name guard.
-			nameThreshold := MinNameSimilarity
-			maxLen := max(len(oldSym.Name), len(newSym.Name))
-			if maxLen < ShortNameLength {
-				nameThreshold = ShortNameMinSimilarity
-			}
-
-			if nameSim >= nameThreshold && overlap >= MinParamOverlap {
-				candidates = append(candidates, scoredPair{
-					oldKey:  oldKey,
-					newKey:  newKey,
-					score:   nameSim * overlap,
-					oldName: oldSym.Name,
-				})
-			}
+	candidates := s.fuzzyCandidates(oldFuncKeys, newFuncKeys)
+	for i := range candidates {
+		if hint, ok := s.history.lookup(s.oldByKey[candidates[i].oldKey], s.newByKey[candidates[i].newKey]); ok {
+			candidates[i].hint = &hint
 		}
 	}
-
-	// Sort by descending score, tie-break by old symbol name.
-	sort.SliceStable(candidates, func(i, j int) bool {
-		if candidates[i].score != candidates[j].score {
-			return candidates[i].score > candidates[j].score
-		}
-		return candidates[i].oldName < candidates[j].oldName
-	})
+	sortCandidates(candidates)
 
 	// Greedy matching.
 	for _, pair := range candidates {
@@ -390,7 +687,7 @@ func (s *diffState) fuzzyMatch() {
 		oldSym := s.oldByKey[pair.oldKey]
 		newSym := s.newByKey[pair.newKey]
 
-		s.emit(changespec.Change{
+		c := changespec.Change{
 			Kind:         changespec.ChangeKindRenamed,
 			Symbol:       oldSym.Name,
 			Package:      oldSym.Package,
@@ -398,11 +695,43 @@ func (s *diffState) fuzzyMatch() {
 			OldSignature: oldSym.Signature,
 			NewSignature: newSym.Signature,
 			Confidence:   changespec.ConfidenceMedium,
-		})
+		}
+		if pair.hint != nil {
+			c.Confidence = changespec.ConfidenceHigh
+			c.Detail = hintDetail(*pair.hint)
+		}
+		s.emit(c)
 		s.markMatched(pair.oldKey, pair.newKey)
 	}
 }
 
+// fuzzyCandidates scores the old/new function pairs that clear both Pass 5
+// thresholds. A blocking index limits scoring to pairs that can qualify; the
+// result is the same set an exhaustive comparison would produce.
+func (s *diffState) fuzzyCandidates(oldKeys, newKeys []symbolKey) []scoredPair {
+	rank := tokenRanks([]FuncSigMap{s.oldSigs, s.newSigs}, [][]symbolKey{oldKeys, newKeys})
+	oldEntries := newFuzzyEntries(oldKeys, s.oldByKey, s.oldSigs, rank, s.names)
+	idx := newFuzzyIndex(newFuzzyEntries(newKeys, s.newByKey, s.newSigs, rank, s.names), MinParamOverlap)
+
+	var candidates []scoredPair
+	for _, old := range oldEntries {
+		for _, i := range idx.candidates(old, MinParamOverlap) {
+			new := idx.entries[i]
+			score, ok := fuzzyScore(s.names, old, new)
+			if !ok {
+				continue
+			}
+			candidates = append(candidates, scoredPair{
+				oldKey:  old.key,
+				newKey:  new.key,
+				score:   score,
+				oldName: old.name,
+			})
+		}
+	}
+	return candidates
+}
+
 // Pass 6: all remaining unmatched old symbols are classified as removed.
 func (s *diffState) leftovers() {
 	for key := range s.unmatchedOldSet {
@@ -417,52 +746,118 @@ func (s *diffState) leftovers() {
 	}
 }
 
-// levenshteinDistance computes the edit distance between two strings.
-func levenshteinDistance(a, b string) int {
-	la, lb := len(a), len(b)
-	if la == 0 {
-		return lb
+// attachLocations fills in declaration positions and doc excerpts for every change.
+// Names are unique per package within one version (a package-level identifier or
+// a Type.Member pair), so lookups ignore the symbol kind.
+func (s *diffState) attachLocations() {
+	oldByName := make(map[nameKey]*symbols.Symbol, len(s.oldByKey))
+	for key, sym := range s.oldByKey {
+		oldByName[nameKey{pkg: key.pkg, name: key.name}] = sym
 	}
-	if lb == 0 {
-		return la
+	newByName := make(map[nameKey]*symbols.Symbol, len(s.newByKey))
+	for key, sym := range s.newByKey {
+		newByName[nameKey{pkg: key.pkg, name: key.name}] = sym
 	}
 
-	// Use two rows instead of full matrix.
-	prev := make([]int, lb+1)
-	curr := make([]int, lb+1)
+	for i := range s.changes {
+		c := &s.changes[i]
+
+		if oldSym, ok := oldByName[nameKey{pkg: c.Package, name: c.Symbol}]; ok {
+			c.OldLocation = oldSym.Location
+			c.Doc = oldSym.Doc
+		}
+
+		if c.Kind == changespec.ChangeKindRemoved {
+			continue
+		}
 
-	for j := 0; j <= lb; j++ {
-		prev[j] = j
+		newKey := nameKey{pkg: c.Package, name: c.Symbol}
+		if c.NewPackage != "" {
+			newKey.pkg = c.NewPackage
+		}
+		if c.NewName != "" {
+			newKey.name = c.NewName
+		}
+		if newSym, ok := newByName[newKey]; ok {
+			c.NewLocation = newSym.Location
+			if newSym.Doc != "" {
+				c.Doc = newSym.Doc
+			}
+		}
 	}
+}
 
-	for i := 1; i <= la; i++ {
-		curr[0] = i
-		for j := 1; j <= lb; j++ {
-			cost := 1
-			if a[i-1] == b[j-1] {
-				cost = 0
+// annotateLowTrust flags changes whose package or symbol is affected by a
+// diagnostic from either version. A file that failed to parse makes every symbol
+// of its package look removed, so such changes must not be trusted blindly.
+func (s *diffState) annotateLowTrust(diagnostics ...[]changespec.Diagnostic) {
+	pkgs := make(map[string]struct{})
+	var skippedDirs []string
+	syms := make(map[nameKey]struct{})
+
+	for _, list := range diagnostics {
+		for _, d := range list {
+			switch {
+			case d.Symbol != "":
+				syms[nameKey{pkg: d.Package, name: d.Symbol}] = struct{}{}
+			case d.Kind == changespec.DiagnosticSkippedDir:
+				skippedDirs = append(skippedDirs, d.Package)
+			default:
+				pkgs[d.Package] = struct{}{}
 			}
+		}
+	}
+	if len(pkgs) == 0 && len(skippedDirs) == 0 && len(syms) == 0 {
+		return
+	}
 
-			del := prev[j] + 1
-			ins := curr[j-1] + 1
-			sub := prev[j-1] + cost
+	affectedPkg := func(pkg string) bool {
+		if pkg == "" {
+			return false
+		}
+		if _, ok := pkgs[pkg]; ok {
+			return true
+		}
+		for _, dir := range skippedDirs {
+			if pkg == dir || strings.HasPrefix(pkg, dir+"/") {
+				return true
+			}
+		}
+		return false
+	}
 
-			curr[j] = min(del, ins, sub)
+	affectedSym := func(pkg, name string) bool {
+		if _, ok := syms[nameKey{pkg: pkg, name: name}]; ok {
+			return true
+		}
+		// Diagnostics on a type also cover its methods and fields.
+		if dot := strings.Index(name, "."); dot >= 0 {
+			_, ok := syms[nameKey{pkg: pkg, name: name[:dot]}]
+			return ok
 		}
-		prev, curr = curr, prev
+		return false
 	}
 
-	return prev[lb]
+	for i := range s.changes {
+		c := &s.changes[i]
+		newPkg := c.NewPackage
+		if newPkg == "" {
+			newPkg = c.Package
+		}
+		newName := c.NewName
+		if newName == "" {
+			newName = c.Symbol
+		}
+		if affectedPkg(c.Package) || affectedPkg(c.NewPackage) ||
+			affectedSym(c.Package, c.Symbol) || affectedSym(newPkg, newName) {
+			c.LowTrust = true
+		}
+	}
 }
 
-// nameSimilarity returns the normalized Levenshtein similarity between two strings.
-// Returns a value in [0.0, 1.0] where 1.0 means identical.
-func nameSimilarity(a, b string) float64 {
-	if len(a) == 0 && len(b) == 0 {
-		return 1.0
-	}
-	maxLen := max(len(a), len(b))
-	return 1.0 - float64(levenshteinDistance(a, b))/float64(maxLen)
+// levenshteinDistance computes the edit distance between two strings, counting runes.
+func levenshteinDistance(a, b string) int {
+	return runeLevenshtein([]rune(a), []rune(b))
 }
 
 // paramOverlap computes the Jaccard similarity of parameter type multisets.
@@ -365,7 +365,7 @@ func TestDiffExports_Pass4_TypeRenameCorrelation(t *testing.T) {
 
 func TestDiffExports_Pass5_FuzzyMatch(t *testing.T) {
 	// ProcessRequest(ctx, name, opts) vs ProcessReq(ctx, name, opts, extra)
-	// nameSimilarity ~0.71 >= 0.7, paramOverlap: 4 overlap out of 5 union = 0.8 >= 0.8
+	// Req abbreviates Request, paramOverlap: 4 overlap out of 5 union = 0.8 >= 0.8
 	oldSigs := FuncSigMap{
 		{pkg: "mod", kind: symbols.SymbolFunc, name: "ProcessRequest"}: {
 			params: []string{"context.Context", "string", "int"}, results: []string{"error"},
@@ -596,6 +596,8 @@ func TestLevenshteinDistance(t *testing.T) {
 		{"kitten", "sitting", 3},
 		{"Get", "Set", 1},
 		{"ProcessRequest", "ProcessReq", 4},
+		{"Größe", "Grösse", 2},
+		{"héllo", "hello", 1},
 	}
 	for _, tt := range tests {
 		got := levenshteinDistance(tt.a, tt.b)
@@ -605,27 +607,6 @@ func TestLevenshteinDistance(t *testing.T) {
 	}
 }
 
-func TestNameSimilarity(t *testing.T) {
-	tests := []struct {
-		a, b    string
-		wantMin float64
-		wantMax float64
-	}{
-		{"abc", "abc", 1.0, 1.0},
-		{"", "", 1.0, 1.0},
-		{"Get", "Set", 0.60, 0.70},
-		{"ProcessRequest", "ProcessReq", 0.70, 0.75},
-		{"Initialize", "Initialise", 0.85, 1.0},
-	}
-	for _, tt := range tests {
-		got := nameSimilarity(tt.a, tt.b)
-		if got < tt.wantMin || got > tt.wantMax {
-			t.Errorf("nameSimilarity(%q, %q) = %.3f, want [%.2f, %.2f]",
-				tt.a, tt.b, got, tt.wantMin, tt.wantMax)
-		}
-	}
-}
-
 func TestParamOverlap(t *testing.T) {
 	tests := []struct {
 		name string
@@ -697,3 +678,75 @@ func TestDiffExports_ChangeFieldSemantics(t *testing.T) {
 		t.Errorf("NewName = %q, want NewFunc (new side)", c.NewName)
 	}
 }
+
+func TestDiffExports_LowTrust(t *testing.T) {
+	old := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolFunc, Name: "Parse", Package: "mod/broken", Signature: "(string) error"},
+		{Kind: symbols.SymbolFunc, Name: "Other", Package: "mod/ok", Signature: "(int) error"},
+		{Kind: symbols.SymbolType, Name: "Buf", Package: "mod/ok", Signature: "struct{Data [unknown]byte}"},
+		{Kind: symbols.SymbolField, Name: "Buf.Data", Package: "mod/ok", Signature: "[unknown]byte"},
+		{Kind: symbols.SymbolFunc, Name: "Deep", Package: "mod/skipped/sub", Signature: "()"},
+	})
+	new := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolType, Name: "Buf", Package: "mod/ok", Signature: "struct{Data [unknown]int}"},
+		{Kind: symbols.SymbolField, Name: "Buf.Data", Package: "mod/ok", Signature: "[unknown]int"},
+	})
+	new.Diagnostics = []changespec.Diagnostic{
+		{Kind: changespec.DiagnosticParseError, Package: "mod/broken", File: "broken/a.go", Message: "syntax error"},
+		{Kind: changespec.DiagnosticUnsupported, Package: "mod/ok", Symbol: "Buf", Message: "unsupported"},
+		{Kind: changespec.DiagnosticSkippedDir, Package: "mod/skipped", Message: "permission denied"},
+	}
+
+	changes := DiffExports(old, new, nil, nil)
+
+	want := map[string]bool{
+		"Parse":    true, // package had a parse error
+		"Other":    false,
+		"Buf":      true, // symbol had an unsupported construct
+		"Buf.Data": true, // member of that symbol
+		"Deep":     true, // below a skipped directory
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %d: %+v", len(want), len(changes), changes)
+	}
+	for _, c := range changes {
+		if c.LowTrust != want[c.Symbol] {
+			t.Errorf("%s low_trust = %v, want %v", c.Symbol, c.LowTrust, want[c.Symbol])
+		}
+	}
+}
+
+func TestDiffExports_Locations(t *testing.T) {
+	oldSyms, oldSigs, err := ParseExports(context.Background(), filepath.Join(testdataDir(t), "old"), "github.com/acme/testmod")
+	if err != nil {
+		t.Fatalf("ParseExports old: %v", err)
+	}
+	newSyms, newSigs, err := ParseExports(context.Background(), filepath.Join(testdataDir(t), "new"), "github.com/acme/testmod")
+	if err != nil {
+		t.Fatalf("ParseExports new: %v", err)
+	}
+
+	changes := DiffExports(oldSyms, newSyms, oldSigs, newSigs)
+
+	for _, c := range changes {
+		switch c.Symbol {
+		case "HelperFunc":
+			if c.OldLocation == nil || c.OldLocation.String() != "foo.go:14:6" {
+				t.Errorf("HelperFunc old location = %v, want foo.go:14:6", c.OldLocation)
+			}
+			if c.NewLocation == nil || c.NewLocation.String() != "foo.go:17:6" {
+				t.Errorf("HelperFunc new location = %v, want foo.go:17:6", c.NewLocation)
+			}
+			if c.Doc != "HelperFunc renamed to HelperFunction (same signature)." {
+				t.Errorf("HelperFunc doc = %q", c.Doc)
+			}
+		case "OldOnly":
+			if c.OldLocation == nil || c.OldLocation.File != "foo.go" {
+				t.Errorf("OldOnly old location = %v", c.OldLocation)
+			}
+			if c.NewLocation != nil {
+				t.Errorf("removed symbol has new location %v", c.NewLocation)
+			}
+		}
+	}
+}
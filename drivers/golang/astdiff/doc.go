@@ -0,0 +1,53 @@
+package astdiff
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// maxDocExcerpt is the maximum length in bytes of a doc comment excerpt.
+const maxDocExcerpt = 300
+
+// specDoc returns the doc comment of a type or value spec, falling back to the
+// enclosing declaration's doc for ungrouped declarations (type Foo struct{...}).
+func specDoc(genDecl *ast.GenDecl, doc *ast.CommentGroup) *ast.CommentGroup {
+	if doc != nil {
+		return doc
+	}
+	if !genDecl.Lparen.IsValid() {
+		return genDecl.Doc
+	}
+	return nil
+}
+
+// docExcerpt returns the first paragraph of a doc comment with whitespace
+// collapsed, followed by any "Deprecated:" paragraph, since that is usually the
+// migration hint reviewers look for. The result is truncated to maxDocExcerpt bytes.
+func docExcerpt(cg *ast.CommentGroup) string {
+	if cg == nil {
+		return ""
+	}
+
+	paragraphs := strings.Split(strings.TrimSpace(cg.Text()), "\n\n")
+	if len(paragraphs) == 0 || paragraphs[0] == "" {
+		return ""
+	}
+
+	excerpt := strings.Join(strings.Fields(paragraphs[0]), " ")
+	for _, p := range paragraphs[1:] {
+		if strings.HasPrefix(p, "Deprecated:") {
+			excerpt += " " + strings.Join(strings.Fields(p), " ")
+			break
+		}
+	}
+
+	if len(excerpt) > maxDocExcerpt {
+		cut := maxDocExcerpt
+		// Back up to a rune boundary.
+		for cut > 0 && excerpt[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		excerpt = excerpt[:cut] + "..."
+	}
+	return excerpt
+}
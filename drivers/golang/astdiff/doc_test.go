@@ -0,0 +1,89 @@
+package astdiff
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestDocExcerpt(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"none", "", ""},
+		{"single_line", "// Do sends a request.\n", "Do sends a request."},
+		{"wrapped", "// Do sends a request\n// and waits for the reply.\n", "Do sends a request and waits for the reply."},
+		{"first_paragraph", "// Do sends a request.\n//\n// Details follow here.\n", "Do sends a request."},
+		{
+			"deprecated",
+			"// Do sends a request.\n//\n// Details.\n//\n// Deprecated: use DoContext.\n",
+			"Do sends a request. Deprecated: use DoContext.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "package p\n\n" + tt.doc + "func Do() {}\n"
+			file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ParseComments)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			got := docExcerpt(file.Decls[0].(*ast.FuncDecl).Doc)
+			if got != tt.want {
+				t.Errorf("docExcerpt = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocExcerpt_Truncated(t *testing.T) {
+	long := strings.Repeat("é", maxDocExcerpt)
+	cg := &ast.CommentGroup{List: []*ast.Comment{{Text: "// " + long}}}
+
+	got := docExcerpt(cg)
+	if !strings.HasSuffix(got, "...") {
+		t.Fatalf("expected truncation marker, got %q", got)
+	}
+	body := strings.TrimSuffix(got, "...")
+	if len(body) > maxDocExcerpt {
+		t.Errorf("excerpt length %d exceeds %d", len(body), maxDocExcerpt)
+	}
+	if !strings.HasSuffix(body, "é") {
+		t.Errorf("excerpt cut inside a rune: %q", body[len(body)-4:])
+	}
+}
+
+func TestSpecDoc(t *testing.T) {
+	src := `package p
+
+// Single is documented on the declaration.
+type Single struct{}
+
+type (
+	// Grouped is documented on the spec.
+	Grouped int
+	Bare    int
+)
+`
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	single := file.Decls[0].(*ast.GenDecl)
+	if got := docExcerpt(specDoc(single, single.Specs[0].(*ast.TypeSpec).Doc)); got != "Single is documented on the declaration." {
+		t.Errorf("Single doc = %q", got)
+	}
+
+	group := file.Decls[1].(*ast.GenDecl)
+	if got := docExcerpt(specDoc(group, group.Specs[0].(*ast.TypeSpec).Doc)); got != "Grouped is documented on the spec." {
+		t.Errorf("Grouped doc = %q", got)
+	}
+	if got := specDoc(group, group.Specs[1].(*ast.TypeSpec).Doc); got != nil {
+		t.Errorf("Bare doc = %v, want nil", got)
+	}
+}
@@ -0,0 +1,323 @@
+package astdiff
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+// Candidate blocking for Pass 5.
+//
+// Comparing every unmatched old function with every unmatched new one is
+// quadratic in the number of unmatched functions. The index below only proposes
+// pairs that can still clear the parameter overlap threshold, so the candidate
+// set is identical to the exhaustive comparison:
+//
+//   - Parameter overlap is a Jaccard similarity over type multisets. Two sets with
+//     Jaccard >= t share a token within the first n-ceil(t*n)+1 tokens of each,
+//     when tokens are ordered the same way on both sides (prefix filtering). Tokens
+//     are ordered rarest first so common types like error or context.Context
+//     rarely end up in a prefix.
+//   - Jaccard >= t also bounds the size ratio of the two multisets.
+//   - Name similarity is max(tokens, (chars+tokens)/2). The token score is cheap
+//     from names tokenized once per entry, and fixes the character similarity a
+//     pair needs to clear the name threshold or to raise the score at all. That
+//     bounds the edit distance, so the remaining pairs use a banded edit distance
+//     that stops once the bound is exceeded.
+
+// sigToken is one occurrence of a type in a signature. Numbering repeated types
+// turns the multiset Jaccard used by paramOverlap into a plain set Jaccard.
+type sigToken struct {
+	typ string
+	n   int
+}
+
+// fuzzyEntry is one function or method taking part in fuzzy matching.
+type fuzzyEntry struct {
+	key     symbolKey
+	name    string
+	profile nameProfile
+	sig     funcSignature
+	tokens  []sigToken // rarest first, see tokenRanks
+}
+
+// fuzzyIndex proposes new-side candidates for old-side functions.
+type fuzzyIndex struct {
+	entries  []fuzzyEntry
+	postings map[sigToken][]int // prefix token -> indexes into entries
+	empty    []int              // entries with no params and no results
+
+	// seen and stamp deduplicate candidates across postings lists.
+	seen  []int
+	stamp int
+}
+
+// signatureTokens expands a signature into its occurrence-numbered type tokens.
+func signatureTokens(sig funcSignature) []sigToken {
+	tokens := make([]sigToken, 0, len(sig.params)+len(sig.results))
+	counts := make(map[string]int, cap(tokens))
+	for _, list := range [][]string{sig.params, sig.results} {
+		for _, typ := range list {
+			counts[typ]++
+			tokens = append(tokens, sigToken{typ: typ, n: counts[typ]})
+		}
+	}
+	return tokens
+}
+
+// newFuzzyEntries builds entries for keys, ordering tokens by rank.
+func newFuzzyEntries(keys []symbolKey, byKey map[symbolKey]*symbols.Symbol, sigs FuncSigMap, rank map[sigToken]int, names *nameMatcher) []fuzzyEntry {
+	entries := make([]fuzzyEntry, len(keys))
+	for i, key := range keys {
+		sig := sigs[key]
+		tokens := signatureTokens(sig)
+		sort.Slice(tokens, func(a, b int) bool { return rank[tokens[a]] < rank[tokens[b]] })
+		name := byKey[key].Name
+		entries[i] = fuzzyEntry{key: key, name: name, profile: names.profile(name), sig: sig, tokens: tokens}
+	}
+	return entries
+}
+
+// tokenRanks orders tokens rarest first across both sides, breaking ties by
+// type and occurrence so the order is total.
+func tokenRanks(sigMaps []FuncSigMap, keySets [][]symbolKey) map[sigToken]int {
+	freq := make(map[sigToken]int)
+	for i, keys := range keySets {
+		for _, key := range keys {
+			for _, tok := range signatureTokens(sigMaps[i][key]) {
+				freq[tok]++
+			}
+		}
+	}
+	tokens := make([]sigToken, 0, len(freq))
+	for tok := range freq {
+		tokens = append(tokens, tok)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		a, b := tokens[i], tokens[j]
+		if freq[a] != freq[b] {
+			return freq[a] < freq[b]
+		}
+		if a.typ != b.typ {
+			return a.typ < b.typ
+		}
+		return a.n < b.n
+	})
+	rank := make(map[sigToken]int, len(tokens))
+	for i, tok := range tokens {
+		rank[tok] = i
+	}
+	return rank
+}
+
+// prefixLength returns how many leading tokens of an n-token set must be indexed
+// or probed so that any pair with Jaccard >= minOverlap shares a prefix token.
+func prefixLength(n int, minOverlap float64) int {
+	// Round the required overlap down slightly so float error can only lengthen
+	// the prefix, never drop a qualifying pair.
+	required := int(math.Ceil(minOverlap*float64(n) - 1e-9))
+	return min(n, max(1, n-required+1))
+}
+
+// newFuzzyIndex indexes the new-side entries.
+func newFuzzyIndex(entries []fuzzyEntry, minOverlap float64) *fuzzyIndex {
+	idx := &fuzzyIndex{
+		entries:  entries,
+		postings: make(map[sigToken][]int),
+		seen:     make([]int, len(entries)),
+	}
+	for i, e := range entries {
+		if len(e.tokens) == 0 {
+			idx.empty = append(idx.empty, i)
+			continue
+		}
+		for _, tok := range e.tokens[:prefixLength(len(e.tokens), minOverlap)] {
+			idx.postings[tok] = append(idx.postings[tok], i)
+		}
+	}
+	return idx
+}
+
+// candidates returns the indexes of new entries that may reach minOverlap with
+// old. A signature with no types only overlaps another with no types.
+func (idx *fuzzyIndex) candidates(old fuzzyEntry, minOverlap float64) []int {
+	if len(old.tokens) == 0 {
+		return idx.empty
+	}
+	idx.stamp++
+	var out []int
+	n := len(old.tokens)
+	for _, tok := range old.tokens[:prefixLength(n, minOverlap)] {
+		for _, i := range idx.postings[tok] {
+			if idx.seen[i] == idx.stamp {
+				continue
+			}
+			idx.seen[i] = idx.stamp
+			// Size filter: |A∩B|/|A∪B| <= min/max.
+			m := len(idx.entries[i].tokens)
+			if float64(min(n, m)) < minOverlap*float64(max(n, m))-1e-9 {
+				continue
+			}
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// maxNameDistance returns the largest edit distance between names whose longer
+// length is maxLen that still gives a character similarity of at least
+// threshold, evaluated with the same float expression as editSimilarity.
+// Returns -1 when no distance qualifies.
+func maxNameDistance(maxLen int, threshold float64) int {
+	if maxLen == 0 {
+		if 1.0 >= threshold {
+			return 0
+		}
+		return -1
+	}
+	k := int(math.Floor((1 - threshold) * float64(maxLen)))
+	k = min(max(k, -1), maxLen)
+	for k < maxLen && 1.0-float64(k+1)/float64(maxLen) >= threshold {
+		k++
+	}
+	for k >= 0 && 1.0-float64(k)/float64(maxLen) < threshold {
+		k--
+	}
+	return k
+}
+
+// boundedLevenshtein returns the edit distance between a and b if it is at most
+// k, computing only the diagonal band of width 2k+1. Returns false otherwise.
+func boundedLevenshtein(a, b []rune, k int) (int, bool) {
+	la, lb := len(a), len(b)
+	if k < 0 || abs(la-lb) > k {
+		return 0, false
+	}
+	if la == 0 || lb == 0 {
+		return max(la, lb), true
+	}
+
+	const inf = math.MaxInt / 2
+	prev := make([]int, lb+1)
+	curr := make([]int, lb+1)
+	for j := range prev {
+		if j <= k {
+			prev[j] = j
+		} else {
+			prev[j] = inf
+		}
+	}
+
+	for i := 1; i <= la; i++ {
+		lo, hi := max(1, i-k), min(lb, i+k)
+		if lo > 1 {
+			curr[lo-1] = inf
+		}
+		if i <= k {
+			curr[0] = i
+		} else {
+			curr[0] = inf
+		}
+		rowMin := curr[0]
+		for j := lo; j <= hi; j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		if hi < lb {
+			curr[hi+1] = inf
+		}
+		if rowMin > k {
+			return 0, false
+		}
+		prev, curr = curr, prev
+	}
+
+	if prev[lb] > k {
+		return 0, false
+	}
+	return prev[lb], true
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// fuzzyScore applies the Pass 5 thresholds to one pair and returns the composite
+// score of name similarity and parameter overlap. The character similarity is
+// only computed within the edit distance that can still matter: at least the
+// token score to raise the similarity, and when the token score alone misses
+// the threshold, enough to lift the average over it. Results equal
+// nameSimilarity whenever the pair qualifies.
+func fuzzyScore(names *nameMatcher, old, new fuzzyEntry) (float64, bool) {
+	overlap := paramOverlap(old.sig, new.sig)
+	if overlap < MinParamOverlap {
+		return 0, false
+	}
+
+	maxLen := max(len(old.profile.runes), len(new.profile.runes))
+	nameThreshold := MinNameSimilarity
+	if maxLen < ShortNameLength {
+		nameThreshold = ShortNameMinSimilarity
+	}
+
+	tokens := names.tokenScore(old.profile, new.profile)
+	needed := tokens
+	if tokens < nameThreshold {
+		needed = 2*nameThreshold - tokens
+	}
+	// Loosen the bound slightly so float error can only widen the band.
+	nameSim := tokens
+	if dist, ok := boundedLevenshtein(old.profile.runes, new.profile.runes, maxNameDistance(maxLen, needed-1e-9)); ok {
+		chars := 1.0
+		if maxLen > 0 {
+			chars = 1.0 - float64(dist)/float64(maxLen)
+		}
+		nameSim = combineSimilarity(tokens, chars)
+	}
+	if nameSim < nameThreshold {
+		return 0, false
+	}
+	return nameSim * overlap, true
+}
+
+// sortCandidates orders candidate pairs backed by upstream history first, then
+// by descending score, then by old and new symbol identity so greedy matching
+// is deterministic.
+func sortCandidates(candidates []scoredPair) {
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if (a.hint != nil) != (b.hint != nil) {
+			return a.hint != nil
+		}
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.oldName != b.oldName {
+			return a.oldName < b.oldName
+		}
+		if c := compareKeys(a.oldKey, b.oldKey); c != 0 {
+			return c < 0
+		}
+		return compareKeys(a.newKey, b.newKey) < 0
+	})
+}
+
+// compareKeys orders symbol keys by package, kind and name.
+func compareKeys(a, b symbolKey) int {
+	if c := strings.Compare(a.pkg, b.pkg); c != 0 {
+		return c
+	}
+	if c := strings.Compare(string(a.kind), string(b.kind)); c != 0 {
+		return c
+	}
+	return strings.Compare(a.name, b.name)
+}
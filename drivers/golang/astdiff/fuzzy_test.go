@@ -0,0 +1,375 @@
+package astdiff
+
+import (
+	"fmt"
+	"maps"
+	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+// exhaustiveFuzzyCandidates is the reference Pass 5 candidate generation:
+// every old function scored against every new one with the full name similarity.
+func exhaustiveFuzzyCandidates(s *diffState, oldKeys, newKeys []symbolKey) []scoredPair {
+	newProfiles := make([]nameProfile, len(newKeys))
+	for i, newKey := range newKeys {
+		newProfiles[i] = s.names.profile(s.newByKey[newKey].Name)
+	}
+
+	var candidates []scoredPair
+	for _, oldKey := range oldKeys {
+		oldSym := s.oldByKey[oldKey]
+		oldSig := s.oldSigs[oldKey]
+		oldProfile := s.names.profile(oldSym.Name)
+
+		for i, newKey := range newKeys {
+			newSym := s.newByKey[newKey]
+			newSig := s.newSigs[newKey]
+
+			nameSim := s.names.similarity(oldProfile, newProfiles[i])
+			overlap := paramOverlap(oldSig, newSig)
+
+			nameThreshold := MinNameSimilarity
+			if max(utf8.RuneCountInString(oldSym.Name), utf8.RuneCountInString(newSym.Name)) < ShortNameLength {
+				nameThreshold = ShortNameMinSimilarity
+			}
+
+			if nameSim >= nameThreshold && overlap >= MinParamOverlap {
+				candidates = append(candidates, scoredPair{
+					oldKey:  oldKey,
+					newKey:  newKey,
+					score:   nameSim * overlap,
+					oldName: oldSym.Name,
+				})
+			}
+		}
+	}
+	return candidates
+}
+
+// funcKeys returns the keys of sigs in deterministic order.
+func funcKeys(sigs FuncSigMap) []symbolKey {
+	keys := make([]symbolKey, 0, len(sigs))
+	for k := range sigs {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, compareKeys)
+	return keys
+}
+
+var fuzzTypePool = []string{"string", "int", "error", "bool", "context.Context", "[]byte", "*Config"}
+
+// nameGen produces a random function name; nameMutator derives a renamed one.
+type (
+	nameGen     func(rng *rand.Rand) string
+	nameMutator func(rng *rand.Rand, name string) string
+)
+
+// randomCharName builds names over a small alphabet, so character similarity
+// decides most pairs.
+func randomCharName(rng *rand.Rand) string {
+	name := make([]byte, 1+rng.Intn(12))
+	for i := range name {
+		name[i] = "abcAB"[rng.Intn(5)]
+	}
+	return string(name)
+}
+
+// mutateChars applies a few single-character edits to name.
+func mutateChars(rng *rand.Rand, name string) string {
+	b := []byte(name)
+	for range rng.Intn(4) {
+		i := rng.Intn(len(b))
+		switch rng.Intn(3) {
+		case 0:
+			b[i] = "abcAB"[rng.Intn(5)]
+		case 1:
+			b = slices.Insert(b, i, "abcAB"[rng.Intn(5)])
+		default:
+			if len(b) > 1 {
+				b = slices.Delete(b, i, i+1)
+			}
+		}
+	}
+	return string(b)
+}
+
+var (
+	fuzzVerbPool   = []string{"Get", "Fetch", "Read", "Load", "Remove", "Delete", "Drop", "Set", "Put", "Find", "Lookup", "Close"}
+	fuzzNounPool   = []string{"User", "Users", "Req", "Request", "Item", "Items", "Config", "Conf", "Token"}
+	fuzzAffixPool  = []string{"", "", "WithContext", "Ctx", "V2"}
+	fuzzVerbGroups = map[string][]string{}
+)
+
+func init() {
+	for _, group := range DefaultSynonyms {
+		for _, w := range group {
+			if slices.Contains(fuzzVerbPool, w) {
+				fuzzVerbGroups[w] = group
+			}
+		}
+	}
+}
+
+// randomWordName builds a verb-noun-affix name, so token similarity with its
+// synonym, abbreviation and affix rules decides most pairs.
+func randomWordName(rng *rand.Rand) string {
+	name := fuzzVerbPool[rng.Intn(len(fuzzVerbPool))]
+	for range 1 + rng.Intn(2) {
+		name += fuzzNounPool[rng.Intn(len(fuzzNounPool))]
+	}
+	return name + fuzzAffixPool[rng.Intn(len(fuzzAffixPool))]
+}
+
+// mutateWords swaps the verb for a synonym, adds or drops an affix, or edits
+// characters.
+func mutateWords(rng *rand.Rand, name string) string {
+	switch rng.Intn(4) {
+	case 0:
+		for _, verb := range fuzzVerbPool {
+			if rest, ok := strings.CutPrefix(name, verb); ok {
+				group := fuzzVerbGroups[verb]
+				return group[rng.Intn(len(group))] + rest
+			}
+		}
+		return name
+	case 1:
+		for _, affix := range fuzzAffixPool {
+			if rest, ok := strings.CutSuffix(name, affix); ok && affix != "" {
+				return rest
+			}
+		}
+		return name + fuzzAffixPool[2+rng.Intn(len(fuzzAffixPool)-2)]
+	case 2:
+		return mutateChars(rng, name)
+	default:
+		return name
+	}
+}
+
+// randomFuncs builds n functions with names from gen and signatures over a
+// small type pool.
+func randomFuncs(rng *rand.Rand, n int, gen nameGen) FuncSigMap {
+	sigs := make(FuncSigMap)
+	for len(sigs) < n {
+		var sig funcSignature
+		for range rng.Intn(6) {
+			sig.params = append(sig.params, fuzzTypePool[rng.Intn(len(fuzzTypePool))])
+		}
+		for range rng.Intn(3) {
+			sig.results = append(sig.results, fuzzTypePool[rng.Intn(len(fuzzTypePool))])
+		}
+		sigs[symbolKey{pkg: "mod", kind: symbols.SymbolFunc, name: gen(rng)}] = sig
+	}
+	return sigs
+}
+
+// mutateFuncs derives a new version from old with mutate applied to each name
+// and a small edit to each signature, so pairs near both thresholds are common.
+func mutateFuncs(rng *rand.Rand, old FuncSigMap, mutate nameMutator) FuncSigMap {
+	sigs := make(FuncSigMap, len(old))
+	for _, key := range funcKeys(old) {
+		sig := funcSignature{params: slices.Clone(old[key].params), results: old[key].results}
+		switch rng.Intn(3) {
+		case 0:
+			sig.params = append(sig.params, fuzzTypePool[rng.Intn(len(fuzzTypePool))])
+		case 1:
+			if len(sig.params) > 0 {
+				sig.params = sig.params[1:]
+			}
+		}
+		sigs[symbolKey{pkg: key.pkg, kind: key.kind, name: mutate(rng, key.name)}] = sig
+	}
+	return sigs
+}
+
+// symbolsFor builds a symbol set matching sigs.
+func symbolsFor(sigs FuncSigMap) symbols.Symbols {
+	syms := symbols.Symbols{Module: "mod"}
+	for _, key := range funcKeys(sigs) {
+		syms.Entries = append(syms.Entries, symbols.Symbol{
+			Kind: key.kind, Name: key.name, Package: key.pkg,
+			Signature: fmt.Sprint(sigs[key].params, sigs[key].results),
+		})
+	}
+	return syms
+}
+
+func TestFuzzyCandidates_MatchExhaustive(t *testing.T) {
+	tests := []struct {
+		name   string
+		gen    nameGen
+		mutate nameMutator
+	}{
+		{name: "characters", gen: randomCharName, mutate: mutateChars},
+		{name: "synonyms and affixes", gen: randomWordName, mutate: mutateWords},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for seed := int64(1); seed <= 20; seed++ {
+				t.Run(fmt.Sprint("seed", seed), func(t *testing.T) {
+					t.Parallel()
+					rng := rand.New(rand.NewSource(seed))
+					oldSigs := randomFuncs(rng, 200, tt.gen)
+					newSigs := mutateFuncs(rng, oldSigs, tt.mutate)
+					maps.Copy(newSigs, randomFuncs(rng, 50, tt.gen))
+
+					s := newDiffState(symbolsFor(oldSigs), symbolsFor(newSigs), oldSigs, newSigs)
+					oldKeys, newKeys := funcKeys(oldSigs), funcKeys(newSigs)
+
+					want := exhaustiveFuzzyCandidates(s, oldKeys, newKeys)
+					got := s.fuzzyCandidates(oldKeys, newKeys)
+					sortCandidates(want)
+					sortCandidates(got)
+
+					if len(want) == 0 {
+						t.Fatal("reference produced no candidates; generator too sparse")
+					}
+					if !reflect.DeepEqual(got, want) {
+						t.Errorf("indexed candidates differ from exhaustive: got %d, want %d", len(got), len(want))
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestBoundedLevenshtein(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randString := func() string {
+		b := make([]rune, rng.Intn(12))
+		for i := range b {
+			b[i] = []rune("abcé")[rng.Intn(4)]
+		}
+		return string(b)
+	}
+	for range 2000 {
+		a, b := randString(), randString()
+		want := levenshteinDistance(a, b)
+		for k := -1; k <= 12; k++ {
+			got, ok := boundedLevenshtein([]rune(a), []rune(b), k)
+			if ok != (want <= k) || (ok && got != want) {
+				t.Fatalf("boundedLevenshtein(%q, %q, %d) = (%d, %v), distance %d", a, b, k, got, ok, want)
+			}
+		}
+	}
+}
+
+func TestMaxNameDistance(t *testing.T) {
+	for _, threshold := range []float64{0, 0.5, MinNameSimilarity, ShortNameMinSimilarity, 1, 1.3} {
+		for maxLen := 0; maxLen <= 40; maxLen++ {
+			k := maxNameDistance(maxLen, threshold)
+			for d := 0; d <= maxLen; d++ {
+				sim := 1.0
+				if maxLen > 0 {
+					sim = 1.0 - float64(d)/float64(maxLen)
+				}
+				if (sim >= threshold) != (d <= k) {
+					t.Fatalf("maxNameDistance(%d, %v) = %d, but distance %d has similarity %v", maxLen, threshold, k, d, sim)
+				}
+			}
+		}
+	}
+}
+
+// syntheticSDK builds old and new versions of a generated-SDK-shaped module with
+// n methods spread over service clients. Every stride-th method is renamed and
+// gains a parameter, so it survives the exact passes and reaches Pass 5.
+func syntheticSDK(n, stride int) (old, new symbols.Symbols, oldSigs, newSigs FuncSigMap) {
+	const pkg = "github.com/acme/sdk"
+	old = symbols.Symbols{Module: pkg}
+	new = symbols.Symbols{Module: pkg}
+	oldSigs, newSigs = make(FuncSigMap), make(FuncSigMap)
+
+	verbs := []string{"Get", "List", "Create", "Update", "Delete", "Describe"}
+	add := func(syms *symbols.Symbols, sigs FuncSigMap, recv, name string, sig funcSignature) {
+		full := recv + "." + name
+		sigs[symbolKey{pkg: pkg, kind: symbols.SymbolMethod, name: full}] = sig
+		syms.Entries = append(syms.Entries, symbols.Symbol{
+			Kind: symbols.SymbolMethod, Name: full, Package: pkg, Receiver: recv,
+			Signature: "(" + strings.Join(sig.params, ", ") + ") (" + strings.Join(sig.results, ", ") + ")",
+		})
+	}
+
+	for i := range n {
+		recv := fmt.Sprintf("Service%dClient", i%200)
+		resource := fmt.Sprintf("Resource%d", i)
+		name := verbs[i%len(verbs)] + resource
+		sig := funcSignature{
+			params:  []string{"context.Context", "*" + name + "Input", "...request.Option"},
+			results: []string{"*" + name + "Output", "error"},
+		}
+		add(&old, oldSigs, recv, name, sig)
+
+		if i%stride != 0 {
+			add(&new, newSigs, recv, name, sig)
+			continue
+		}
+		renamed := funcSignature{
+			params:  append(append([]string{}, sig.params...), "*"+name+"Options"),
+			results: sig.results,
+		}
+		add(&new, newSigs, recv, name+"WithContext", renamed)
+	}
+	return old, new, oldSigs, newSigs
+}
+
+func BenchmarkDiffExports_SyntheticSDK20k(b *testing.B) {
+	old, new, oldSigs, newSigs := syntheticSDK(20000, 10)
+	b.ResetTimer()
+	for range b.N {
+		DiffExports(old, new, oldSigs, newSigs)
+	}
+}
+
+func BenchmarkFuzzyCandidates_Indexed20k(b *testing.B) {
+	s, oldKeys, newKeys := syntheticUnmatched(b)
+	b.ResetTimer()
+	for range b.N {
+		s.fuzzyCandidates(oldKeys, newKeys)
+	}
+}
+
+func BenchmarkFuzzyCandidates_Exhaustive20k(b *testing.B) {
+	s, oldKeys, newKeys := syntheticUnmatched(b)
+	b.ResetTimer()
+	for range b.N {
+		exhaustiveFuzzyCandidates(s, oldKeys, newKeys)
+	}
+}
+
+// syntheticUnmatched runs the passes preceding Pass 5 on the synthetic SDK and
+// returns the function keys left for fuzzy matching.
+func syntheticUnmatched(b *testing.B) (*diffState, []symbolKey, []symbolKey) {
+	b.Helper()
+	old, new, oldSigs, newSigs := syntheticSDK(20000, 10)
+	s := newDiffState(old, new, oldSigs, newSigs)
+	s.tagChanged()
+	s.aliasMoves()
+	s.exactMatch()
+	s.changed()
+	s.renamed()
+	s.correlateMethods()
+
+	var oldKeys, newKeys []symbolKey
+	for key := range s.unmatchedOldSet {
+		if _, ok := s.oldSigs[key]; ok {
+			oldKeys = append(oldKeys, key)
+		}
+	}
+	for key := range s.unmatchedNewSet {
+		if _, ok := s.newSigs[key]; ok {
+			newKeys = append(newKeys, key)
+		}
+	}
+	if len(oldKeys) == 0 || len(newKeys) == 0 {
+		b.Fatal("synthetic module left nothing for Pass 5")
+	}
+	return s, oldKeys, newKeys
+}
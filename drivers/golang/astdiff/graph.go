@@ -0,0 +1,217 @@
+package astdiff
+
+import (
+	"go/ast"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/emenda-labs/emenda/core/changespec"
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+// typeRefs returns the exported module declarations referenced by exprs, fully
+// qualified and sorted. Identifiers in skip are type parameters, not package
+// declarations. Field, parameter and method names are not references.
+func (c *packageCollector) typeRefs(skip map[string]bool, exprs ...ast.Expr) []string {
+	seen := make(map[string]bool)
+	var refs []string
+	add := func(ref string) {
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	var visit func(ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.Field:
+			ast.Inspect(x.Type, visit)
+			return false
+		case *ast.SelectorExpr:
+			pkg, ok := x.X.(*ast.Ident)
+			if !ok || !x.Sel.IsExported() {
+				return false
+			}
+			if path, ok := c.imports[pkg.Name]; ok && inModule(path, c.module) {
+				add(path + "." + x.Sel.Name)
+			}
+			return false
+		case *ast.Ident:
+			if x.IsExported() && !skip[x.Name] {
+				add(c.pkgPath + "." + x.Name)
+			}
+		}
+		return true
+	}
+	for _, expr := range exprs {
+		if expr != nil {
+			ast.Inspect(expr, visit)
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// inModule reports whether the import path belongs to module.
+func inModule(path, module string) bool {
+	return path == module || strings.HasPrefix(path, module+"/")
+}
+
+// fieldNames returns the names declared by a field list, e.g. type parameters.
+func fieldNames(fields *ast.FieldList) map[string]bool {
+	if fields == nil {
+		return nil
+	}
+	names := make(map[string]bool)
+	for _, field := range fields.List {
+		for _, name := range field.Names {
+			names[name.Name] = true
+		}
+	}
+	return names
+}
+
+// typeParamNames returns the type parameters in scope for a function or method
+// declaration, including those bound by a generic receiver (func (l *List[T]) ...).
+func typeParamNames(funcDecl *ast.FuncDecl) map[string]bool {
+	names := fieldNames(funcDecl.Type.TypeParams)
+	if funcDecl.Recv == nil || len(funcDecl.Recv.List) == 0 {
+		return names
+	}
+	recv := funcDecl.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	var indices []ast.Expr
+	switch r := recv.(type) {
+	case *ast.IndexExpr:
+		indices = []ast.Expr{r.Index}
+	case *ast.IndexListExpr:
+		indices = r.Indices
+	}
+	for _, idx := range indices {
+		if ident, ok := idx.(*ast.Ident); ok {
+			if names == nil {
+				names = make(map[string]bool)
+			}
+			names[ident.Name] = true
+		}
+	}
+	return names
+}
+
+// dependencyGraph maps a module declaration to the exported symbols whose types
+// reference it, ordered by package and name.
+type dependencyGraph map[nameKey][]symbolKey
+
+// newDependencyGraph builds the reverse reference graph of a symbol set.
+// Synthesized alias members are skipped; the alias itself references its target.
+func newDependencyGraph(byKey map[symbolKey]*symbols.Symbol, synthetic map[symbolKey]struct{}) dependencyGraph {
+	g := make(dependencyGraph)
+	for key, sym := range byKey {
+		if _, ok := synthetic[key]; ok {
+			continue
+		}
+		for _, ref := range sym.Refs {
+			pkg, name := splitQualified(ref)
+			target := nameKey{pkg: pkg, name: name}
+			g[target] = append(g[target], key)
+		}
+	}
+	for _, deps := range g {
+		slices.SortFunc(deps, func(a, b symbolKey) int {
+			if c := strings.Compare(a.pkg, b.pkg); c != 0 {
+				return c
+			}
+			if c := strings.Compare(a.name, b.name); c != 0 {
+				return c
+			}
+			return strings.Compare(string(a.kind), string(b.kind))
+		})
+	}
+	return g
+}
+
+// affected walks the graph breadth first from origin and returns every symbol
+// reached. The walk continues through types and interfaces only: a function
+// taking a Config does not pass the change on, a struct embedding it does.
+// Members of a type that reference the type itself (Node.Next *Node) are skipped.
+func (g dependencyGraph) affected(origin nameKey, byKey map[symbolKey]*symbols.Symbol) []changespec.Affected {
+	type step struct {
+		node nameKey
+		via  []string
+	}
+	visitedTypes := map[nameKey]bool{origin: true}
+	visited := make(map[symbolKey]bool)
+	queue := []step{{node: origin, via: []string{origin.pkg + "." + origin.name}}}
+
+	var out []changespec.Affected
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, dep := range g[cur.node] {
+			if visited[dep] {
+				continue
+			}
+			visited[dep] = true
+
+			if dot := strings.Index(dep.name, "."); dot >= 0 && (nameKey{pkg: dep.pkg, name: dep.name[:dot]}) == cur.node {
+				continue
+			}
+			depNode := nameKey{pkg: dep.pkg, name: dep.name}
+			isType := dep.kind == symbols.SymbolType || dep.kind == symbols.SymbolInterface
+			if isType && visitedTypes[depNode] {
+				continue
+			}
+
+			sym := byKey[dep]
+			out = append(out, changespec.Affected{
+				Symbol:  sym.Name,
+				Package: sym.Package,
+				Kind:    string(dep.kind),
+				Via:     cur.via,
+			})
+			if isType {
+				visitedTypes[depNode] = true
+				via := append(slices.Clip(cur.via), depNode.pkg+"."+depNode.name)
+				queue = append(queue, step{node: depNode, via: via})
+			}
+		}
+	}
+	return out
+}
+
+// attachAffected records, for every change to a type or to a struct field, the
+// old-version symbols that reference the type directly or transitively. Field
+// changes propagate from their owning type, whose shape they change.
+func (s *diffState) attachAffected() {
+	graph := newDependencyGraph(s.oldByKey, s.oldAliasMembers)
+	if len(graph) == 0 {
+		return
+	}
+	for i := range s.changes {
+		c := &s.changes[i]
+		origin, ok := s.changeOrigin(c)
+		if !ok {
+			continue
+		}
+		c.Affected = graph.affected(origin, s.oldByKey)
+	}
+}
+
+// changeOrigin returns the old-version type a change propagates from.
+func (s *diffState) changeOrigin(c *changespec.Change) (nameKey, bool) {
+	name := c.Symbol
+	if dot := strings.Index(name, "."); dot >= 0 {
+		if _, ok := s.oldByKey[symbolKey{pkg: c.Package, kind: symbols.SymbolField, name: name}]; !ok {
+			return nameKey{}, false
+		}
+		name = name[:dot]
+	}
+	if _, _, ok := lookupType(s.oldByKey, c.Package, name); !ok {
+		return nameKey{}, false
+	}
+	return nameKey{pkg: c.Package, name: name}, true
+}
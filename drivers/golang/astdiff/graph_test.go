@@ -0,0 +1,173 @@
+package astdiff
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/emenda-labs/emenda/core/changespec"
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+func TestParseExports_Refs(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module github.com/acme/app\n",
+		"app.go": `package app
+
+import (
+	"context"
+
+	"github.com/acme/app/auth"
+)
+
+type Config struct {
+	Token auth.Token
+	Name  string
+}
+
+type Server struct {
+	Config
+	Limit [MaxConns]int
+}
+
+const MaxConns = 4
+
+type List[T any] struct{ Items []T }
+
+func (l *List[T]) Push(v T) {}
+
+type Client struct{}
+
+func (c *Client) Reload(ctx context.Context, cfg Config) error { return nil }
+
+func New(cfg Config) *Server { return nil }
+
+func Map[K comparable, V any](m map[K]V) []V { return nil }
+
+type Handler interface {
+	Handle(Request) Response
+}
+
+type Request struct{}
+type Response struct{}
+
+var Default = &Config{}
+var Fallback *Config
+`,
+		"auth/auth.go": "package auth\n\ntype Token string\n",
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "auth"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range files {
+		if err := writeFile(filepath.Join(dir, name), src); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	syms, _, err := ParseExports(context.Background(), dir, "github.com/acme/app")
+	if err != nil {
+		t.Fatalf("ParseExports: %v", err)
+	}
+	byName := make(map[string]symbols.Symbol)
+	for _, s := range syms.Entries {
+		byName[s.Name] = s
+	}
+
+	const app = "github.com/acme/app."
+	tests := []struct {
+		symbol string
+		want   []string
+	}{
+		{"Config", []string{"github.com/acme/app/auth.Token"}},
+		{"Config.Token", []string{"github.com/acme/app/auth.Token"}},
+		{"Config.Name", nil},
+		{"Server", []string{app + "Config", app + "MaxConns"}},
+		{"Server.Config", []string{app + "Config"}},
+		{"List", nil},
+		{"List.Push", nil},
+		{"Client.Reload", []string{app + "Config"}},
+		{"New", []string{app + "Config", app + "Server"}},
+		{"Map", nil},
+		{"Handler", []string{app + "Request", app + "Response"}},
+		{"Default", nil},
+		{"Fallback", []string{app + "Config"}},
+	}
+	for _, tt := range tests {
+		sym, ok := byName[tt.symbol]
+		if !ok {
+			t.Errorf("missing symbol %s", tt.symbol)
+			continue
+		}
+		if !reflect.DeepEqual(sym.Refs, tt.want) {
+			t.Errorf("%s refs = %q, want %q", tt.symbol, sym.Refs, tt.want)
+		}
+	}
+}
+
+func TestDiffExports_Affected(t *testing.T) {
+	const pkg = "mod"
+	cfg := "mod.Config"
+	srv := "mod.Server"
+	old := buildSymbols(pkg, []symbols.Symbol{
+		{Kind: symbols.SymbolType, Name: "Config", Package: pkg, Signature: "struct{Addr string}"},
+		{Kind: symbols.SymbolField, Name: "Config.Addr", Package: pkg, Signature: "string"},
+		{Kind: symbols.SymbolType, Name: "Node", Package: pkg, Signature: "struct{Next *Node}", Refs: []string{"mod.Node"}},
+		{Kind: symbols.SymbolType, Name: "Server", Package: pkg, Signature: "struct{Config}", Refs: []string{cfg}},
+		{Kind: symbols.SymbolField, Name: "Server.Config", Package: pkg, Signature: "Config", Refs: []string{cfg}},
+		{Kind: symbols.SymbolFunc, Name: "New", Package: pkg, Signature: "(Config) *Server", Refs: []string{cfg, srv}},
+		{Kind: symbols.SymbolMethod, Name: "Client.Reload", Package: pkg, Receiver: "Client", Signature: "(Config) error", Refs: []string{cfg}},
+		{Kind: symbols.SymbolFunc, Name: "Serve", Package: pkg, Signature: "(*Server)", Refs: []string{srv}},
+	})
+	new := buildSymbols(pkg, []symbols.Symbol{
+		{Kind: symbols.SymbolType, Name: "Config", Package: pkg, Signature: "struct{Addr int}"},
+		{Kind: symbols.SymbolField, Name: "Config.Addr", Package: pkg, Signature: "int"},
+		{Kind: symbols.SymbolType, Name: "Node", Package: pkg, Signature: "struct{Next *Node}", Refs: []string{"mod.Node"}},
+		{Kind: symbols.SymbolType, Name: "Server", Package: pkg, Signature: "struct{Config}", Refs: []string{cfg}},
+		{Kind: symbols.SymbolField, Name: "Server.Config", Package: pkg, Signature: "Config", Refs: []string{cfg}},
+		{Kind: symbols.SymbolFunc, Name: "New", Package: pkg, Signature: "(Config) *Server", Refs: []string{cfg, srv}},
+		{Kind: symbols.SymbolMethod, Name: "Client.Reload", Package: pkg, Receiver: "Client", Signature: "(Config) error", Refs: []string{cfg}},
+		{Kind: symbols.SymbolFunc, Name: "Serve", Package: pkg, Signature: "(*Server)", Refs: []string{srv}},
+	})
+
+	changes := DiffExports(old, new, nil, nil)
+	byName := make(map[string]changespec.Change)
+	for _, c := range changes {
+		byName[c.Symbol] = c
+	}
+
+	want := []changespec.Affected{
+		{Symbol: "Client.Reload", Package: pkg, Kind: "method", Via: []string{cfg}},
+		{Symbol: "New", Package: pkg, Kind: "func", Via: []string{cfg}},
+		{Symbol: "Server", Package: pkg, Kind: "type", Via: []string{cfg}},
+		{Symbol: "Server.Config", Package: pkg, Kind: "field", Via: []string{cfg}},
+		{Symbol: "Serve", Package: pkg, Kind: "func", Via: []string{cfg, srv}},
+	}
+	for _, name := range []string{"Config", "Config.Addr"} {
+		c, ok := byName[name]
+		if !ok {
+			t.Fatalf("missing change for %s: %+v", name, changes)
+		}
+		if !reflect.DeepEqual(c.Affected, want) {
+			t.Errorf("%s affected = %+v, want %+v", name, c.Affected, want)
+		}
+	}
+}
+
+func TestDependencyGraph_SelfReference(t *testing.T) {
+	byKey := map[symbolKey]*symbols.Symbol{
+		{pkg: "mod", kind: symbols.SymbolType, name: "Node"}: {
+			Kind: symbols.SymbolType, Name: "Node", Package: "mod", Refs: []string{"mod.Node"},
+		},
+		{pkg: "mod", kind: symbols.SymbolField, name: "Node.Next"}: {
+			Kind: symbols.SymbolField, Name: "Node.Next", Package: "mod", Refs: []string{"mod.Node"},
+		},
+	}
+	g := newDependencyGraph(byKey, nil)
+	if got := g.affected(nameKey{pkg: "mod", name: "Node"}, byKey); len(got) != 0 {
+		t.Errorf("affected = %+v, want none", got)
+	}
+}
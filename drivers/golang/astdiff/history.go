@@ -0,0 +1,162 @@
+package astdiff
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/emenda-labs/emenda/core/changespec"
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+// History is rename and move evidence gathered outside the two source
+// snapshots, typically from the upstream repository's commits between the
+// versions. Hints never create matches on their own: they resolve collisions in
+// Pass 3 and raise confidence on Pass 5 candidates. Package moves let symbols
+// whose files moved be matched across packages.
+type History struct {
+	Renames []RenameHint
+
+	// PackageMoves maps old import paths to the import paths their files moved to.
+	PackageMoves map[string]string
+}
+
+// RenameHint is evidence that a symbol was renamed between the versions.
+type RenameHint struct {
+	// OldName and NewName are package-level identifiers or Type.Member names.
+	// A bare name also matches a member of the same type on both sides.
+	OldName string
+	NewName string
+
+	// OldPackage and NewPackage are import paths. Empty matches any package.
+	OldPackage string
+	NewPackage string
+
+	// Source describes the evidence, e.g. `commit 1a2b3c4d: "rename Dial to Connect"`.
+	Source string
+}
+
+// historyIndex answers whether an old/new symbol pair is backed by a hint.
+type historyIndex struct {
+	byNames map[[2]string][]RenameHint
+	moves   map[string]string
+}
+
+// newHistoryIndex indexes h. A nil History yields an index with no evidence.
+func newHistoryIndex(h *History) *historyIndex {
+	idx := &historyIndex{byNames: make(map[[2]string][]RenameHint)}
+	if h == nil {
+		return idx
+	}
+	for _, hint := range h.Renames {
+		if hint.OldName == "" || hint.NewName == "" || hint.OldName == hint.NewName {
+			continue
+		}
+		names := [2]string{hint.OldName, hint.NewName}
+		idx.byNames[names] = append(idx.byNames[names], hint)
+	}
+	idx.moves = h.PackageMoves
+	return idx
+}
+
+// lookup returns the hint backing a rename of oldSym to newSym, if any.
+func (idx *historyIndex) lookup(oldSym, newSym *symbols.Symbol) (RenameHint, bool) {
+	if len(idx.byNames) == 0 {
+		return RenameHint{}, false
+	}
+	candidates := idx.byNames[[2]string{oldSym.Name, newSym.Name}]
+	if oldRecv, oldMember, ok := strings.Cut(oldSym.Name, "."); ok {
+		if newRecv, newMember, ok := strings.Cut(newSym.Name, "."); ok && oldRecv == newRecv {
+			candidates = append(slices.Clip(candidates), idx.byNames[[2]string{oldMember, newMember}]...)
+		}
+	}
+	for _, hint := range candidates {
+		if hint.OldPackage != "" && hint.OldPackage != oldSym.Package {
+			continue
+		}
+		if hint.NewPackage != "" && hint.NewPackage != newSym.Package {
+			continue
+		}
+		return hint, true
+	}
+	return RenameHint{}, false
+}
+
+// hintDetail formats the evidence for a change's Detail field.
+func hintDetail(hint RenameHint) string {
+	return "upstream history: " + hint.Source
+}
+
+// resolveCollision pairs the old and new symbols of one Pass 3 signature group
+// using rename hints. Pairs are returned in deterministic order; each symbol is
+// used at most once.
+func (s *diffState) resolveCollision(oldKeys, newKeys []symbolKey) []scoredPair {
+	oldKeys = append([]symbolKey(nil), oldKeys...)
+	newKeys = append([]symbolKey(nil), newKeys...)
+	sort.Slice(oldKeys, func(i, j int) bool { return compareKeys(oldKeys[i], oldKeys[j]) < 0 })
+	sort.Slice(newKeys, func(i, j int) bool { return compareKeys(newKeys[i], newKeys[j]) < 0 })
+
+	usedNew := make(map[symbolKey]bool)
+	var pairs []scoredPair
+	for _, oldKey := range oldKeys {
+		for _, newKey := range newKeys {
+			if usedNew[newKey] {
+				continue
+			}
+			if _, ok := s.history.lookup(s.oldByKey[oldKey], s.newByKey[newKey]); ok {
+				usedNew[newKey] = true
+				pairs = append(pairs, scoredPair{oldKey: oldKey, newKey: newKey, oldName: s.oldByKey[oldKey].Name})
+				break
+			}
+		}
+	}
+	return pairs
+}
+
+// Move pass: symbols whose package directory moved upstream are matched by
+// name in the new package. Package-level symbols are reported as moved; members
+// follow their type and only surface when their signature also changed.
+func (s *diffState) packageMoves() {
+	if len(s.history.moves) == 0 {
+		return
+	}
+	oldKeys := s.unmatchedOld()
+	sort.Slice(oldKeys, func(i, j int) bool { return compareKeys(oldKeys[i], oldKeys[j]) < 0 })
+
+	for _, oldKey := range oldKeys {
+		newPkg, ok := s.history.moves[oldKey.pkg]
+		if !ok {
+			continue
+		}
+		newKey := symbolKey{pkg: newPkg, kind: oldKey.kind, name: oldKey.name}
+		if _, ok := s.unmatchedNewSet[newKey]; !ok {
+			continue
+		}
+		oldSym := s.oldByKey[oldKey]
+		newSym := s.newByKey[newKey]
+		member := oldKey.kind == symbols.SymbolMethod || oldKey.kind == symbols.SymbolField
+
+		c := changespec.Change{
+			Kind:         changespec.ChangeKindPackageMoved,
+			Symbol:       oldSym.Name,
+			Package:      oldSym.Package,
+			NewPackage:   newPkg,
+			OldSignature: oldSym.Signature,
+			NewSignature: newSym.Signature,
+			Confidence:   changespec.ConfidenceHigh,
+			Detail:       fmt.Sprintf("upstream history: files moved from %s to %s", oldKey.pkg, newPkg),
+		}
+		if !s.sameSignature(oldSym, newSym) {
+			c.Kind = changespec.ChangeKindSignatureChanged
+			if oldKey.kind == symbols.SymbolType || oldKey.kind == symbols.SymbolInterface {
+				c.Kind = changespec.ChangeKindTypeChanged
+			}
+			c.Detail = fmt.Sprintf("moved to %s and changed", newPkg)
+			s.emit(c)
+		} else if !member {
+			s.emit(c)
+		}
+		s.markMatched(oldKey, newKey)
+	}
+}
@@ -0,0 +1,154 @@
+package astdiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/emenda-labs/emenda/core/changespec"
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+func changesBySymbol(changes []changespec.Change) map[string]changespec.Change {
+	out := make(map[string]changespec.Change, len(changes))
+	for _, c := range changes {
+		out[c.Symbol] = c
+	}
+	return out
+}
+
+func TestDiffExports_HistoryResolvesCollision(t *testing.T) {
+	sig := "(string) error"
+	old := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolFunc, Name: "Open", Package: "mod", Signature: sig},
+		{Kind: symbols.SymbolFunc, Name: "Mount", Package: "mod", Signature: sig},
+	})
+	new := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolFunc, Name: "Attach", Package: "mod", Signature: sig},
+		{Kind: symbols.SymbolFunc, Name: "Load", Package: "mod", Signature: sig},
+	})
+
+	// Without evidence the collision defers to Pass 5, where the names are too
+	// far apart to match.
+	for _, c := range DiffExports(old, new, nil, nil) {
+		if c.Kind != changespec.ChangeKindRemoved {
+			t.Errorf("without history: got %s %s, want removed", c.Kind, c.Symbol)
+		}
+	}
+
+	history := &History{Renames: []RenameHint{
+		{OldName: "Open", NewName: "Load", OldPackage: "mod", NewPackage: "mod", Source: "declaration rewritten in open.go"},
+	}}
+	changes := DiffExportsWithOptions(old, new, nil, nil, DiffOptions{History: history})
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d: %+v", len(changes), changes)
+	}
+	bySymbol := changesBySymbol(changes)
+
+	open := bySymbol["Open"]
+	if open.Kind != changespec.ChangeKindRenamed || open.NewName != "Load" || open.Confidence != changespec.ConfidenceHigh {
+		t.Errorf("Open = %+v, want high-confidence rename to Load", open)
+	}
+	if open.Detail != "upstream history: declaration rewritten in open.go" {
+		t.Errorf("Open detail = %q", open.Detail)
+	}
+
+	mount := bySymbol["Mount"]
+	if mount.Kind != changespec.ChangeKindRenamed || mount.NewName != "Attach" || mount.Confidence != changespec.ConfidenceMedium {
+		t.Errorf("Mount = %+v, want medium-confidence rename to Attach by elimination", mount)
+	}
+}
+
+func TestDiffExports_HistoryRaisesFuzzyConfidence(t *testing.T) {
+	oldKey := symbolKey{pkg: "mod", kind: symbols.SymbolFunc, name: "Connect"}
+	newKey := symbolKey{pkg: "mod", kind: symbols.SymbolFunc, name: "ConnectTo"}
+	params := []string{"string", "int", "bool", "[]byte"}
+	oldSigs := FuncSigMap{oldKey: {params: params, results: []string{"error"}}}
+	newSigs := FuncSigMap{newKey: {params: append(params[:4:4], "time.Duration"), results: []string{"error"}}}
+	old := symbolsFor(oldSigs)
+	new := symbolsFor(newSigs)
+
+	tests := []struct {
+		name    string
+		history *History
+		want    changespec.ConfidenceLevel
+	}{
+		{"no_history", nil, changespec.ConfidenceMedium},
+		{"commit_message", &History{Renames: []RenameHint{
+			{OldName: "Connect", NewName: "ConnectTo", Source: `commit abc1234: "rename Connect to ConnectTo"`},
+		}}, changespec.ConfidenceHigh},
+		{"other_package", &History{Renames: []RenameHint{
+			{OldName: "Connect", NewName: "ConnectTo", OldPackage: "mod/other"},
+		}}, changespec.ConfidenceMedium},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := DiffExportsWithOptions(old, new, oldSigs, newSigs, DiffOptions{History: tt.history})
+			if len(changes) != 1 || changes[0].Kind != changespec.ChangeKindRenamed {
+				t.Fatalf("expected one rename, got %+v", changes)
+			}
+			if changes[0].Confidence != tt.want {
+				t.Errorf("confidence = %q, want %q", changes[0].Confidence, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffExports_HistoryHintOrdersFuzzyCandidates(t *testing.T) {
+	// Both old functions compete for FetchAll; the hinted one wins even though the
+	// other scores higher.
+	newKey := symbolKey{pkg: "mod", kind: symbols.SymbolFunc, name: "FetchAll"}
+	sig := funcSignature{params: []string{"context.Context", "string"}, results: []string{"error"}}
+	oldSigs := FuncSigMap{
+		{pkg: "mod", kind: symbols.SymbolFunc, name: "FetchAlls"}: sig,
+		{pkg: "mod", kind: symbols.SymbolFunc, name: "GetAll"}:    sig,
+	}
+	newSigs := FuncSigMap{newKey: sig}
+	old, new := symbolsFor(oldSigs), symbolsFor(newSigs)
+	// Keep Pass 3 from pairing by signature: make the rendered signatures differ.
+	for i := range old.Entries {
+		old.Entries[i].Signature += " old"
+	}
+
+	if c := changesBySymbol(DiffExports(old, new, oldSigs, newSigs))["GetAll"]; c.NewName != "FetchAll" {
+		t.Fatalf("without history GetAll = %+v, want rename to FetchAll", c)
+	}
+
+	history := &History{Renames: []RenameHint{{OldName: "FetchAlls", NewName: "FetchAll", Source: "commit 1"}}}
+	bySymbol := changesBySymbol(DiffExportsWithOptions(old, new, oldSigs, newSigs, DiffOptions{History: history}))
+	if c := bySymbol["FetchAlls"]; c.Kind != changespec.ChangeKindRenamed || c.NewName != "FetchAll" || c.Confidence != changespec.ConfidenceHigh {
+		t.Errorf("FetchAlls = %+v, want high-confidence rename to FetchAll", c)
+	}
+	if c := bySymbol["GetAll"]; c.Kind != changespec.ChangeKindRemoved {
+		t.Errorf("GetAll = %+v, want removed", c)
+	}
+}
+
+func TestDiffExports_HistoryPackageMove(t *testing.T) {
+	entries := func(pkg, sig string) []symbols.Symbol {
+		return []symbols.Symbol{
+			{Kind: symbols.SymbolType, Name: "Client", Package: pkg, Signature: "struct{}"},
+			{Kind: symbols.SymbolMethod, Name: "Client.Do", Package: pkg, Receiver: "Client", Signature: sig},
+			{Kind: symbols.SymbolFunc, Name: "New", Package: pkg, Signature: "() *Client"},
+		}
+	}
+	old := buildSymbols("mod", entries("mod/transport", "(string) error"))
+	new := buildSymbols("mod", entries("mod/net/transport", "(string, int) error"))
+	history := &History{PackageMoves: map[string]string{"mod/transport": "mod/net/transport"}}
+
+	changes := DiffExportsWithOptions(old, new, nil, nil, DiffOptions{History: history})
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %d: %+v", len(changes), changes)
+	}
+	bySymbol := changesBySymbol(changes)
+
+	for _, name := range []string{"Client", "New"} {
+		c := bySymbol[name]
+		if c.Kind != changespec.ChangeKindPackageMoved || c.NewPackage != "mod/net/transport" || c.Confidence != changespec.ConfidenceHigh {
+			t.Errorf("%s = %+v, want package_moved to mod/net/transport", name, c)
+		}
+	}
+	do := bySymbol["Client.Do"]
+	if do.Kind != changespec.ChangeKindSignatureChanged || !strings.Contains(do.Detail, "moved to mod/net/transport") {
+		t.Errorf("Client.Do = %+v, want signature_changed after move", do)
+	}
+}
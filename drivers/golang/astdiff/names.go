@@ -0,0 +1,277 @@
+package astdiff
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultSynonyms groups verbs that API authors commonly swap when renaming.
+var DefaultSynonyms = [][]string{
+	{"Get", "Fetch", "Retrieve", "Load", "Read"},
+	{"Remove", "Delete", "Del", "Drop"},
+	{"Create", "Make", "Build"},
+	{"Update", "Modify", "Edit", "Patch"},
+	{"Find", "Search", "Lookup", "Query"},
+	{"Set", "Put", "Store"},
+	{"Send", "Emit", "Publish"},
+	{"Start", "Begin", "Run"},
+	{"Stop", "End", "Halt"},
+	{"Close", "Shutdown"},
+	{"Init", "Initialize", "Setup"},
+}
+
+// DefaultAffixes lists name suffixes that are added or dropped when API variants
+// are introduced. A trailing version token (V2, V3, ...) is always treated as an affix.
+var DefaultAffixes = []string{"WithContext", "WithCtx", "Context", "Ctx"}
+
+const (
+	// synonymTokenScore is the similarity of two tokens from the same synonym group.
+	synonymTokenScore = 0.9
+
+	// abbrevTokenScore is the similarity of a token and a longer token it
+	// prefixes (Req/Request, User/Users).
+	abbrevTokenScore = 0.8
+
+	// minAbbrevLength is the shortest token accepted as an abbreviation.
+	minAbbrevLength = 3
+
+	// minTokenEditSimilarity is the edit similarity below which two tokens are
+	// considered unrelated rather than misspellings of each other.
+	minTokenEditSimilarity = 0.8
+
+	// affixWeight scales the similarity of names that differ in their affixes.
+	affixWeight = 0.95
+)
+
+// nameMatcher scores how likely one identifier is a rename of another.
+type nameMatcher struct {
+	synonyms map[string]int // lowercase token -> synonym group
+	affixes  [][]string     // tokenized affixes, longest first
+}
+
+// newNameMatcher builds a matcher from the synonym and affix settings in opts.
+func newNameMatcher(opts DiffOptions) *nameMatcher {
+	m := &nameMatcher{synonyms: make(map[string]int)}
+	for group, words := range opts.synonyms() {
+		for _, w := range words {
+			m.synonyms[strings.ToLower(w)] = group
+		}
+	}
+	for _, affix := range opts.affixes() {
+		if tokens := splitNameTokens(affix); len(tokens) > 0 {
+			m.affixes = append(m.affixes, tokens)
+		}
+	}
+	sort.SliceStable(m.affixes, func(i, j int) bool { return len(m.affixes[i]) > len(m.affixes[j]) })
+	return m
+}
+
+// nameProfile is the preprocessed form of an identifier.
+type nameProfile struct {
+	runes   []rune
+	core    []string // tokens with affixes stripped
+	affixes []string // stripped affix tokens, innermost first
+}
+
+// profile tokenizes name and strips trailing affixes, keeping at least one core token.
+func (m *nameMatcher) profile(name string) nameProfile {
+	p := nameProfile{runes: []rune(name), core: splitNameTokens(name)}
+	for stripped := true; stripped; {
+		stripped = false
+		n := len(p.core)
+		if n > 1 && isMajorVersion(p.core[n-1]) {
+			p.affixes = append(p.affixes, p.core[n-1])
+			p.core = p.core[:n-1]
+			stripped = true
+			continue
+		}
+		for _, affix := range m.affixes {
+			if n > len(affix) && slices.Equal(p.core[n-len(affix):], affix) {
+				p.affixes = append(p.affixes, affix...)
+				p.core = p.core[:n-len(affix)]
+				stripped = true
+				break
+			}
+		}
+	}
+	return p
+}
+
+// nameSimilarity scores two identifiers in [0.0, 1.0].
+func (m *nameMatcher) nameSimilarity(a, b string) float64 {
+	return m.similarity(m.profile(a), m.profile(b))
+}
+
+// similarity combines token and character similarity. Token similarity carries
+// the score; character similarity can lift it at most halfway, so names that
+// share letters but no words (Get/Set) stay low.
+func (m *nameMatcher) similarity(a, b nameProfile) float64 {
+	if len(a.runes) == 0 && len(b.runes) == 0 {
+		return 1.0
+	}
+	return combineSimilarity(m.tokenScore(a, b), editSimilarity(a.runes, b.runes))
+}
+
+// tokenScore is the token part of similarity: the soft token set similarity of
+// the cores, lowered when the affixes differ.
+func (m *nameMatcher) tokenScore(a, b nameProfile) float64 {
+	tokens := m.tokenSetSimilarity(a.core, b.core)
+	if !slices.Equal(a.affixes, b.affixes) {
+		tokens *= affixWeight
+	}
+	return tokens
+}
+
+// combineSimilarity merges token and character similarity into the name
+// similarity. Character similarity only counts when it exceeds the token score.
+func combineSimilarity(tokens, chars float64) float64 {
+	return max(tokens, (chars+tokens)/2)
+}
+
+// tokenSetSimilarity is a soft Dice coefficient: tokens are paired greedily by
+// descending token similarity, and each pair contributes its similarity.
+func (m *nameMatcher) tokenSetSimilarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		if len(a) == len(b) {
+			return 1.0
+		}
+		return 0
+	}
+
+	type pair struct {
+		i, j int
+		sim  float64
+	}
+	var pairs []pair
+	for i, x := range a {
+		for j, y := range b {
+			if sim := m.tokenSimilarity(x, y); sim > 0 {
+				pairs = append(pairs, pair{i, j, sim})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].sim > pairs[j].sim })
+
+	usedA := make([]bool, len(a))
+	usedB := make([]bool, len(b))
+	var total float64
+	for _, p := range pairs {
+		if usedA[p.i] || usedB[p.j] {
+			continue
+		}
+		usedA[p.i], usedB[p.j] = true, true
+		total += p.sim
+	}
+	return 2 * total / float64(len(a)+len(b))
+}
+
+// tokenSimilarity scores two lowercase tokens: identical, synonyms, an
+// abbreviation, or a close misspelling. Unrelated tokens score zero.
+func (m *nameMatcher) tokenSimilarity(x, y string) float64 {
+	if x == y {
+		return 1.0
+	}
+	if gx, ok := m.synonyms[x]; ok {
+		if gy, ok := m.synonyms[y]; ok && gx == gy {
+			return synonymTokenScore
+		}
+	}
+
+	var best float64
+	short, long := x, y
+	if len(short) > len(long) {
+		short, long = long, short
+	}
+	if len([]rune(short)) >= minAbbrevLength && strings.HasPrefix(long, short) {
+		best = abbrevTokenScore
+	}
+	if sim := editSimilarity([]rune(x), []rune(y)); sim >= minTokenEditSimilarity {
+		best = max(best, sim)
+	}
+	return best
+}
+
+// splitNameTokens splits an identifier into lowercase words at underscores,
+// dots and camelCase boundaries. Acronyms stay together (HTTPServer -> http,
+// server) and digits stay with the preceding letters (V2, Base64).
+func splitNameTokens(name string) []string {
+	runes := []rune(name)
+	var tokens []string
+	start := -1
+	flush := func(end int) {
+		if start >= 0 && end > start {
+			tokens = append(tokens, strings.ToLower(string(runes[start:end])))
+		}
+		start = -1
+	}
+
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush(i)
+			continue
+		}
+		if start < 0 {
+			start = i
+			continue
+		}
+		prev := runes[i-1]
+		if !unicode.IsUpper(r) {
+			continue
+		}
+		switch {
+		case unicode.IsLower(prev) || unicode.IsDigit(prev):
+			// fooBar, UTF8String
+			flush(i)
+			start = i
+		case unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+			// HTTPServer: the last capital starts the next word.
+			flush(i)
+			start = i
+		}
+	}
+	flush(len(runes))
+	return tokens
+}
+
+// editSimilarity returns the normalized Levenshtein similarity of two rune slices.
+func editSimilarity(a, b []rune) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1.0
+	}
+	return 1.0 - float64(runeLevenshtein(a, b))/float64(max(len(a), len(b)))
+}
+
+// runeLevenshtein computes the edit distance between two rune slices.
+func runeLevenshtein(a, b []rune) int {
+	la, lb := len(a), len(b)
+	if la == 0 {
+		return lb
+	}
+	if lb == 0 {
+		return la
+	}
+
+	// Use two rows instead of full matrix.
+	prev := make([]int, lb+1)
+	curr := make([]int, lb+1)
+
+	for j := 0; j <= lb; j++ {
+		prev[j] = j
+	}
+
+	for i := 1; i <= la; i++ {
+		curr[0] = i
+		for j := 1; j <= lb; j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[lb]
+}
@@ -0,0 +1,134 @@
+package astdiff
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/emenda-labs/emenda/core/changespec"
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+func TestSplitNameTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"NewClient", []string{"new", "client"}},
+		{"HTTPServer", []string{"http", "server"}},
+		{"ServeHTTP", []string{"serve", "http"}},
+		{"parse_config", []string{"parse", "config"}},
+		{"Client.DoV2", []string{"client", "do", "v2"}},
+		{"UTF8String", []string{"utf8", "string"}},
+		{"Base64Encode", []string{"base64", "encode"}},
+		{"GrößeÄndern", []string{"größe", "ändern"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := splitNameTokens(tt.name); !slices.Equal(got, tt.want) {
+			t.Errorf("splitNameTokens(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNameProfile_Affixes(t *testing.T) {
+	m := newNameMatcher(DiffOptions{})
+	tests := []struct {
+		name    string
+		core    []string
+		affixes []string
+	}{
+		{"NewClientWithContext", []string{"new", "client"}, []string{"with", "context"}},
+		{"QueryContextV2", []string{"query"}, []string{"v2", "context"}},
+		{"Context", []string{"context"}, nil},
+		{"V2", []string{"v2"}, nil},
+	}
+	for _, tt := range tests {
+		p := m.profile(tt.name)
+		if !slices.Equal(p.core, tt.core) || !slices.Equal(p.affixes, tt.affixes) {
+			t.Errorf("profile(%q) = core %q affixes %q, want core %q affixes %q", tt.name, p.core, p.affixes, tt.core, tt.affixes)
+		}
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		wantMin float64
+		wantMax float64
+	}{
+		{"abc", "abc", 1.0, 1.0},
+		{"", "", 1.0, 1.0},
+		{"Get", "Set", 0.0, 0.5},
+		{"ProcessRequest", "ProcessReq", 0.85, 0.95},
+		{"Initialize", "Initialise", 0.85, 1.0},
+		{"NewClient", "NewClientWithContext", 0.9, 0.99},
+		{"Query", "QueryCtx", 0.9, 0.99},
+		{"Open", "OpenV2", 0.9, 0.99},
+		{"GetUser", "FetchUser", 0.9, 0.99},
+		{"RemoveItem", "DeleteItem", 0.9, 0.99},
+		{"ParseConfig", "ParseConfiguration", 0.85, 0.95},
+		{"Client.Close", "Client.Open", 0.0, 0.6},
+		{"Größe", "Grösse", 0.3, 0.4}, // rune distance 2 over 6 runes, halved: no shared word
+	}
+	m := newNameMatcher(DiffOptions{})
+	for _, tt := range tests {
+		got := m.nameSimilarity(tt.a, tt.b)
+		if got < tt.wantMin || got > tt.wantMax {
+			t.Errorf("nameSimilarity(%q, %q) = %.3f, want [%.2f, %.2f]",
+				tt.a, tt.b, got, tt.wantMin, tt.wantMax)
+		}
+	}
+}
+
+func TestNameSimilarity_ConfiguredSynonyms(t *testing.T) {
+	defaults := newNameMatcher(DiffOptions{})
+	custom := newNameMatcher(DiffOptions{Synonyms: [][]string{{"Acquire", "Obtain"}}})
+	none := newNameMatcher(DiffOptions{Synonyms: [][]string{}})
+
+	if got := defaults.nameSimilarity("AcquireLock", "ObtainLock"); got >= MinNameSimilarity {
+		t.Errorf("default similarity of Acquire/Obtain = %.3f, want below threshold", got)
+	}
+	if got := custom.nameSimilarity("AcquireLock", "ObtainLock"); got < MinNameSimilarity {
+		t.Errorf("custom similarity of Acquire/Obtain = %.3f, want at least %.2f", got, MinNameSimilarity)
+	}
+	if got := none.nameSimilarity("GetUser", "FetchUser"); got >= MinNameSimilarity {
+		t.Errorf("similarity of Get/Fetch with synonyms disabled = %.3f, want below threshold", got)
+	}
+}
+
+func TestDiffExportsWithOptions_AffixRename(t *testing.T) {
+	// NewClient -> NewClientWithContext scores 0.45 on plain edit distance; the
+	// affix bonus lets Pass 5 pair them despite the added parameter.
+	oldSigs := FuncSigMap{
+		{pkg: "mod", kind: symbols.SymbolFunc, name: "NewClient"}: {
+			params: []string{"string", "*Options", "int", "bool"}, results: []string{"*Client", "error"},
+		},
+	}
+	newSigs := FuncSigMap{
+		{pkg: "mod", kind: symbols.SymbolFunc, name: "NewClientWithContext"}: {
+			params: []string{"context.Context", "string", "*Options", "int", "bool"}, results: []string{"*Client", "error"},
+		},
+	}
+	old := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolFunc, Name: "NewClient", Package: "mod", Signature: "(string, *Options, int, bool) (*Client, error)"},
+	})
+	new := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolFunc, Name: "NewClientWithContext", Package: "mod", Signature: "(context.Context, string, *Options, int, bool) (*Client, error)"},
+	})
+
+	changes := DiffExportsWithOptions(old, new, oldSigs, newSigs, DiffOptions{})
+	if len(changes) != 1 || changes[0].Kind != changespec.ChangeKindRenamed {
+		t.Fatalf("expected one rename, got %+v", changes)
+	}
+	if changes[0].NewName != "NewClientWithContext" {
+		t.Errorf("new_name = %q, want NewClientWithContext", changes[0].NewName)
+	}
+
+	// Without affixes the added words count in full and the pair is rejected.
+	changes = DiffExportsWithOptions(old, new, oldSigs, newSigs, DiffOptions{Affixes: []string{}})
+	for _, c := range changes {
+		if c.Kind == changespec.ChangeKindRenamed {
+			t.Errorf("unexpected rename without affixes: %+v", c)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package astdiff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/emenda-labs/emenda/core/changespec"
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+// Parameter pass: functions and methods present in both versions whose parameter
+// types are unchanged but whose same-typed parameters swapped names, e.g.
+// Retry(maxAttempts, delayMs int) becoming Retry(delayMs, maxAttempts int). Call
+// sites still compile and silently pass arguments in the wrong order, so these
+// are emitted as warnings and do not consume symbols from the unmatched sets.
+func (s *diffState) paramSwaps() {
+	for _, key := range sortedSymbolKeys(s.oldSigs) {
+		if key.kind != symbols.SymbolFunc && key.kind != symbols.SymbolMethod {
+			continue
+		}
+		if _, synthetic := s.oldAliasMembers[key]; synthetic {
+			continue
+		}
+		oldSym, ok := s.oldByKey[key]
+		if !ok {
+			continue
+		}
+		newSig, ok := s.newSigs[key]
+		if !ok {
+			continue
+		}
+		oldSig := s.oldSigs[key]
+
+		detail := describeParamSwaps(oldSig, newSig)
+		if detail == "" {
+			continue
+		}
+
+		s.emit(changespec.Change{
+			Kind:         changespec.ChangeKindParamsSwapped,
+			Symbol:       oldSym.Name,
+			Package:      oldSym.Package,
+			OldSignature: renderNamedParams(oldSig),
+			NewSignature: renderNamedParams(newSig),
+			Confidence:   changespec.ConfidenceMedium,
+			Severity:     changespec.SeverityWarning,
+			Detail:       detail,
+		})
+	}
+}
+
+// describeParamSwaps reports same-typed parameters that moved to another
+// position between old and new, one clause per parameter type. It returns ""
+// when the parameter types differ (a signature change, handled by later passes),
+// when names are missing, or when no name moved.
+func describeParamSwaps(old, new funcSignature) string {
+	if len(old.params) != len(new.params) || len(old.paramNames) != len(old.params) || len(new.paramNames) != len(new.params) {
+		return ""
+	}
+	for i := range old.params {
+		if old.params[i] != new.params[i] {
+			return ""
+		}
+	}
+
+	// Positions of each named parameter in the new version.
+	newPos := make(map[string]int, len(new.paramNames))
+	for i, name := range new.paramNames {
+		if isMeaningfulParamName(name) {
+			newPos[name] = i
+		}
+	}
+
+	// Group moved parameters by type, keeping the order types first appear in.
+	var types []string
+	moved := make(map[string][]int)
+	for i, name := range old.paramNames {
+		if !isMeaningfulParamName(name) || !isMeaningfulParamName(new.paramNames[i]) || new.paramNames[i] == name {
+			continue
+		}
+		j, ok := newPos[name]
+		if !ok || old.params[j] != old.params[i] {
+			continue
+		}
+		typ := old.params[i]
+		if _, seen := moved[typ]; !seen {
+			types = append(types, typ)
+		}
+		moved[typ] = append(moved[typ], i)
+	}
+	if len(types) == 0 {
+		return ""
+	}
+
+	clauses := make([]string, 0, len(types))
+	for _, typ := range types {
+		var before, after []string
+		for _, i := range moved[typ] {
+			before = append(before, old.paramNames[i])
+			after = append(after, new.paramNames[i])
+		}
+		clauses = append(clauses, fmt.Sprintf("parameters %s (%s) reordered to %s",
+			strings.Join(before, ", "), typ, strings.Join(after, ", ")))
+	}
+	return strings.Join(clauses, "; ")
+}
+
+// isMeaningfulParamName reports whether name identifies a parameter. Unnamed
+// and blank parameters carry no intent that could be swapped.
+func isMeaningfulParamName(name string) bool {
+	return name != "" && name != "_"
+}
+
+// renderNamedParams renders a parameter list with names, e.g. "(ctx context.Context, n int)".
+func renderNamedParams(sig funcSignature) string {
+	parts := make([]string, len(sig.params))
+	for i, typ := range sig.params {
+		if i < len(sig.paramNames) && sig.paramNames[i] != "" {
+			parts[i] = sig.paramNames[i] + " " + typ
+		} else {
+			parts[i] = typ
+		}
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// sortedSymbolKeys returns the keys of sigs in deterministic order.
+func sortedSymbolKeys(sigs FuncSigMap) []symbolKey {
+	keys := make([]symbolKey, 0, len(sigs))
+	for k := range sigs {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return compareKeys(keys[i], keys[j]) < 0 })
+	return keys
+}
@@ -0,0 +1,92 @@
+package astdiff
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/emenda-labs/emenda/core/changespec"
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+func TestDescribeParamSwaps(t *testing.T) {
+	sig := func(types, names []string) funcSignature {
+		return funcSignature{params: types, paramNames: names}
+	}
+	ints := []string{"int", "int"}
+	tests := []struct {
+		name     string
+		old, new funcSignature
+		want     string
+	}{
+		{"unchanged", sig(ints, []string{"a", "b"}), sig(ints, []string{"a", "b"}), ""},
+		{"swapped", sig(ints, []string{"maxAttempts", "delayMs"}), sig(ints, []string{"delayMs", "maxAttempts"}),
+			"parameters maxAttempts, delayMs (int) reordered to delayMs, maxAttempts"},
+		{"renamed_only", sig(ints, []string{"a", "b"}), sig(ints, []string{"x", "y"}), ""},
+		{"types_differ", sig([]string{"int", "string"}, []string{"a", "b"}), sig([]string{"string", "int"}, []string{"b", "a"}), ""},
+		{"different_types_no_swap", sig([]string{"int", "string"}, []string{"a", "b"}), sig([]string{"int", "string"}, []string{"b", "a"}), ""},
+		{"rotation", sig([]string{"int", "int", "int"}, []string{"x", "y", "z"}), sig([]string{"int", "int", "int"}, []string{"z", "x", "y"}),
+			"parameters x, y, z (int) reordered to z, x, y"},
+		{"two_groups", sig([]string{"string", "string", "int", "int"}, []string{"src", "dst", "w", "h"}),
+			sig([]string{"string", "string", "int", "int"}, []string{"dst", "src", "h", "w"}),
+			"parameters src, dst (string) reordered to dst, src; parameters w, h (int) reordered to h, w"},
+		{"blank", sig(ints, []string{"_", "a"}), sig(ints, []string{"a", "_"}), ""},
+		{"unnamed", sig(ints, nil), sig(ints, []string{"a", "b"}), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeParamSwaps(tt.old, tt.new); got != tt.want {
+				t.Errorf("describeParamSwaps = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExports_ParamNames(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeFile(filepath.Join(dir, "go.mod"), "module github.com/acme/retry\n"); err != nil {
+		t.Fatal(err)
+	}
+	src := "package retry\n\nfunc Retry(maxAttempts, delayMs int, _ string, fn func() error) error { return nil }\n"
+	if err := writeFile(filepath.Join(dir, "retry.go"), src); err != nil {
+		t.Fatal(err)
+	}
+
+	_, sigs, err := ParseExports(context.Background(), dir, "github.com/acme/retry")
+	if err != nil {
+		t.Fatalf("ParseExports: %v", err)
+	}
+	sig := sigs[symbolKey{pkg: "github.com/acme/retry", kind: symbols.SymbolFunc, name: "Retry"}]
+	if want := []string{"maxAttempts", "delayMs", "_", "fn"}; !reflect.DeepEqual(sig.paramNames, want) {
+		t.Errorf("paramNames = %q, want %q", sig.paramNames, want)
+	}
+	if want := []string{"int", "int", "string", "func() error"}; !reflect.DeepEqual(sig.params, want) {
+		t.Errorf("params = %q, want %q", sig.params, want)
+	}
+}
+
+func TestDiffExports_ParamsSwapped(t *testing.T) {
+	key := symbolKey{pkg: "mod", kind: symbols.SymbolFunc, name: "Retry"}
+	sym := symbols.Symbol{Kind: symbols.SymbolFunc, Name: "Retry", Package: "mod", Signature: "(int, int) error"}
+	old := buildSymbols("mod", []symbols.Symbol{sym})
+	new := buildSymbols("mod", []symbols.Symbol{sym})
+	oldSigs := FuncSigMap{key: {params: []string{"int", "int"}, paramNames: []string{"maxAttempts", "delayMs"}, results: []string{"error"}}}
+	newSigs := FuncSigMap{key: {params: []string{"int", "int"}, paramNames: []string{"delayMs", "maxAttempts"}, results: []string{"error"}}}
+
+	changes := DiffExports(old, new, oldSigs, newSigs)
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d: %+v", len(changes), changes)
+	}
+	c := changes[0]
+	if c.Kind != changespec.ChangeKindParamsSwapped || c.Severity != changespec.SeverityWarning {
+		t.Errorf("kind/severity = %q/%q, want params_swapped/warning", c.Kind, c.Severity)
+	}
+	if c.OldSignature != "(maxAttempts int, delayMs int)" || c.NewSignature != "(delayMs int, maxAttempts int)" {
+		t.Errorf("signatures = %q -> %q", c.OldSignature, c.NewSignature)
+	}
+	if c.Detail != "parameters maxAttempts, delayMs (int) reordered to delayMs, maxAttempts" {
+		t.Errorf("detail = %q", c.Detail)
+	}
+}
@@ -2,15 +2,21 @@ package astdiff
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/parser"
+	"go/scanner"
 	"go/token"
 	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 
+	"github.com/emenda-labs/emenda/core/changespec"
 	"github.com/emenda-labs/emenda/drivers/golang/symbols"
 )
 
@@ -25,23 +31,72 @@ type symbolKey struct {
 // Built during ParseExports, consumed by DiffExports Pass 5 for param overlap.
 type FuncSigMap map[symbolKey]funcSignature
 
+// ParseOptions controls optional behavior of ParseExportsWithOptions.
+// The zero value selects the defaults.
+type ParseOptions struct {
+	// TagKeys lists the struct tag keys recorded on field symbols.
+	// Nil means DefaultTagKeys.
+	TagKeys []string
+
+	// Workers bounds how many package directories are parsed concurrently.
+	// Zero or negative means runtime.GOMAXPROCS(0).
+	Workers int
+
+	// Behavior summarizes function bodies into Symbols.Behavior for
+	// CompareBehavior.
+	Behavior bool
+}
+
+// workers returns the configured worker count, falling back to GOMAXPROCS.
+func (o ParseOptions) workers() int {
+	if o.Workers <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return o.Workers
+}
+
+// tagKeys returns the configured struct tag keys, falling back to DefaultTagKeys.
+func (o ParseOptions) tagKeys() []string {
+	if o.TagKeys == nil {
+		return DefaultTagKeys
+	}
+	return o.TagKeys
+}
+
 // ParseExports walks the Go module source at rootDir and collects all exported symbols.
 // The module parameter is the Go module import path (e.g. "github.com/acme/foo").
 // Returns the symbol set and a cached map of structured function signatures for
 // use in DiffExports fuzzy matching.
 func ParseExports(ctx context.Context, rootDir, module string) (symbols.Symbols, FuncSigMap, error) {
+	return ParseExportsWithOptions(ctx, rootDir, module, ParseOptions{})
+}
+
+// ParseExportsWithOptions is ParseExports with explicit parse options.
+func ParseExportsWithOptions(ctx context.Context, rootDir, module string, opts ParseOptions) (symbols.Symbols, FuncSigMap, error) {
 	sourceRoot, err := FindSourceRoot(rootDir)
 	if err != nil {
 		return symbols.Symbols{}, nil, fmt.Errorf("finding source root in %s: %w", rootDir, err)
 	}
 
-	fset := token.NewFileSet()
-	var entries []symbols.Symbol
-	sigMap := make(FuncSigMap)
+	// Group files by directory so each package is collected as a unit; reachability
+	// of unexported types depends on every file in the package.
+	var dirs []string
+	filesByDir := make(map[string][]string)
+	var diagnostics []changespec.Diagnostic
 
 	walkErr := filepath.WalkDir(sourceRoot, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
-			return err
+			if path == sourceRoot || d == nil || !d.IsDir() {
+				return err
+			}
+			// Unreadable directory: record it and keep going.
+			diagnostics = append(diagnostics, changespec.Diagnostic{
+				Kind:    changespec.DiagnosticSkippedDir,
+				Package: dirPackagePath(sourceRoot, path, module),
+				File:    relPath(sourceRoot, path),
+				Message: err.Error(),
+			})
+			return fs.SkipDir
 		}
 
 		if ctx.Err() != nil {
@@ -50,6 +105,14 @@ func ParseExports(ctx context.Context, rootDir, module string) (symbols.Symbols,
 
 		// Skip symlinks to prevent symlink-based path escapes.
 		if d.Type()&os.ModeSymlink != 0 {
+			if info, statErr := os.Stat(path); statErr == nil && info.IsDir() {
+				diagnostics = append(diagnostics, changespec.Diagnostic{
+					Kind:    changespec.DiagnosticSkippedDir,
+					Package: dirPackagePath(sourceRoot, path, module),
+					File:    relPath(sourceRoot, path),
+					Message: "symlinked directory not followed",
+				})
+			}
 			return nil
 		}
 
@@ -58,6 +121,10 @@ func ParseExports(ctx context.Context, rootDir, module string) (symbols.Symbols,
 			if base == "internal" || base == "testdata" || base == "vendor" || strings.HasPrefix(base, "_") {
 				return fs.SkipDir
 			}
+			// Nested modules are not part of this module's API.
+			if path != sourceRoot && hasGoMod(path) {
+				return fs.SkipDir
+			}
 			return nil
 		}
 
@@ -65,46 +132,235 @@ func ParseExports(ctx context.Context, rootDir, module string) (symbols.Symbols,
 			return nil
 		}
 
-		file, parseErr := parser.ParseFile(fset, path, nil, 0)
-		if parseErr != nil {
-			fmt.Fprintf(os.Stderr, "warning: skipping %s: %v\n", path, parseErr)
-			return nil
+		dir := filepath.Dir(path)
+		if _, seen := filesByDir[dir]; !seen {
+			dirs = append(dirs, dir)
+		}
+		filesByDir[dir] = append(filesByDir[dir], path)
+		return nil
+	})
+	if walkErr != nil {
+		return symbols.Symbols{}, nil, fmt.Errorf("walking source at %s: %w", sourceRoot, walkErr)
+	}
+
+	// Parse packages concurrently. Results are stored by directory index and
+	// merged in walk order so the output does not depend on scheduling.
+	fset := token.NewFileSet()
+	results := make([]packageResult, len(dirs))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(opts.workers(), len(dirs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				dir := dirs[i]
+				results[i] = parsePackage(ctx, fset, sourceRoot, module, dirPackagePath(sourceRoot, dir, module), filesByDir[dir], opts)
+			}
+		}()
+	}
+
+feed:
+	for i := range dirs {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return symbols.Symbols{}, nil, fmt.Errorf("parsing source at %s: %w", sourceRoot, err)
+	}
+
+	sigMap := make(FuncSigMap)
+	var entries []symbols.Symbol
+	var behavior *symbols.Behavior
+	if opts.Behavior {
+		behavior = &symbols.Behavior{}
+	}
+	for _, r := range results {
+		entries = append(entries, r.entries...)
+		diagnostics = append(diagnostics, r.diagnostics...)
+		maps.Copy(sigMap, r.sigMap)
+		if behavior != nil {
+			mergeBehavior(behavior, r.behavior)
 		}
+	}
+	if behavior != nil {
+		pruneConstRefs(behavior)
+	}
+
+	return symbols.Symbols{Module: module, Entries: entries, Diagnostics: diagnostics, Behavior: behavior}, sigMap, nil
+}
+
+// packageResult holds the symbols collected from one package directory.
+type packageResult struct {
+	entries     []symbols.Symbol
+	sigMap      FuncSigMap
+	diagnostics []changespec.Diagnostic
+	behavior    *symbols.Behavior
+}
 
+// parsePackage parses the files of one package directory and collects its exports.
+// fset is shared between workers; token.FileSet is safe for concurrent use.
+func parsePackage(ctx context.Context, fset *token.FileSet, sourceRoot, module, pkgPath string, paths []string, opts ParseOptions) packageResult {
+	var result packageResult
+
+	var files []*ast.File
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			return packageResult{}
+		}
+		file, parseErr := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if parseErr != nil {
+			result.diagnostics = append(result.diagnostics, parseDiagnostic(sourceRoot, pkgPath, path, parseErr))
+			continue
+		}
 		if file.Name.Name == "main" {
-			return nil
+			continue
+		}
+		files = append(files, file)
+	}
+	if len(files) == 0 {
+		return result
+	}
+
+	c := &packageCollector{
+		fset:       fset,
+		sourceRoot: sourceRoot,
+		module:     module,
+		pkgPath:    pkgPath,
+		tagKeys:    opts.tagKeys(),
+		reachable:  reachableTypes(fset, files),
+		sigMap:     make(FuncSigMap),
+	}
+	if opts.Behavior {
+		c.behavior = &symbols.Behavior{}
+		c.consts = packageConsts(files)
+	}
+	c.collectFiles(files)
+
+	result.entries = c.entries
+	result.sigMap = c.sigMap
+	result.diagnostics = append(result.diagnostics, c.diagnostics...)
+	result.behavior = c.behavior
+	return result
+}
+
+// parseDiagnostic converts a parser error into a diagnostic positioned at the first error.
+func parseDiagnostic(sourceRoot, pkgPath, path string, err error) changespec.Diagnostic {
+	diag := changespec.Diagnostic{
+		Kind:    changespec.DiagnosticParseError,
+		Package: pkgPath,
+		File:    relPath(sourceRoot, path),
+		Message: err.Error(),
+	}
+	var list scanner.ErrorList
+	if errors.As(err, &list) && len(list) > 0 {
+		diag.Line = list[0].Pos.Line
+		diag.Message = list[0].Msg
+		if len(list) > 1 {
+			diag.Message = fmt.Sprintf("%s (and %d more errors)", list[0].Msg, len(list)-1)
 		}
+	}
+	return diag
+}
 
-		pkgPath := computePackagePath(sourceRoot, path, module)
+// relPath returns path relative to root using forward slashes, or path itself
+// if it is not below root.
+func relPath(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
 
+// packageCollector accumulates the exported symbols of a single package.
+type packageCollector struct {
+	fset       *token.FileSet
+	sourceRoot string
+	module     string
+	pkgPath    string
+	tagKeys    []string
+	// reachable holds unexported type names whose exported methods and fields
+	// are reachable through the package's exported API.
+	reachable map[string]bool
+	// imports maps import names to paths for the file being collected.
+	imports     map[string]string
+	entries     []symbols.Symbol
+	sigMap      FuncSigMap
+	diagnostics []changespec.Diagnostic
+	// behavior is nil unless ParseOptions.Behavior is set; consts then holds
+	// the names of the package's constants.
+	behavior *symbols.Behavior
+	consts   map[string]bool
+}
+
+// checkUnsupported records a diagnostic if bad is non-nil. bad is the expression
+// renderTypeExpr could not render while producing the signature of symbol.
+func (c *packageCollector) checkUnsupported(symbol string, bad ast.Expr) {
+	if bad == nil {
+		return
+	}
+	pos := c.fset.Position(bad.Pos())
+	c.diagnostics = append(c.diagnostics, changespec.Diagnostic{
+		Kind:    changespec.DiagnosticUnsupported,
+		Package: c.pkgPath,
+		Symbol:  symbol,
+		File:    relPath(c.sourceRoot, pos.Filename),
+		Line:    pos.Line,
+		Message: fmt.Sprintf("unsupported type expression %T rendered as \"unknown\"", bad),
+	})
+}
+
+// location converts pos to a Location relative to the module source root.
+func (c *packageCollector) location(pos token.Pos) *changespec.Location {
+	p := c.fset.Position(pos)
+	if !p.IsValid() {
+		return nil
+	}
+	return &changespec.Location{
+		File:   relPath(c.sourceRoot, p.Filename),
+		Line:   p.Line,
+		Column: p.Column,
+	}
+}
+
+// collectFiles collects the exported declarations of every file in the package.
+func (c *packageCollector) collectFiles(files []*ast.File) {
+	for _, file := range files {
+		c.imports = fileImports(file)
 		for _, decl := range file.Decls {
 			switch d := decl.(type) {
 			case *ast.FuncDecl:
-				collectFunc(fset, d, pkgPath, &entries, sigMap)
+				c.collectFunc(d)
+				c.collectInit(d)
 			case *ast.GenDecl:
 				switch d.Tok {
 				case token.TYPE:
-					collectTypes(fset, d, pkgPath, &entries)
+					c.collectTypes(d)
 				case token.CONST:
-					collectValues(fset, d, pkgPath, symbols.SymbolConst, &entries)
+					c.collectValues(d, symbols.SymbolConst)
+					c.collectConstValues(d)
 				case token.VAR:
-					collectValues(fset, d, pkgPath, symbols.SymbolVar, &entries)
+					c.collectValues(d, symbols.SymbolVar)
 				}
 			}
 		}
-
-		return nil
-	})
-	if walkErr != nil {
-		return symbols.Symbols{}, nil, fmt.Errorf("walking source at %s: %w", sourceRoot, walkErr)
 	}
-
-	return symbols.Symbols{Module: module, Entries: entries}, sigMap, nil
 }
 
-// collectFunc processes a single function or method declaration and appends
-// the resulting symbol to entries. Methods on unexported receivers are skipped.
-func collectFunc(fset *token.FileSet, funcDecl *ast.FuncDecl, pkgPath string, entries *[]symbols.Symbol, sigMap FuncSigMap) {
+// collectFunc processes a single function or method declaration. Methods on
+// unexported receivers are skipped unless the receiver type is reachable.
+func (c *packageCollector) collectFunc(funcDecl *ast.FuncDecl) {
 	if funcDecl.Name == nil || !funcDecl.Name.IsExported() {
 		return
 	}
@@ -114,75 +370,101 @@ func collectFunc(fset *token.FileSet, funcDecl *ast.FuncDecl, pkgPath string, en
 
 	if funcDecl.Recv != nil {
 		recvName := receiverTypeName(funcDecl.Recv)
-		if recvName == "" || !ast.IsExported(recvName) {
+		if recvName == "" || !(ast.IsExported(recvName) || c.reachable[recvName]) {
 			return
 		}
 		sym = symbols.Symbol{
 			Kind:     symbols.SymbolMethod,
 			Name:     recvName + "." + funcDecl.Name.Name,
-			Package:  pkgPath,
+			Package:  c.pkgPath,
 			Receiver: recvName,
 		}
-		key = symbolKey{pkg: pkgPath, kind: symbols.SymbolMethod, name: sym.Name}
+		key = symbolKey{pkg: c.pkgPath, kind: symbols.SymbolMethod, name: sym.Name}
 	} else {
 		sym = symbols.Symbol{
 			Kind:    symbols.SymbolFunc,
 			Name:    funcDecl.Name.Name,
-			Package: pkgPath,
+			Package: c.pkgPath,
 		}
-		key = symbolKey{pkg: pkgPath, kind: symbols.SymbolFunc, name: sym.Name}
+		key = symbolKey{pkg: c.pkgPath, kind: symbols.SymbolFunc, name: sym.Name}
 	}
 
-	sig := extractFuncSignature(fset, funcDecl.Type)
+	sig := extractFuncSignature(c.fset, funcDecl.Type)
 	sym.Signature = renderFuncSignature(sig)
-	sigMap[key] = sig
-
-	*entries = append(*entries, sym)
+	sym.Location = c.location(funcDecl.Name.Pos())
+	sym.Doc = docExcerpt(funcDecl.Doc)
+	sym.Refs = c.typeRefs(typeParamNames(funcDecl), funcDecl.Type)
+	c.sigMap[key] = sig
+	c.checkUnsupported(sym.Name, unsupportedFuncType(funcDecl.Type))
+
+	c.entries = append(c.entries, sym)
+	c.collectBehavior(sym, funcDecl)
 }
 
 // collectTypes processes a GenDecl with token.TYPE, extracting exported types,
-// their struct fields, and interface declarations.
-func collectTypes(fset *token.FileSet, genDecl *ast.GenDecl, pkgPath string, entries *[]symbols.Symbol) {
+// their struct fields, and interface declarations. Reachable unexported struct
+// types contribute their exported fields only. Field symbols record the struct
+// tag values for the configured tag keys.
+func (c *packageCollector) collectTypes(genDecl *ast.GenDecl) {
 	for _, spec := range genDecl.Specs {
 		typeSpec, ok := spec.(*ast.TypeSpec)
-		if !ok || typeSpec.Name == nil || !typeSpec.Name.IsExported() {
+		if !ok || typeSpec.Name == nil {
+			continue
+		}
+		if !typeSpec.Name.IsExported() && !c.reachable[typeSpec.Name.Name] {
 			continue
 		}
 
 		typeName := typeSpec.Name.Name
+		c.checkUnsupported(typeName, unsupportedTypeSpec(typeSpec))
+
+		if typeSpec.Name.IsExported() {
+			var kind symbols.SymbolKind
+			if _, isIface := typeSpec.Type.(*ast.InterfaceType); isIface {
+				kind = symbols.SymbolInterface
+			} else {
+				kind = symbols.SymbolType
+			}
 
-		var kind symbols.SymbolKind
-		if _, isIface := typeSpec.Type.(*ast.InterfaceType); isIface {
-			kind = symbols.SymbolInterface
-		} else {
-			kind = symbols.SymbolType
+			sym := symbols.Symbol{
+				Kind:      kind,
+				Name:      typeName,
+				Package:   c.pkgPath,
+				Signature: extractTypeSignature(c.fset, typeSpec),
+				Location:  c.location(typeSpec.Name.Pos()),
+				Doc:       docExcerpt(specDoc(genDecl, typeSpec.Doc)),
+				Refs:      c.typeRefs(fieldNames(typeSpec.TypeParams), typeSpec.Type),
+			}
+			if typeSpec.Assign.IsValid() {
+				sym.AliasOf = aliasTarget(typeSpec.Type, c.pkgPath, c.imports)
+			}
+			c.entries = append(c.entries, sym)
 		}
 
-		*entries = append(*entries, symbols.Symbol{
-			Kind:      kind,
-			Name:      typeName,
-			Package:   pkgPath,
-			Signature: extractTypeSignature(fset, typeSpec),
-		})
-
 		// Extract exported fields from struct types.
 		structType, ok := typeSpec.Type.(*ast.StructType)
 		if !ok || structType.Fields == nil {
 			continue
 		}
 
+		typeParams := fieldNames(typeSpec.TypeParams)
 		for _, field := range structType.Fields.List {
+			refs := c.typeRefs(typeParams, field.Type)
 			if len(field.Names) == 0 {
 				// Embedded field: emit if the type name is exported.
 				embName := baseTypeName(field.Type)
 				if embName == "" || !ast.IsExported(embName) {
 					continue
 				}
-				*entries = append(*entries, symbols.Symbol{
+				c.entries = append(c.entries, symbols.Symbol{
 					Kind:      symbols.SymbolField,
 					Name:      typeName + "." + embName,
-					Package:   pkgPath,
-					Signature: renderTypeExpr(fset, field.Type),
+					Package:   c.pkgPath,
+					Signature: renderTypeExpr(c.fset, field.Type),
+					Tags:      parseStructTag(field.Tag, c.tagKeys),
+					Location:  c.location(field.Type.Pos()),
+					Doc:       docExcerpt(field.Doc),
+					Refs:      refs,
 				})
 				continue
 			}
@@ -191,11 +473,15 @@ func collectTypes(fset *token.FileSet, genDecl *ast.GenDecl, pkgPath string, ent
 				if !name.IsExported() {
 					continue
 				}
-				*entries = append(*entries, symbols.Symbol{
+				c.entries = append(c.entries, symbols.Symbol{
 					Kind:      symbols.SymbolField,
 					Name:      typeName + "." + name.Name,
-					Package:   pkgPath,
-					Signature: renderTypeExpr(fset, field.Type),
+					Package:   c.pkgPath,
+					Signature: renderTypeExpr(c.fset, field.Type),
+					Tags:      parseStructTag(field.Tag, c.tagKeys),
+					Location:  c.location(name.Pos()),
+					Doc:       docExcerpt(field.Doc),
+					Refs:      refs,
 				})
 			}
 		}
@@ -203,7 +489,7 @@ func collectTypes(fset *token.FileSet, genDecl *ast.GenDecl, pkgPath string, ent
 }
 
 // collectValues processes a GenDecl with token.CONST or token.VAR.
-func collectValues(fset *token.FileSet, genDecl *ast.GenDecl, pkgPath string, kind symbols.SymbolKind, entries *[]symbols.Symbol) {
+func (c *packageCollector) collectValues(genDecl *ast.GenDecl, kind symbols.SymbolKind) {
 	for _, spec := range genDecl.Specs {
 		valSpec, ok := spec.(*ast.ValueSpec)
 		if !ok {
@@ -213,11 +499,15 @@ func collectValues(fset *token.FileSet, genDecl *ast.GenDecl, pkgPath string, ki
 			if !name.IsExported() {
 				continue
 			}
-			*entries = append(*entries, symbols.Symbol{
+			c.checkUnsupported(name.Name, unsupportedTypeExpr(valSpec.Type))
+			c.entries = append(c.entries, symbols.Symbol{
 				Kind:      kind,
 				Name:      name.Name,
-				Package:   pkgPath,
-				Signature: extractConstVarType(fset, valSpec),
+				Package:   c.pkgPath,
+				Signature: extractConstVarType(c.fset, valSpec),
+				Location:  c.location(name.Pos()),
+				Doc:       docExcerpt(specDoc(genDecl, valSpec.Doc)),
+				Refs:      c.typeRefs(nil, valSpec.Type),
 			})
 		}
 	}
@@ -226,7 +516,11 @@ func collectValues(fset *token.FileSet, genDecl *ast.GenDecl, pkgPath string, ki
 // computePackagePath derives the full Go import path for the package
 // containing the file at filePath, relative to the module source root.
 func computePackagePath(sourceRoot, filePath, module string) string {
-	dir := filepath.Dir(filePath)
+	return dirPackagePath(sourceRoot, filepath.Dir(filePath), module)
+}
+
+// dirPackagePath derives the full Go import path for the package in dir.
+func dirPackagePath(sourceRoot, dir, module string) string {
 	relDir, err := filepath.Rel(sourceRoot, dir)
 	if err != nil || relDir == "." || relDir == "" {
 		return module
@@ -2,11 +2,15 @@ package astdiff
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"testing"
 
+	"github.com/emenda-labs/emenda/core/changespec"
 	"github.com/emenda-labs/emenda/drivers/golang/symbols"
 )
 
@@ -313,3 +317,216 @@ func writeFile(path, content string) error {
 func mkdirAll(path string) error {
 	return os.MkdirAll(path, 0755)
 }
+
+func TestParseExports_SkipsNestedModules(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeFile(filepath.Join(dir, "go.mod"), "module github.com/acme/foo\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFile(filepath.Join(dir, "foo.go"), "package foo\n\nfunc Root() {}\n"); err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range []string{"otel", "tools", "plain"} {
+		if err := mkdirAll(filepath.Join(dir, sub)); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeFile(filepath.Join(dir, sub, sub+".go"), "package "+sub+"\n\nfunc Nested() {}\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, sub := range []string{"otel", "tools"} {
+		if err := writeFile(filepath.Join(dir, sub, "go.mod"), "module github.com/acme/foo/"+sub+"\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	syms, _, err := ParseExports(context.Background(), dir, "github.com/acme/foo")
+	if err != nil {
+		t.Fatalf("ParseExports: %v", err)
+	}
+
+	got := make(map[string]bool)
+	for _, s := range syms.Entries {
+		got[s.Package+"."+s.Name] = true
+	}
+	for _, key := range []string{"github.com/acme/foo.Root", "github.com/acme/foo/plain.Nested"} {
+		if !got[key] {
+			t.Errorf("missing symbol %s", key)
+		}
+	}
+	for _, key := range []string{"github.com/acme/foo/otel.Nested", "github.com/acme/foo/tools.Nested"} {
+		if got[key] {
+			t.Errorf("nested module symbol present: %s", key)
+		}
+	}
+
+	// Parsing a nested module as its own unit yields its symbols under its own path.
+	syms, _, err = ParseExports(context.Background(), filepath.Join(dir, "otel"), "github.com/acme/foo/otel")
+	if err != nil {
+		t.Fatalf("ParseExports nested: %v", err)
+	}
+	if len(syms.Entries) != 1 || syms.Entries[0].Package != "github.com/acme/foo/otel" {
+		t.Errorf("nested entries = %+v", syms.Entries)
+	}
+}
+
+func TestParseExports_ParseErrorDiagnostic(t *testing.T) {
+	dir := filepath.Join(testdataDir(t), "old")
+	syms, _, err := ParseExports(context.Background(), dir, "github.com/acme/testmod")
+	if err != nil {
+		t.Fatalf("ParseExports: %v", err)
+	}
+
+	if len(syms.Diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d: %+v", len(syms.Diagnostics), syms.Diagnostics)
+	}
+	d := syms.Diagnostics[0]
+	if d.Kind != changespec.DiagnosticParseError {
+		t.Errorf("kind = %q, want parse_error", d.Kind)
+	}
+	if d.File != "broken.go" || d.Line != 4 {
+		t.Errorf("position = %s:%d, want broken.go:4", d.File, d.Line)
+	}
+	if d.Package != "github.com/acme/testmod" {
+		t.Errorf("package = %q, want github.com/acme/testmod", d.Package)
+	}
+}
+
+func TestParseExports_UnsupportedDiagnostic(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeFile(filepath.Join(dir, "go.mod"), "module github.com/acme/unsup\n"); err != nil {
+		t.Fatal(err)
+	}
+	src := `package unsup
+
+const N = 4
+
+func Sum(v [2 * N]int) int { return 0 }
+
+type Buf struct {
+	Data [N + 1]byte
+}
+
+func Fine(v [N]int) {}
+`
+	if err := writeFile(filepath.Join(dir, "unsup.go"), src); err != nil {
+		t.Fatal(err)
+	}
+
+	syms, _, err := ParseExports(context.Background(), dir, "github.com/acme/unsup")
+	if err != nil {
+		t.Fatalf("ParseExports: %v", err)
+	}
+
+	got := make(map[string]changespec.Diagnostic)
+	for _, d := range syms.Diagnostics {
+		if d.Kind != changespec.DiagnosticUnsupported {
+			t.Errorf("unexpected diagnostic kind %q", d.Kind)
+		}
+		got[d.Symbol] = d
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected diagnostics for Sum and Buf, got %+v", syms.Diagnostics)
+	}
+	if d := got["Sum"]; d.File != "unsup.go" || d.Line != 5 {
+		t.Errorf("Sum diagnostic = %+v, want unsup.go:5", d)
+	}
+	if _, ok := got["Buf"]; !ok {
+		t.Error("missing diagnostic for Buf")
+	}
+}
+
+func TestParseExports_Locations(t *testing.T) {
+	dir := filepath.Join(testdataDir(t), "new")
+	syms, _, err := ParseExports(context.Background(), dir, "github.com/acme/testmod")
+	if err != nil {
+		t.Fatalf("ParseExports: %v", err)
+	}
+
+	want := map[string]changespec.Location{
+		"github.com/acme/testmod.DoWork":            {File: "foo.go", Line: 8, Column: 6},
+		"github.com/acme/testmod.Settings.Host":     {File: "foo.go", Line: 35, Column: 2},
+		"github.com/acme/testmod/sub.SubFunc":       {File: "sub/bar.go"},
+		"github.com/acme/testmod.Settings.Validate": {File: "foo.go"},
+	}
+	for _, s := range syms.Entries {
+		key := s.Package + "." + s.Name
+		exp, ok := want[key]
+		if !ok {
+			continue
+		}
+		delete(want, key)
+		if s.Location == nil {
+			t.Errorf("%s has no location", key)
+			continue
+		}
+		if s.Location.File != exp.File {
+			t.Errorf("%s file = %q, want %q", key, s.Location.File, exp.File)
+		}
+		if exp.Line != 0 && (s.Location.Line != exp.Line || s.Location.Column != exp.Column) {
+			t.Errorf("%s position = %s, want %s", key, s.Location, exp)
+		}
+	}
+	for key := range want {
+		t.Errorf("missing symbol %s", key)
+	}
+}
+
+// writeManyPackages creates a module with n packages, each with a few files.
+func writeManyPackages(t *testing.T, n int) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := writeFile(filepath.Join(dir, "go.mod"), "module github.com/acme/many\n"); err != nil {
+		t.Fatal(err)
+	}
+	for i := range n {
+		pkg := fmt.Sprintf("pkg%03d", i)
+		if err := mkdirAll(filepath.Join(dir, pkg)); err != nil {
+			t.Fatal(err)
+		}
+		for j := range 3 {
+			src := fmt.Sprintf("package %s\n\ntype T%d struct{ F int }\n\nfunc (T%d) M() {}\n\nfunc F%d(x int) error { return nil }\n", pkg, j, j, j)
+			if err := writeFile(filepath.Join(dir, pkg, fmt.Sprintf("f%d.go", j)), src); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return dir
+}
+
+func TestParseExports_DeterministicAcrossWorkers(t *testing.T) {
+	dir := writeManyPackages(t, 40)
+
+	serial, serialSigs, err := ParseExportsWithOptions(context.Background(), dir, "github.com/acme/many", ParseOptions{Workers: 1})
+	if err != nil {
+		t.Fatalf("ParseExports serial: %v", err)
+	}
+	if len(serial.Entries) != 40*3*4 {
+		t.Fatalf("expected %d entries, got %d", 40*3*4, len(serial.Entries))
+	}
+
+	for range 5 {
+		parallel, parallelSigs, err := ParseExportsWithOptions(context.Background(), dir, "github.com/acme/many", ParseOptions{Workers: 8})
+		if err != nil {
+			t.Fatalf("ParseExports parallel: %v", err)
+		}
+		if !reflect.DeepEqual(serial, parallel) {
+			t.Fatal("parallel parse produced different symbols than serial parse")
+		}
+		if !reflect.DeepEqual(serialSigs, parallelSigs) {
+			t.Fatal("parallel parse produced different signatures than serial parse")
+		}
+	}
+}
+
+func TestParseExports_Canceled(t *testing.T) {
+	dir := writeManyPackages(t, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := ParseExportsWithOptions(ctx, dir, "github.com/acme/many", ParseOptions{Workers: 4})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,398 @@
+package astdiff
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"github.com/emenda-labs/emenda/core/changespec"
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+// packageIndex holds the package-level declarations needed for reachability analysis.
+type packageIndex struct {
+	types   map[string]*ast.TypeSpec
+	methods map[string][]*ast.FuncDecl // keyed by receiver base type name
+	funcs   map[string]*ast.FuncDecl
+	vars    []*ast.ValueSpec
+}
+
+// indexPackage builds a packageIndex from the parsed files of one package.
+func indexPackage(files []*ast.File) *packageIndex {
+	idx := &packageIndex{
+		types:   make(map[string]*ast.TypeSpec),
+		methods: make(map[string][]*ast.FuncDecl),
+		funcs:   make(map[string]*ast.FuncDecl),
+	}
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Name == nil {
+					continue
+				}
+				if d.Recv != nil {
+					recv := receiverTypeName(d.Recv)
+					idx.methods[recv] = append(idx.methods[recv], d)
+				} else {
+					idx.funcs[d.Name.Name] = d
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch sp := spec.(type) {
+					case *ast.TypeSpec:
+						if sp.Name != nil {
+							idx.types[sp.Name.Name] = sp
+						}
+					case *ast.ValueSpec:
+						if d.Tok == token.VAR {
+							idx.vars = append(idx.vars, sp)
+						}
+					}
+				}
+			}
+		}
+	}
+	return idx
+}
+
+// reachableTypes returns the unexported type names of a package whose values
+// consumers can obtain through the exported API: as results of exported functions
+// and methods, as the type of exported fields and variables, through embedding,
+// through aliases, or by satisfying an exported interface. Exported methods and
+// fields of these types are callable by consumers even though the type cannot be named.
+func reachableTypes(fset *token.FileSet, files []*ast.File) map[string]bool {
+	idx := indexPackage(files)
+	reachable := make(map[string]bool)
+	var queue []string
+
+	mark := func(expr ast.Expr) {
+		for _, name := range localTypeRefs(expr) {
+			if ast.IsExported(name) || reachable[name] {
+				continue
+			}
+			if _, ok := idx.types[name]; !ok {
+				continue
+			}
+			reachable[name] = true
+			queue = append(queue, name)
+		}
+	}
+
+	// markType marks everything a consumer holding a value of the named type can reach.
+	markType := func(name string) {
+		for _, m := range idx.methods[name] {
+			if m.Name.IsExported() {
+				markFieldList(m.Type.Results, mark)
+			}
+		}
+		spec, ok := idx.types[name]
+		if !ok {
+			return
+		}
+		if spec.Assign.IsValid() {
+			mark(spec.Type)
+			return
+		}
+		switch t := spec.Type.(type) {
+		case *ast.StructType:
+			if t.Fields == nil {
+				return
+			}
+			for _, field := range t.Fields.List {
+				if len(field.Names) == 0 {
+					mark(field.Type)
+					continue
+				}
+				for _, n := range field.Names {
+					if n.IsExported() {
+						mark(field.Type)
+						break
+					}
+				}
+			}
+		case *ast.InterfaceType:
+			if t.Methods == nil {
+				return
+			}
+			for _, m := range t.Methods.List {
+				if ft, ok := m.Type.(*ast.FuncType); ok {
+					markFieldList(ft.Results, mark)
+				} else {
+					mark(m.Type)
+				}
+			}
+		}
+	}
+
+	// Roots: the exported surface of the package.
+	for name, fn := range idx.funcs {
+		if ast.IsExported(name) {
+			markFieldList(fn.Type.Results, mark)
+		}
+	}
+	for name := range idx.types {
+		if ast.IsExported(name) {
+			markType(name)
+		}
+	}
+	for _, spec := range idx.vars {
+		exported := false
+		for _, n := range spec.Names {
+			exported = exported || n.IsExported()
+		}
+		if !exported {
+			continue
+		}
+		if spec.Type != nil {
+			mark(spec.Type)
+			continue
+		}
+		for _, val := range spec.Values {
+			mark(inferredValueType(idx, val))
+		}
+	}
+
+	for {
+		for len(queue) > 0 {
+			name := queue[0]
+			queue = queue[1:]
+			markType(name)
+		}
+
+		// Interface satisfaction: an unexported type implementing an exported or
+		// reachable interface may be handed out as that interface.
+		for name := range idx.types {
+			if ast.IsExported(name) || reachable[name] {
+				continue
+			}
+			if satisfiesVisibleInterface(fset, idx, name, reachable) {
+				reachable[name] = true
+				queue = append(queue, name)
+			}
+		}
+		if len(queue) == 0 {
+			break
+		}
+	}
+
+	return reachable
+}
+
+// markFieldList applies mark to every field type in a parameter or result list.
+func markFieldList(fields *ast.FieldList, mark func(ast.Expr)) {
+	if fields == nil {
+		return
+	}
+	for _, field := range fields.List {
+		mark(field.Type)
+	}
+}
+
+// inferredValueType returns a type expression for untyped var initializers of the
+// forms T{...}, &T{...} and f(...) where f is a package-level function.
+// Returns nil when the type cannot be inferred syntactically.
+func inferredValueType(idx *packageIndex, val ast.Expr) ast.Expr {
+	if unary, ok := val.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		val = unary.X
+	}
+	switch v := val.(type) {
+	case *ast.CompositeLit:
+		return v.Type
+	case *ast.CallExpr:
+		ident, ok := v.Fun.(*ast.Ident)
+		if !ok {
+			return nil
+		}
+		fn, ok := idx.funcs[ident.Name]
+		if !ok || fn.Type.Results == nil || len(fn.Type.Results.List) == 0 {
+			return nil
+		}
+		return fn.Type.Results.List[0].Type
+	}
+	return nil
+}
+
+// localTypeRefs returns the unqualified identifiers referenced in a type expression.
+// Package-qualified names are skipped; they cannot refer to unexported local types.
+func localTypeRefs(expr ast.Expr) []string {
+	if expr == nil {
+		return nil
+	}
+	var names []string
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.SelectorExpr:
+			return false
+		case *ast.Ident:
+			names = append(names, x.Name)
+		}
+		return true
+	})
+	return names
+}
+
+// satisfiesVisibleInterface reports whether the named type's exported methods
+// cover every method of some exported or reachable interface in the package.
+// Methods are compared by name and rendered signature.
+func satisfiesVisibleInterface(fset *token.FileSet, idx *packageIndex, name string, reachable map[string]bool) bool {
+	methods := idx.methods[name]
+	if len(methods) == 0 {
+		return false
+	}
+	have := make(map[string]string, len(methods))
+	for _, m := range methods {
+		if m.Name.IsExported() {
+			have[m.Name.Name] = renderFuncSignature(extractFuncSignature(fset, m.Type))
+		}
+	}
+
+	for ifaceName, spec := range idx.types {
+		if !ast.IsExported(ifaceName) && !reachable[ifaceName] {
+			continue
+		}
+		iface, ok := spec.Type.(*ast.InterfaceType)
+		if !ok || iface.Methods == nil || len(iface.Methods.List) == 0 {
+			continue
+		}
+
+		satisfied := true
+		for _, m := range iface.Methods.List {
+			ft, ok := m.Type.(*ast.FuncType)
+			if !ok || len(m.Names) == 0 {
+				// Embedded interfaces are not expanded; be conservative.
+				satisfied = false
+				break
+			}
+			if have[m.Names[0].Name] != renderFuncSignature(extractFuncSignature(fset, ft)) {
+				satisfied = false
+				break
+			}
+		}
+		if satisfied {
+			return true
+		}
+	}
+	return false
+}
+
+// minHiddenMemberOverlap is the Jaccard overlap of member sets above which an
+// unexported receiver that disappeared is taken as renamed to one that appeared.
+const minHiddenMemberOverlap = 0.5
+
+// inferHiddenRenames detects renames of unexported types reachable from the
+// exported API. Such types have no symbol of their own, only their exported
+// members, so an unexported receiver that disappears while another appears in
+// the same package with mostly the same members is taken as renamed. Pairs
+// must be unambiguous on both sides.
+func (s *diffState) inferHiddenRenames() {
+	oldMembers := hiddenMembers(s.oldByKey, s.oldAliasMembers)
+	newMembers := hiddenMembers(s.newByKey, nil)
+
+	best := make(map[nameKey]nameKey)
+	claims := make(map[nameKey]int)
+	for oldRecv, oldSet := range oldMembers {
+		if _, ok := newMembers[oldRecv]; ok {
+			continue
+		}
+		var match nameKey
+		bestScore, tie := 0.0, false
+		for newRecv, newSet := range newMembers {
+			if newRecv.pkg != oldRecv.pkg {
+				continue
+			}
+			if _, ok := oldMembers[newRecv]; ok {
+				continue
+			}
+			score := setOverlap(oldSet, newSet)
+			switch {
+			case score > bestScore:
+				match, bestScore, tie = newRecv, score, false
+			case score == bestScore:
+				tie = true
+			}
+		}
+		if bestScore >= minHiddenMemberOverlap && !tie {
+			best[oldRecv] = match
+			claims[match]++
+		}
+	}
+
+	for oldRecv, newRecv := range best {
+		if claims[newRecv] == 1 {
+			s.hiddenRenames[oldRecv] = newRecv.name
+		}
+	}
+}
+
+// hiddenMembers groups the exported methods and fields of unexported receivers
+// by package and receiver name, skipping the keys in skip.
+func hiddenMembers(byKey map[symbolKey]*symbols.Symbol, skip map[symbolKey]struct{}) map[nameKey]map[string]bool {
+	members := make(map[nameKey]map[string]bool)
+	for key := range byKey {
+		if key.kind != symbols.SymbolMethod && key.kind != symbols.SymbolField {
+			continue
+		}
+		if _, ok := skip[key]; ok {
+			continue
+		}
+		recv, member, ok := strings.Cut(key.name, ".")
+		if !ok || token.IsExported(recv) {
+			continue
+		}
+		nk := nameKey{pkg: key.pkg, name: recv}
+		if members[nk] == nil {
+			members[nk] = make(map[string]bool)
+		}
+		members[nk][string(key.kind)+" "+member] = true
+	}
+	return members
+}
+
+// setOverlap returns the Jaccard similarity of two sets.
+func setOverlap(a, b map[string]bool) float64 {
+	common := 0
+	for k := range a {
+		if b[k] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// correlateHiddenMembers pairs the members of renamed unexported types (see
+// inferHiddenRenames). Consumers cannot name these types, so a member whose
+// signature only differs by the renamed types is unchanged; other signature
+// changes are reported against the old member.
+func (s *diffState) correlateHiddenMembers() {
+	if len(s.hiddenRenames) == 0 {
+		return
+	}
+	for _, oldKey := range s.unmatchedOld() {
+		if oldKey.kind != symbols.SymbolMethod && oldKey.kind != symbols.SymbolField {
+			continue
+		}
+		recv, member, _ := strings.Cut(oldKey.name, ".")
+		newRecv, ok := s.hiddenRenames[nameKey{pkg: oldKey.pkg, name: recv}]
+		if !ok {
+			continue
+		}
+		newKey := symbolKey{pkg: oldKey.pkg, kind: oldKey.kind, name: newRecv + "." + member}
+		if _, unmatched := s.unmatchedNewSet[newKey]; !unmatched {
+			continue
+		}
+
+		oldSym, newSym := s.oldByKey[oldKey], s.newByKey[newKey]
+		if !s.sameSignature(oldSym, newSym) {
+			s.emit(changespec.Change{
+				Kind:         changespec.ChangeKindSignatureChanged,
+				Symbol:       oldSym.Name,
+				Package:      oldSym.Package,
+				OldSignature: oldSym.Signature,
+				NewSignature: newSym.Signature,
+				Confidence:   changespec.ConfidenceHigh,
+			})
+		}
+		s.markMatched(oldKey, newKey)
+	}
+}
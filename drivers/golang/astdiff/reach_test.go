@@ -0,0 +1,211 @@
+package astdiff
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/emenda-labs/emenda/core/changespec"
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+func TestReachableTypes(t *testing.T) {
+	src := `package p
+
+import "io"
+
+type Closer interface {
+	Close() error
+}
+
+type Options struct {
+	Retry   *retryPolicy
+	backoff backoffPolicy
+	embedded
+}
+
+var Default = newClient()
+
+var Global = &state{}
+
+var Typed pool
+
+func New() *client { return nil }
+
+func Open() (io.Reader, error) { return nil, nil }
+
+func (c *client) Session() session { return session{} }
+
+type client struct{ Timeout int }
+type session struct{ ID string }
+type retryPolicy struct{ Max int }
+type backoffPolicy struct{ Base int }
+type embedded struct{}
+type state struct{}
+type pool struct{}
+type conn struct{}
+type handle struct{}
+type orphan struct{}
+
+func newClient() *client { return nil }
+
+func (conn) Close() error { return nil }
+func (h *handle) Close() {}
+func (orphan) Do() {}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	got := reachableTypes(fset, []*ast.File{file})
+
+	want := map[string]bool{
+		"client":      true, // exported func result
+		"session":     true, // result of a method on a reachable type
+		"retryPolicy": true, // exported field type
+		"embedded":    true, // embedded in exported struct
+		"state":       true, // untyped var initialized with &T{}
+		"pool":        true, // typed exported var
+		"conn":        true, // satisfies exported interface Closer
+	}
+	for name := range want {
+		if !got[name] {
+			t.Errorf("%s should be reachable", name)
+		}
+	}
+
+	for _, name := range []string{"backoffPolicy", "handle", "orphan"} {
+		if got[name] {
+			t.Errorf("%s should not be reachable", name)
+		}
+	}
+}
+
+func TestParseExports_UnexportedReachable(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeFile(filepath.Join(dir, "go.mod"), "module github.com/acme/reach\n"); err != nil {
+		t.Fatal(err)
+	}
+	// Declarations split across files: reachability is computed per package.
+	if err := writeFile(filepath.Join(dir, "new.go"), "package reach\n\nfunc New() *client { return nil }\n"); err != nil {
+		t.Fatal(err)
+	}
+	src := `package reach
+
+type client struct {
+	Timeout int
+	secret  string
+}
+
+func (c *client) Do(req string) error { return nil }
+
+func (c *client) reset() {}
+
+type hidden struct{ Value int }
+
+func (h hidden) Get() int { return h.Value }
+`
+	if err := writeFile(filepath.Join(dir, "client.go"), src); err != nil {
+		t.Fatal(err)
+	}
+
+	syms, sigMap, err := ParseExports(context.Background(), dir, "github.com/acme/reach")
+	if err != nil {
+		t.Fatalf("ParseExports: %v", err)
+	}
+
+	byName := make(map[string]symbols.Symbol)
+	for _, s := range syms.Entries {
+		byName[s.Name] = s
+	}
+
+	for _, name := range []string{"New", "client.Do", "client.Timeout"} {
+		if _, ok := byName[name]; !ok {
+			t.Errorf("missing symbol %s", name)
+		}
+	}
+	for _, name := range []string{"client", "client.reset", "client.secret", "hidden", "hidden.Get", "hidden.Value"} {
+		if _, ok := byName[name]; ok {
+			t.Errorf("unwanted symbol present: %s", name)
+		}
+	}
+
+	if byName["client.Do"].Receiver != "client" {
+		t.Errorf("client.Do receiver = %q, want client", byName["client.Do"].Receiver)
+	}
+	key := symbolKey{pkg: "github.com/acme/reach", kind: symbols.SymbolMethod, name: "client.Do"}
+	if _, ok := sigMap[key]; !ok {
+		t.Errorf("FuncSigMap missing key %+v", key)
+	}
+}
+
+func TestDiffExports_HiddenTypeRenamed(t *testing.T) {
+	parse := func(src string) (symbols.Symbols, FuncSigMap) {
+		t.Helper()
+		dir := t.TempDir()
+		if err := writeFile(filepath.Join(dir, "go.mod"), "module github.com/acme/reach\n"); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeFile(filepath.Join(dir, "reach.go"), src); err != nil {
+			t.Fatal(err)
+		}
+		syms, sigs, err := ParseExports(context.Background(), dir, "github.com/acme/reach")
+		if err != nil {
+			t.Fatalf("ParseExports: %v", err)
+		}
+		return syms, sigs
+	}
+
+	old, oldSigs := parse(`package reach
+
+func New() *client { return nil }
+
+func Open() *store { return nil }
+
+type client struct{ Timeout int }
+
+func (c *client) Do(req string) error { return nil }
+func (c *client) Clone() *client      { return c }
+func (c *client) Retry(n int)         {}
+
+type store struct{}
+
+func (s *store) Flush() {}
+`)
+	new, newSigs := parse(`package reach
+
+func New() *conn { return nil }
+
+func Open() *cache { return nil }
+
+type conn struct{ Timeout int }
+
+func (c *conn) Do(req string) error { return nil }
+func (c *conn) Clone() *conn        { return c }
+func (c *conn) Retry(n int64)       {}
+
+type cache struct{}
+
+func (c *cache) Evict() {}
+`)
+
+	got := make(map[string]changespec.ChangeKind)
+	for _, c := range DiffExports(old, new, oldSigs, newSigs) {
+		got[c.Symbol] = c.Kind
+	}
+	want := map[string]changespec.ChangeKind{
+		"client.Retry": changespec.ChangeKindSignatureChanged,
+		// store and cache share no members, so they are not paired.
+		"Open":        changespec.ChangeKindSignatureChanged,
+		"store.Flush": changespec.ChangeKindRemoved,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+package astdiff
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+// ParserVersion identifies the output format of ParseExports. Bump it whenever
+// ParseExports produces different symbols or signatures for the same source, so
+// persisted snapshots from older parsers are invalidated.
+const ParserVersion = 7
+
+// funcSigEntry is the serialized form of one FuncSigMap entry.
+type funcSigEntry struct {
+	Package    string             `json:"package"`
+	Kind       symbols.SymbolKind `json:"kind"`
+	Name       string             `json:"name"`
+	Params     []string           `json:"params,omitempty"`
+	ParamNames []string           `json:"param_names,omitempty"`
+	Results    []string           `json:"results,omitempty"`
+}
+
+// MarshalJSON encodes the map as a list of entries sorted by package, kind and name.
+func (m FuncSigMap) MarshalJSON() ([]byte, error) {
+	entries := make([]funcSigEntry, 0, len(m))
+	for key, sig := range m {
+		entries = append(entries, funcSigEntry{
+			Package:    key.pkg,
+			Kind:       key.kind,
+			Name:       key.name,
+			Params:     sig.params,
+			ParamNames: sig.paramNames,
+			Results:    sig.results,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Package != entries[j].Package {
+			return entries[i].Package < entries[j].Package
+		}
+		if entries[i].Kind != entries[j].Kind {
+			return entries[i].Kind < entries[j].Kind
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return json.Marshal(entries)
+}
+
+// UnmarshalJSON decodes the list form produced by MarshalJSON.
+func (m *FuncSigMap) UnmarshalJSON(data []byte) error {
+	var entries []funcSigEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	out := make(FuncSigMap, len(entries))
+	for _, e := range entries {
+		out[symbolKey{pkg: e.Package, kind: e.Kind, name: e.Name}] = funcSignature{params: e.Params, paramNames: e.ParamNames, results: e.Results}
+	}
+	*m = out
+	return nil
+}
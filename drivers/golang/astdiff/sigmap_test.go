@@ -0,0 +1,40 @@
+package astdiff
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFuncSigMap_JSONRoundTrip(t *testing.T) {
+	_, sigMap, err := ParseExports(context.Background(), filepath.Join(testdataDir(t), "new"), "github.com/acme/testmod")
+	if err != nil {
+		t.Fatalf("ParseExports: %v", err)
+	}
+	if len(sigMap) == 0 {
+		t.Fatal("expected signatures in testdata")
+	}
+
+	data, err := json.Marshal(sigMap)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var got FuncSigMap
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(got, sigMap) {
+		t.Errorf("round trip mismatch:\n got %v\nwant %v", got, sigMap)
+	}
+
+	// Encoding is deterministic so cache entries are reproducible.
+	again, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(again) != string(data) {
+		t.Error("encoding is not deterministic")
+	}
+}
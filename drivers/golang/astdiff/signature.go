@@ -5,14 +5,16 @@ import (
 	"go/ast"
 	"go/token"
 	"sort"
+	"strconv"
 	"strings"
 )
 
 // funcSignature holds structured function parameter and result types.
 // Used for param overlap comparison in DiffExports Pass 5.
 type funcSignature struct {
-	params  []string // parameter type strings only
-	results []string // result type strings only
+	params     []string // parameter type strings only
+	paramNames []string // parameter names parallel to params; empty for unnamed parameters
+	results    []string // result type strings only
 }
 
 // maxTypeDepth is the maximum nesting depth for type expression rendering.
@@ -52,13 +54,10 @@ func renderTypeExprDepth(fset *token.FileSet, expr ast.Expr, depth int) string {
 		return "map[" + renderTypeExprDepth(fset, e.Key, next) + "]" + renderTypeExprDepth(fset, e.Value, next)
 
 	case *ast.InterfaceType:
-		if e.Methods == nil || len(e.Methods.List) == 0 {
-			return "interface{}"
-		}
-		return "interface{...}"
+		return renderInterfaceDepth(fset, e, next)
 
 	case *ast.FuncType:
-		sig := extractFuncSignature(fset, e)
+		sig := extractFuncSignatureDepth(fset, e, next)
 		return "func" + renderFuncSignature(sig)
 
 	case *ast.Ellipsis:
@@ -75,7 +74,7 @@ func renderTypeExprDepth(fset *token.FileSet, expr ast.Expr, depth int) string {
 		}
 
 	case *ast.StructType:
-		return "struct{...}"
+		return renderInlineStruct(fset, e, next)
 
 	case *ast.IndexExpr:
 		return renderTypeExprDepth(fset, e.X, next) + "[" + renderTypeExprDepth(fset, e.Index, next) + "]"
@@ -93,39 +92,223 @@ func renderTypeExprDepth(fset *token.FileSet, expr ast.Expr, depth int) string {
 	case *ast.BasicLit:
 		return e.Value
 
+	case *ast.UnaryExpr:
+		// Approximation element of a type set: ~T.
+		if e.Op != token.TILDE {
+			return "unknown"
+		}
+		return "~" + renderTypeExprDepth(fset, e.X, next)
+
+	case *ast.BinaryExpr:
+		// Union of type set terms: A | B.
+		if e.Op != token.OR {
+			return "unknown"
+		}
+		return renderTypeExprDepth(fset, e.X, next) + " | " + renderTypeExprDepth(fset, e.Y, next)
+
 	default:
 		return "unknown"
 	}
 }
 
+// renderInlineStruct renders an anonymous struct type. Unlike a declared struct,
+// every field takes part in type identity, so unexported fields and tags are
+// kept. Fields declared together (a, b int) are listed one per name and tags are
+// requoted, so the result does not depend on source formatting.
+func renderInlineStruct(fset *token.FileSet, structType *ast.StructType, depth int) string {
+	if structType.Fields == nil || len(structType.Fields.List) == 0 {
+		return "struct{}"
+	}
+
+	var fields []string
+	for _, field := range structType.Fields.List {
+		typeStr := renderTypeExprDepth(fset, field.Type, depth)
+
+		var tag string
+		if field.Tag != nil {
+			// An empty tag is identical to no tag.
+			if value, err := strconv.Unquote(field.Tag.Value); err == nil && value != "" {
+				tag = " " + strconv.Quote(value)
+			}
+		}
+
+		if len(field.Names) == 0 {
+			// Embedded field.
+			fields = append(fields, typeStr+tag)
+			continue
+		}
+		for _, name := range field.Names {
+			fields = append(fields, name.Name+" "+typeStr+tag)
+		}
+	}
+	return "struct{" + strings.Join(fields, "; ") + "}"
+}
+
+// unsupportedTypeExpr returns the first sub-expression that renderTypeExpr renders
+// as "unknown", or nil if the whole expression is supported. It descends exactly
+// where the renderer does.
+func unsupportedTypeExpr(expr ast.Expr) ast.Expr {
+	return unsupportedTypeExprDepth(expr, 0)
+}
+
+func unsupportedTypeExprDepth(expr ast.Expr, depth int) ast.Expr {
+	if expr == nil || depth > maxTypeDepth {
+		return nil
+	}
+
+	next := depth + 1
+
+	switch e := expr.(type) {
+	case *ast.Ident, *ast.BasicLit:
+		return nil
+	case *ast.StructType:
+		if e.Fields == nil {
+			return nil
+		}
+		for _, field := range e.Fields.List {
+			if bad := unsupportedTypeExprDepth(field.Type, next); bad != nil {
+				return bad
+			}
+		}
+		return nil
+	case *ast.InterfaceType:
+		return unsupportedInterface(e, next)
+	case *ast.UnaryExpr:
+		if e.Op != token.TILDE {
+			return expr
+		}
+		return unsupportedTypeExprDepth(e.X, next)
+	case *ast.BinaryExpr:
+		if e.Op != token.OR {
+			return expr
+		}
+		if bad := unsupportedTypeExprDepth(e.X, next); bad != nil {
+			return bad
+		}
+		return unsupportedTypeExprDepth(e.Y, next)
+	case *ast.SelectorExpr:
+		return unsupportedTypeExprDepth(e.X, next)
+	case *ast.StarExpr:
+		return unsupportedTypeExprDepth(e.X, next)
+	case *ast.ArrayType:
+		if bad := unsupportedTypeExprDepth(e.Len, next); bad != nil {
+			return bad
+		}
+		return unsupportedTypeExprDepth(e.Elt, next)
+	case *ast.MapType:
+		if bad := unsupportedTypeExprDepth(e.Key, next); bad != nil {
+			return bad
+		}
+		return unsupportedTypeExprDepth(e.Value, next)
+	case *ast.FuncType:
+		return unsupportedFuncTypeDepth(e, next)
+	case *ast.Ellipsis:
+		return unsupportedTypeExprDepth(e.Elt, next)
+	case *ast.ChanType:
+		return unsupportedTypeExprDepth(e.Value, next)
+	case *ast.IndexExpr:
+		if bad := unsupportedTypeExprDepth(e.X, next); bad != nil {
+			return bad
+		}
+		return unsupportedTypeExprDepth(e.Index, next)
+	case *ast.IndexListExpr:
+		if bad := unsupportedTypeExprDepth(e.X, next); bad != nil {
+			return bad
+		}
+		for _, idx := range e.Indices {
+			if bad := unsupportedTypeExprDepth(idx, next); bad != nil {
+				return bad
+			}
+		}
+		return nil
+	case *ast.ParenExpr:
+		return unsupportedTypeExprDepth(e.X, next)
+	default:
+		return expr
+	}
+}
+
+// unsupportedFuncType applies unsupportedTypeExpr to every parameter and result type.
+func unsupportedFuncType(funcType *ast.FuncType) ast.Expr {
+	return unsupportedFuncTypeDepth(funcType, 0)
+}
+
+func unsupportedFuncTypeDepth(funcType *ast.FuncType, depth int) ast.Expr {
+	if funcType == nil {
+		return nil
+	}
+	for _, list := range []*ast.FieldList{funcType.Params, funcType.Results} {
+		if list == nil {
+			continue
+		}
+		for _, field := range list.List {
+			if bad := unsupportedTypeExprDepth(field.Type, depth); bad != nil {
+				return bad
+			}
+		}
+	}
+	return nil
+}
+
+// unsupportedInterface applies unsupportedTypeExpr to every method signature and
+// embedded element of an interface.
+func unsupportedInterface(interfaceType *ast.InterfaceType, depth int) ast.Expr {
+	if interfaceType.Methods == nil {
+		return nil
+	}
+	for _, method := range interfaceType.Methods.List {
+		if funcType, ok := method.Type.(*ast.FuncType); ok {
+			if bad := unsupportedFuncTypeDepth(funcType, depth); bad != nil {
+				return bad
+			}
+			continue
+		}
+		if bad := unsupportedTypeExprDepth(method.Type, depth); bad != nil {
+			return bad
+		}
+	}
+	return nil
+}
+
 // extractFuncSignature extracts structured parameter and result types from a function type.
 // Handles multiple names per field (e.g. a, b int) and variadic parameters.
 func extractFuncSignature(fset *token.FileSet, funcType *ast.FuncType) funcSignature {
+	return extractFuncSignatureDepth(fset, funcType, 0)
+}
+
+func extractFuncSignatureDepth(fset *token.FileSet, funcType *ast.FuncType, depth int) funcSignature {
 	if funcType == nil {
 		return funcSignature{}
 	}
 
-	var params []string
+	var params, paramNames []string
+	named := false
 	if funcType.Params != nil {
 		for _, field := range funcType.Params.List {
-			typeStr := renderTypeExpr(fset, field.Type)
+			typeStr := renderTypeExprDepth(fset, field.Type, depth)
 
 			if len(field.Names) == 0 {
 				// Unnamed parameter (common in interface method signatures).
 				params = append(params, typeStr)
+				paramNames = append(paramNames, "")
 			} else {
 				// Expand one entry per name sharing the same type.
-				for range field.Names {
+				for _, name := range field.Names {
 					params = append(params, typeStr)
+					paramNames = append(paramNames, name.Name)
 				}
+				named = true
 			}
 		}
 	}
+	if !named {
+		paramNames = nil
+	}
 
 	var results []string
 	if funcType.Results != nil {
 		for _, field := range funcType.Results.List {
-			typeStr := renderTypeExpr(fset, field.Type)
+			typeStr := renderTypeExprDepth(fset, field.Type, depth)
 			if len(field.Names) == 0 {
 				results = append(results, typeStr)
 			} else {
@@ -136,7 +319,7 @@ func extractFuncSignature(fset *token.FileSet, funcType *ast.FuncType) funcSigna
 		}
 	}
 
-	return funcSignature{params: params, results: results}
+	return funcSignature{params: params, paramNames: paramNames, results: results}
 }
 
 // renderFuncSignature renders a funcSignature to its canonical string form.
@@ -172,6 +355,30 @@ func extractTypeSignature(fset *token.FileSet, typeSpec *ast.TypeSpec) string {
 	}
 }
 
+// unsupportedTypeSpec applies unsupportedTypeExpr to everything extractTypeSignature renders.
+func unsupportedTypeSpec(typeSpec *ast.TypeSpec) ast.Expr {
+	if typeSpec.Assign.IsValid() {
+		return unsupportedTypeExpr(typeSpec.Type)
+	}
+
+	switch t := typeSpec.Type.(type) {
+	case *ast.StructType:
+		if t.Fields == nil {
+			return nil
+		}
+		for _, field := range t.Fields.List {
+			if bad := unsupportedTypeExpr(field.Type); bad != nil {
+				return bad
+			}
+		}
+		return nil
+	case *ast.InterfaceType:
+		return unsupportedInterface(t, 0)
+	default:
+		return unsupportedTypeExpr(typeSpec.Type)
+	}
+}
+
 // renderStructSignature produces "struct{Field1 Type1; Field2 Type2}" with exported fields only.
 func renderStructSignature(fset *token.FileSet, structType *ast.StructType) string {
 	if structType.Fields == nil || len(structType.Fields.List) == 0 {
@@ -204,6 +411,12 @@ func renderStructSignature(fset *token.FileSet, structType *ast.StructType) stri
 
 // renderInterfaceSignature produces "interface{Method1(sig); Method2(sig)}" sorted alphabetically.
 func renderInterfaceSignature(fset *token.FileSet, interfaceType *ast.InterfaceType) string {
+	return renderInterfaceDepth(fset, interfaceType, 0)
+}
+
+// renderInterfaceDepth renders methods, embedded interfaces and type set terms,
+// sorted alphabetically since their order does not affect the interface.
+func renderInterfaceDepth(fset *token.FileSet, interfaceType *ast.InterfaceType, depth int) string {
 	if interfaceType.Methods == nil || len(interfaceType.Methods.List) == 0 {
 		return "interface{}"
 	}
@@ -214,12 +427,12 @@ func renderInterfaceSignature(fset *token.FileSet, interfaceType *ast.InterfaceT
 			// Named method.
 			name := method.Names[0].Name
 			if funcType, ok := method.Type.(*ast.FuncType); ok {
-				sig := extractFuncSignature(fset, funcType)
+				sig := extractFuncSignatureDepth(fset, funcType, depth)
 				entries = append(entries, name+renderFuncSignature(sig))
 			}
 		} else {
-			// Embedded interface.
-			entries = append(entries, renderTypeExpr(fset, method.Type))
+			// Embedded interface or type set.
+			entries = append(entries, renderTypeExprDepth(fset, method.Type, depth))
 		}
 	}
 
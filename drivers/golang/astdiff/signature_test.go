@@ -1,10 +1,18 @@
 package astdiff
 
 import (
+	"context"
+	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/emenda-labs/emenda/core/changespec"
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
 )
 
 func TestRenderTypeExpr(t *testing.T) {
@@ -27,6 +35,13 @@ func TestRenderTypeExpr(t *testing.T) {
 		{"nested_pointer_slice", "type T = *[]int", "*[]int"},
 		{"map_of_slices", "type T = map[string][]int", "map[string][]int"},
 		{"paren", "type T = (int)", "(int)"},
+		{"inline_struct", "type T = struct{ A, B int; c string }", "struct{A int; B int; c string}"},
+		{"inline_struct_tags", "type T = struct{ ID string `json:\"id\"`; Empty int `` }", `struct{ID string "json:\"id\""; Empty int}`},
+		{"inline_struct_embedded", "type T = struct{ *context.Context; int }", "struct{*context.Context; int}"},
+		{"inline_struct_nested", "type T = []struct{ Inner struct{ X int } }", "[]struct{Inner struct{X int}}"},
+		{"inline_interface", "type T = interface{ Close() error; Read(p []byte) (n int, err error) }", "interface{Close() error; Read([]byte) (int, error)}"},
+		{"inline_interface_embedded", "type T = func() interface{ context.Context; Extra() }", "func() interface{Extra(); context.Context}"},
+		{"type_set", "type T = interface{ ~int | ~string; String() string }", "interface{String() string; ~int | ~string}"},
 	}
 
 	for _, tt := range tests {
@@ -355,3 +370,159 @@ func slicesEqual(a, b []string) bool {
 	}
 	return true
 }
+
+func TestUnsupportedTypeExpr(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string // type T = <expr>
+		want bool
+	}{
+		{"ident", "type T = int", false},
+		{"array_const", "type T = [N]int", false},
+		{"array_binary", "type T = [2 * N]int", true},
+		{"nested_binary", "type T = map[string][N + 1]byte", true},
+		{"func_param", "type T = func([N - 1]int) error", true},
+		{"inline_struct", "type T = struct{ A [N]int }", false},
+		{"inline_struct_field", "type T = struct{ A [2 * N]int }", true},
+		{"inline_interface_method", "type T = interface{ M([N + 1]int) }", true},
+		{"type_set", "type T = interface{ ~int | ~string }", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "", "package p\nconst N = 2\n"+tt.src, 0)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			genDecl := file.Decls[1].(*ast.GenDecl)
+			typeSpec := genDecl.Specs[0].(*ast.TypeSpec)
+
+			got := unsupportedTypeExpr(typeSpec.Type) != nil
+			if got != tt.want {
+				t.Errorf("unsupported = %v, want %v", got, tt.want)
+			}
+			if rendered := renderTypeExpr(fset, typeSpec.Type); strings.Contains(rendered, "unknown") != tt.want {
+				t.Errorf("checker disagrees with renderer: %q", rendered)
+			}
+		})
+	}
+}
+
+func TestRenderTypeExpr_RoundTrip(t *testing.T) {
+	// Each rendering must parse back to an equivalent type: rendering the reparsed
+	// expression is a fixed point, and go/types considers both types identical.
+	exprs := []string{
+		"struct{}",
+		"struct{ A, B int; c string }",
+		"struct{ ID string `json:\"id,omitempty\" db:\"id\"`; Raw []byte `raw:\"a\\tb\"` }",
+		"struct{ *Named; Named2; Tagged int `x:\"1\"` }",
+		"[]struct{ Inner struct{ X int }; F func(struct{ Y string }) error }",
+		"map[string]struct{ Ch <-chan struct{}; Send chan<- int }",
+		"interface{}",
+		"interface{ Close() error; Read(p []byte) (n int, err error) }",
+		"interface{ Named3; Extra(...int) }",
+		"interface{ ~int | ~string; String() string }",
+		"func(interface{ M() }) struct{ A [4]byte }",
+		"*struct{ Next *struct{ V int } }",
+	}
+	prelude := `package p
+
+type Named struct{ V int }
+type Named2 int
+type Named3 interface{ Base() }
+`
+	for i, src := range exprs {
+		t.Run(src, func(t *testing.T) {
+			fset := token.NewFileSet()
+			orig, err := parser.ParseExpr(src)
+			if err != nil {
+				t.Fatalf("parse original: %v", err)
+			}
+			rendered := renderTypeExpr(fset, orig)
+
+			reparsed, err := parser.ParseExpr(rendered)
+			if err != nil {
+				t.Fatalf("rendered %q does not parse: %v", rendered, err)
+			}
+			if again := renderTypeExpr(fset, reparsed); again != rendered {
+				t.Errorf("not a fixed point:\n first  %q\n second %q", rendered, again)
+			}
+
+			// Type sets are only valid as constraints, so compare those as interfaces
+			// through a type parameter bound.
+			decl := "type Orig = %s\ntype Rendered = %s\n"
+			if strings.Contains(src, "~") {
+				decl = "func Orig[_ %s]() {}\nfunc Rendered[_ %s]() {}\n"
+			}
+			file, err := parser.ParseFile(fset, fmt.Sprintf("rt%d.go", i), prelude+fmt.Sprintf(decl, src, rendered), 0)
+			if err != nil {
+				t.Fatalf("parse package: %v", err)
+			}
+			pkg, err := new(types.Config).Check("p", fset, []*ast.File{file}, nil)
+			if err != nil {
+				t.Fatalf("type check: %v", err)
+			}
+			a, b := pkg.Scope().Lookup("Orig").Type(), pkg.Scope().Lookup("Rendered").Type()
+			if sa, ok := a.(*types.Signature); ok {
+				a = sa.TypeParams().At(0).Constraint()
+				b = b.(*types.Signature).TypeParams().At(0).Constraint()
+			}
+			if !types.Identical(a, b) {
+				t.Errorf("rendered type %q is not identical to %q (%s vs %s)", rendered, src, a, b)
+			}
+		})
+	}
+}
+
+func TestRenderTypeExpr_FormattingStable(t *testing.T) {
+	variants := []string{
+		"struct{ A, B int; Tag string `json:\"tag\"` }",
+		"struct {\n\tA int // first\n\tB int\n\n\tTag string \"json:\\\"tag\\\"\"\n}",
+	}
+	fset := token.NewFileSet()
+	var want string
+	for i, src := range variants {
+		expr, err := parser.ParseExpr(src)
+		if err != nil {
+			t.Fatalf("parse %q: %v", src, err)
+		}
+		got := renderTypeExpr(fset, expr)
+		if i == 0 {
+			want = got
+			continue
+		}
+		if got != want {
+			t.Errorf("formatting changed rendering:\n %q\n %q", want, got)
+		}
+	}
+}
+
+func TestParseExports_InlineStructParamChange(t *testing.T) {
+	parse := func(src string) (symbols.Symbols, FuncSigMap) {
+		t.Helper()
+		dir := t.TempDir()
+		if err := writeFile(filepath.Join(dir, "go.mod"), "module github.com/acme/inline\n"); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeFile(filepath.Join(dir, "inline.go"), "package inline\n\n"+src); err != nil {
+			t.Fatal(err)
+		}
+		syms, sigs, err := ParseExports(context.Background(), dir, "github.com/acme/inline")
+		if err != nil {
+			t.Fatalf("ParseExports: %v", err)
+		}
+		return syms, sigs
+	}
+
+	old, oldSigs := parse("func Configure(opts struct{ Host string }) interface{ Close() error } { return nil }\n")
+	new, newSigs := parse("func Configure(opts struct{ Host string; Port int }) interface{ Close() error } { return nil }\n")
+
+	changes := DiffExports(old, new, oldSigs, newSigs)
+	if len(changes) != 1 || changes[0].Kind != changespec.ChangeKindSignatureChanged {
+		t.Fatalf("expected one signature change, got %+v", changes)
+	}
+	if want := "(struct{Host string; Port int}) interface{Close() error}"; changes[0].NewSignature != want {
+		t.Errorf("new signature = %q, want %q", changes[0].NewSignature, want)
+	}
+}
@@ -0,0 +1,168 @@
+package astdiff
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultTagKeys are the struct tag keys recorded when ParseOptions.TagKeys is nil.
+var DefaultTagKeys = []string{"json", "yaml", "xml", "protobuf", "db"}
+
+// rawTagKeys lists tag keys whose values do not follow the "name,opt,opt"
+// convention. Their values are compared as opaque strings.
+var rawTagKeys = map[string]bool{
+	"protobuf": true,
+}
+
+// parseStructTag extracts the values for keys from a struct field tag literal.
+// Returns nil if the field has no tag or none of the keys are present.
+func parseStructTag(lit *ast.BasicLit, keys []string) map[string]string {
+	if lit == nil || len(keys) == 0 {
+		return nil
+	}
+
+	raw, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return nil
+	}
+
+	tag := reflect.StructTag(raw)
+	var tags map[string]string
+	for _, key := range keys {
+		val, ok := tag.Lookup(key)
+		if !ok {
+			continue
+		}
+		if tags == nil {
+			tags = make(map[string]string, len(keys))
+		}
+		tags[key] = val
+	}
+	return tags
+}
+
+// renderTag renders a single tag key/value pair in source form, e.g. json:"user_id".
+// Returns an empty string when the key is absent.
+func renderTag(key, val string, ok bool) string {
+	if !ok {
+		return ""
+	}
+	return key + ":" + strconv.Quote(val)
+}
+
+// defaultTagNames gives, for tag keys whose usual library is known, the name
+// that library uses for a field the tag does not name: encoding/json and
+// encoding/xml keep the Go field name, gopkg.in/yaml lowercases it. Other keys,
+// such as db (the name comes from the sqlx mapper) and keys added through
+// ParseOptions.TagKeys, have no known default.
+var defaultTagNames = map[string]func(fieldName string) string{
+	"json": func(fieldName string) string { return fieldName },
+	"xml":  func(fieldName string) string { return fieldName },
+	"yaml": strings.ToLower,
+}
+
+// tagValue splits a conventional tag value into its name and option set. An
+// absent tag or empty name falls back to the default name for key; known is
+// false when key has no known default, and name is then empty.
+func tagValue(key, fieldName, val string, ok bool) (name string, known bool, opts map[string]bool) {
+	parts := strings.Split(val, ",")
+	if ok {
+		name = parts[0]
+	}
+	if name == "" {
+		defaultName := defaultTagNames[key]
+		if defaultName == nil {
+			return "", false, tagOptions(parts, ok)
+		}
+		name = defaultName(fieldName)
+	}
+	return name, true, tagOptions(parts, ok)
+}
+
+// tagOptions returns the option set of a split tag value, or nil when the tag
+// is absent or has no options.
+func tagOptions(parts []string, ok bool) map[string]bool {
+	if !ok {
+		return nil
+	}
+	var opts map[string]bool
+	for _, opt := range parts[1:] {
+		if opt == "" {
+			continue
+		}
+		if opts == nil {
+			opts = make(map[string]bool, len(parts)-1)
+		}
+		opts[opt] = true
+	}
+	return opts
+}
+
+// renderTagName quotes a field name for a change detail. A name with no known
+// default is shown as "(default)".
+func renderTagName(name string, known bool) string {
+	if !known {
+		return "(default)"
+	}
+	return strconv.Quote(name)
+}
+
+// compareTag describes how a single tag key changed between two versions of a field.
+// Returns an empty string when the change has no effect on serialization.
+func compareTag(key, fieldName, oldVal, newVal string, oldOK, newOK bool) string {
+	if oldOK == newOK && oldVal == newVal {
+		return ""
+	}
+
+	if rawTagKeys[key] {
+		return fmt.Sprintf("%s: %q -> %q", key, oldVal, newVal)
+	}
+
+	oldExcluded := oldOK && oldVal == "-"
+	newExcluded := newOK && newVal == "-"
+	switch {
+	case !oldExcluded && newExcluded:
+		return key + ": field now excluded (\"-\")"
+	case oldExcluded && !newExcluded:
+		return key + ": field no longer excluded"
+	case oldExcluded && newExcluded:
+		return ""
+	}
+
+	oldName, oldKnown, oldOpts := tagValue(key, fieldName, oldVal, oldOK)
+	newName, newKnown, newOpts := tagValue(key, fieldName, newVal, newOK)
+
+	var details []string
+	if oldName != newName || oldKnown != newKnown {
+		details = append(details, fmt.Sprintf("name %s -> %s", renderTagName(oldName, oldKnown), renderTagName(newName, newKnown)))
+	}
+	for _, opt := range sortedKeys(oldOpts) {
+		if !newOpts[opt] {
+			details = append(details, opt+" removed")
+		}
+	}
+	for _, opt := range sortedKeys(newOpts) {
+		if !oldOpts[opt] {
+			details = append(details, opt+" added")
+		}
+	}
+
+	if len(details) == 0 {
+		return ""
+	}
+	return key + ": " + strings.Join(details, ", ")
+}
+
+// sortedKeys returns the keys of m in ascending order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,170 @@
+package astdiff
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/emenda-labs/emenda/core/changespec"
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+func TestCompareTag(t *testing.T) {
+	tests := []struct {
+		name           string
+		key            string
+		oldVal, newVal string
+		oldOK, newOK   bool
+		want           string
+	}{
+		{"unchanged", "json", "user_id", "user_id", true, true, ""},
+		{"renamed", "json", "user_id", "userId", true, true, `json: name "user_id" -> "userId"`},
+		{"omitempty_added", "json", "id", "id,omitempty", true, true, "json: omitempty added"},
+		{"omitempty_removed", "yaml", "id,omitempty", "id", true, true, "yaml: omitempty removed"},
+		{"excluded", "json", "id", "-", true, true, `json: field now excluded ("-")`},
+		{"unexcluded", "json", "-", "id", true, true, "json: field no longer excluded"},
+		{"dash_name", "json", "-,", "-", true, true, `json: field now excluded ("-")`},
+		{"tag_added_same_name", "json", "", "ID", false, true, ""},
+		{"empty_name_same_field", "json", "ID", ",omitempty", true, true, "json: omitempty added"},
+		{"tag_removed", "json", "user_id", "", true, false, `json: name "user_id" -> "ID"`},
+		{"yaml_tag_added_lowercase", "yaml", "", "id", false, true, ""},
+		{"yaml_tag_added_field_case", "yaml", "", "ID", false, true, `yaml: name "id" -> "ID"`},
+		{"yaml_empty_name", "yaml", "id", ",omitempty", true, true, "yaml: omitempty added"},
+		{"xml_tag_added_same_name", "xml", "", "ID", false, true, ""},
+		{"db_tag_added", "db", "", "id", false, true, `db: name (default) -> "id"`},
+		{"db_tag_removed", "db", "id", "", true, false, `db: name "id" -> (default)`},
+		{"db_empty_name", "db", ",omitempty", "", true, false, "db: omitempty removed"},
+		{"reordered_options", "json", "id,omitempty,string", "id,string,omitempty", true, true, ""},
+		{"protobuf_raw", "protobuf", "bytes,1,opt,name=id", "bytes,2,opt,name=id", true, true, `protobuf: "bytes,1,opt,name=id" -> "bytes,2,opt,name=id"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareTag(tt.key, "ID", tt.oldVal, tt.newVal, tt.oldOK, tt.newOK)
+			if got != tt.want {
+				t.Errorf("compareTag = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExports_FieldTags(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeFile(filepath.Join(dir, "go.mod"), "module github.com/acme/tags\n"); err != nil {
+		t.Fatal(err)
+	}
+	src := "package tags\n\ntype User struct {\n" +
+		"\tID   string `json:\"user_id\" db:\"id\" custom:\"x\"`\n" +
+		"\tName string\n" +
+		"}\n"
+	if err := writeFile(filepath.Join(dir, "user.go"), src); err != nil {
+		t.Fatal(err)
+	}
+
+	syms, _, err := ParseExports(context.Background(), dir, "github.com/acme/tags")
+	if err != nil {
+		t.Fatalf("ParseExports: %v", err)
+	}
+
+	byName := make(map[string]symbols.Symbol)
+	for _, s := range syms.Entries {
+		byName[s.Name] = s
+	}
+
+	id := byName["User.ID"]
+	if id.Tags["json"] != "user_id" || id.Tags["db"] != "id" {
+		t.Errorf("User.ID tags = %v, want json=user_id db=id", id.Tags)
+	}
+	if _, ok := id.Tags["custom"]; ok {
+		t.Error("custom tag key should not be recorded by default")
+	}
+	if byName["User.Name"].Tags != nil {
+		t.Errorf("User.Name tags = %v, want nil", byName["User.Name"].Tags)
+	}
+
+	// The struct signature stays tag-free so tag edits are not compile breaks.
+	if got := byName["User"].Signature; got != "struct{ID string; Name string}" {
+		t.Errorf("User signature = %q", got)
+	}
+
+	syms, _, err = ParseExportsWithOptions(context.Background(), dir, "github.com/acme/tags", ParseOptions{TagKeys: []string{"custom"}})
+	if err != nil {
+		t.Fatalf("ParseExportsWithOptions: %v", err)
+	}
+	for _, s := range syms.Entries {
+		if s.Name != "User.ID" {
+			continue
+		}
+		if len(s.Tags) != 1 || s.Tags["custom"] != "x" {
+			t.Errorf("User.ID tags = %v, want only custom=x", s.Tags)
+		}
+	}
+}
+
+func TestDiffExports_TagChanged(t *testing.T) {
+	old := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolType, Name: "User", Package: "mod", Signature: "struct{ID string}"},
+		{Kind: symbols.SymbolField, Name: "User.ID", Package: "mod", Signature: "string",
+			Tags: map[string]string{"json": "user_id", "yaml": "id"}},
+	})
+	new := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolType, Name: "User", Package: "mod", Signature: "struct{ID string}"},
+		{Kind: symbols.SymbolField, Name: "User.ID", Package: "mod", Signature: "string",
+			Tags: map[string]string{"json": "userId", "yaml": "id,omitempty"}},
+	})
+	changes := DiffExports(old, new, nil, nil)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d: %+v", len(changes), changes)
+	}
+
+	byDetail := make(map[string]changespec.Change)
+	for _, c := range changes {
+		if c.Kind != changespec.ChangeKindTagChanged {
+			t.Errorf("kind = %q, want tag_changed", c.Kind)
+		}
+		if c.Severity != changespec.SeverityBehavioral {
+			t.Errorf("severity = %q, want behavioral", c.Severity)
+		}
+		byDetail[c.Detail] = c
+	}
+
+	c, ok := byDetail[`json: name "user_id" -> "userId"`]
+	if !ok {
+		t.Fatalf("missing json rename change: %+v", changes)
+	}
+	if c.OldSignature != `json:"user_id"` || c.NewSignature != `json:"userId"` {
+		t.Errorf("signatures = %q -> %q", c.OldSignature, c.NewSignature)
+	}
+	if _, ok := byDetail["yaml: omitempty added"]; !ok {
+		t.Errorf("missing yaml omitempty change: %+v", changes)
+	}
+}
+
+func TestDiffExports_TagChangedAlongsideTypeChange(t *testing.T) {
+	// A field whose type and tag both change yields one compile break and one behavioral change.
+	old := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolField, Name: "User.ID", Package: "mod", Signature: "string",
+			Tags: map[string]string{"json": "-"}},
+	})
+	new := buildSymbols("mod", []symbols.Symbol{
+		{Kind: symbols.SymbolField, Name: "User.ID", Package: "mod", Signature: "int64",
+			Tags: map[string]string{"json": "id"}},
+	})
+	changes := DiffExports(old, new, nil, nil)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d: %+v", len(changes), changes)
+	}
+
+	var compile, behavioral int
+	for _, c := range changes {
+		switch c.Severity {
+		case "":
+			compile++
+		case changespec.SeverityBehavioral:
+			behavioral++
+		}
+	}
+	if compile != 1 || behavioral != 1 {
+		t.Errorf("compile = %d, behavioral = %d, want 1 each", compile, behavioral)
+	}
+}
@@ -1,4 +1,4 @@
 package testmod
 
-// This file has a syntax error and should be skipped with a warning.
+// This file has a syntax error and should be skipped with a diagnostic.
 func BrokenFunc( {
@@ -2,38 +2,146 @@ package golang
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	goversion "go/version"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/mod/modfile"
 
 	"github.com/emenda-labs/emenda/core/changespec"
 	"github.com/emenda-labs/emenda/core/driver"
 	"github.com/emenda-labs/emenda/drivers/golang/astdiff"
+	"github.com/emenda-labs/emenda/drivers/golang/githistory"
+	"github.com/emenda-labs/emenda/drivers/golang/snapshot"
 	"github.com/emenda-labs/emenda/pkg/archive"
 	"github.com/emenda-labs/emenda/pkg/gomod"
 	"github.com/emenda-labs/emenda/pkg/goproxy"
+	"github.com/emenda-labs/emenda/pkg/modcache"
 )
 
 var _ driver.LanguageDriver = (*Driver)(nil)
 
+// Options configures optional Driver behavior. The zero value selects the defaults.
+type Options struct {
+	// TagKeys lists the struct tag keys compared for behavioral changes.
+	// Nil means astdiff.DefaultTagKeys; a non-nil value replaces them, so
+	// append to the defaults to extend them.
+	TagKeys []string
+
+	// Strict fails ComputeChanges when either version has diagnostics (parse
+	// failures, skipped directories, unsupported constructs) instead of reporting
+	// them alongside low-trust changes.
+	Strict bool
+
+	// Synonyms lists groups of interchangeable words used when matching renamed
+	// functions (e.g. {"Get", "Fetch"}). Nil means astdiff.DefaultSynonyms; a
+	// non-nil value replaces them, so append to the defaults to extend them.
+	Synonyms [][]string
+
+	// Cache stores parsed exports per module version for ComputeVersionChanges.
+	// A hit skips both the download and the parse. Nil disables caching.
+	Cache *snapshot.Cache
+
+	// UpstreamRepo is the path to a local clone of the module's upstream
+	// repository. When set, the commits between the two versions are mined for
+	// rename and move evidence (see githistory.Mine). Empty disables it.
+	UpstreamRepo string
+
+	// Heuristics summarizes function bodies of both versions and reports
+	// behavioral risks the API diff cannot see (new init functions, changed
+	// constructor defaults, new environment reads, goroutines and panics) as
+	// advisories. They never block an upgrade.
+	Heuristics bool
+
+	// GoSum holds trusted module checksums, typically read from the consumer
+	// module's go.sum. Downloaded zips of listed versions must match them;
+	// other versions are checked against the checksum database.
+	GoSum goproxy.GoSum
+
+	// ModCache is the go command's module cache. Versions found there are read
+	// in place or from the cached zip instead of being downloaded, after
+	// checking them against GoSum or, for versions it does not list, the zip
+	// hash the go command recorded. The cache is never written. Nil disables it.
+	ModCache *modcache.Cache
+
+	// Offline disables downloads, as GOPROXY=off does: every version must come
+	// from ModCache or the snapshot cache.
+	Offline bool
+
+	// Progress receives zip download progress. Nil disables reporting.
+	Progress goproxy.ProgressFunc
+
+	// GoVersion is the go directive of the consuming module, whose dependency
+	// is upgraded. A new version whose go.mod requires a newer Go gets a
+	// DiagnosticGoVersion diagnostic, since the upgrade raises the consumer's
+	// go line. Empty skips the check.
+	GoVersion string
+}
+
 // Driver implements driver.LanguageDriver for Go modules.
 type Driver struct {
 	proxyClient *goproxy.Client
+	opts        Options
 }
 
 // NewDriver creates a Driver with a default goproxy.Client.
 func NewDriver() *Driver {
+	return NewDriverWithOptions(Options{})
+}
+
+// NewDriverWithOptions creates a Driver with a default goproxy.Client and the given options.
+func NewDriverWithOptions(opts Options) *Driver {
 	return &Driver{
-		proxyClient: goproxy.NewClient(),
+		proxyClient: goproxy.NewClientWithOptions(clientOptions(opts)),
+		opts:        opts,
 	}
 }
 
-// FetchSource downloads the module zip from the proxy and extracts it to a temp directory.
+// clientOptions returns the proxy client configuration for opts.
+func clientOptions(opts Options) goproxy.ClientOptions {
+	clientOpts := goproxy.ClientOptions{GoSum: opts.GoSum, Progress: opts.Progress}
+	if opts.Offline {
+		clientOpts.Proxy = "off"
+	}
+	return clientOpts
+}
+
+// FetchSource returns the source of module@version: the module cache entry
+// when it can be verified (see openCached), otherwise the downloaded zip
+// extracted to a temp directory. The cleanup function never removes module
+// cache trees.
 func (d *Driver) FetchSource(ctx context.Context, module, version string) (string, func(), error) {
-	data, err := d.proxyClient.DownloadZip(ctx, module, version)
+	if d.opts.ModCache != nil {
+		entry, ok, err := d.opts.ModCache.Lookup(module, version)
+		if err != nil {
+			return "", nil, fmt.Errorf("looking up %s@%s in module cache: %w", module, version, err)
+		}
+		if ok {
+			dir, cleanup, ok, err := d.openCached(module, version, entry)
+			if err != nil || ok {
+				return dir, cleanup, err
+			}
+		}
+	}
+	if d.opts.Offline {
+		return "", nil, fmt.Errorf("%s@%s has no verified copy in the module cache and downloads are disabled in offline mode", module, version)
+	}
+
+	f, removeZip, err := d.proxyClient.DownloadZipFile(ctx, module, version)
 	if err != nil {
 		return "", nil, fmt.Errorf("downloading zip for %s@%s: %w", module, version, err)
 	}
+	defer removeZip()
 
-	dir, cleanup, err := archive.ExtractZip(data, version)
+	info, err := f.Stat()
+	if err != nil {
+		return "", nil, fmt.Errorf("reading zip for %s@%s: %w", module, version, err)
+	}
+	dir, cleanup, err := extractModuleZip(f, info.Size(), module, version)
 	if err != nil {
 		return "", nil, fmt.Errorf("extracting zip for %s@%s: %w", module, version, err)
 	}
@@ -41,8 +149,92 @@ func (d *Driver) FetchSource(ctx context.Context, module, version string) (strin
 	return dir, cleanup, nil
 }
 
+// extractModuleZip extracts the zip of module@version to a temp directory and
+// returns the module root inside it. Module zips hold every file under
+// "<module>@<version>/", which for nested modules (github.com/acme/foo/otel)
+// lies deeper than FindSourceRoot searches; zips without that prefix return the
+// extraction directory itself.
+func extractModuleZip(r io.ReaderAt, size int64, module, version string) (string, func(), error) {
+	dir, cleanup, err := archive.ExtractZip(r, size, version)
+	if err != nil {
+		return "", nil, err
+	}
+	root := filepath.Join(dir, filepath.FromSlash(module+"@"+version))
+	if info, err := os.Stat(root); err == nil && info.IsDir() {
+		return root, cleanup, nil
+	}
+	return dir, cleanup, nil
+}
+
+// ResolveVersion resolves a version query for module: "latest", a major or
+// minor prefix such as "v1" or "v1.4", an exact version, or a branch or commit
+// (resolved to a pseudo-version). Retracted versions are rejected. In offline
+// mode only exact versions are accepted and retractions are not checked.
+func (d *Driver) ResolveVersion(ctx context.Context, module, query string) (string, error) {
+	if d.opts.Offline {
+		if goproxy.IsExactVersion(query) {
+			return query, nil
+		}
+		return "", fmt.Errorf("resolving %s@%s: version queries need network access; pass an exact version in offline mode", module, query)
+	}
+	info, err := d.proxyClient.Query(ctx, module, query)
+	if err != nil {
+		return "", fmt.Errorf("resolving %s@%s: %w", module, query, err)
+	}
+	return info.Version, nil
+}
+
+// openCached opens a module cache entry, checking the source it returns. The
+// cached zip is used when there is one: it is cheaper to hash than the
+// extracted tree, and cannot have been edited in place. Versions GoSum lists
+// must match it. Other versions must match the zip hash the go command recorded
+// when it verified the download; an entry without one, or whose source no
+// longer matches it (a -modcacherw tree edited since extraction), is not used
+// and ok is false, so the version is downloaded and checked like any other.
+func (d *Driver) openCached(module, version string, entry modcache.Entry) (dir string, cleanup func(), ok bool, err error) {
+	listed := d.opts.GoSum.Lists(module, version)
+	if !listed && entry.ZipHash == "" {
+		return "", nil, false, nil
+	}
+	if entry.Zip != "" {
+		entry.Dir = ""
+	}
+	hash, err := entry.Hash(module, version)
+	if err != nil {
+		return "", nil, false, fmt.Errorf("hashing cached %s@%s: %w", module, version, err)
+	}
+	if listed {
+		if err := d.opts.GoSum.Check(module, version, hash); err != nil {
+			return "", nil, false, err
+		}
+	} else if hash != entry.ZipHash {
+		return "", nil, false, nil
+	}
+
+	if entry.Dir != "" {
+		return entry.Dir, func() {}, true, nil
+	}
+
+	f, err := os.Open(entry.Zip)
+	if err != nil {
+		return "", nil, false, fmt.Errorf("reading cached zip for %s@%s: %w", module, version, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", nil, false, fmt.Errorf("reading cached zip for %s@%s: %w", module, version, err)
+	}
+	dir, cleanup, err = extractModuleZip(f, info.Size(), module, version)
+	if err != nil {
+		return "", nil, false, fmt.Errorf("extracting cached zip for %s@%s: %w", module, version, err)
+	}
+	return dir, cleanup, true, nil
+}
+
 // ComputeChanges diffs two unpacked Go module versions.
-// Internally parses exports from both versions and computes the diff.
+// Internally parses exports from both versions and computes the diff. Both
+// versions must declare the same module path. The trees are arbitrary local
+// paths, so the snapshot cache is neither read nor written.
 func (d *Driver) ComputeChanges(ctx context.Context, oldPath, newPath, oldVersion, newVersion string) (changespec.ChangeSpec, error) {
 	oldRoot, err := astdiff.FindSourceRoot(oldPath)
 	if err != nil {
@@ -67,25 +259,296 @@ func (d *Driver) ComputeChanges(ctx context.Context, oldPath, newPath, oldVersio
 	if module != newModule {
 		return changespec.ChangeSpec{}, fmt.Errorf("module mismatch: old=%s new=%s", module, newModule)
 	}
+	newGo, err := gomod.FindGoVersion(newRoot)
+	if err != nil {
+		return changespec.ChangeSpec{}, fmt.Errorf("reading go version from %s: %w", newVersion, err)
+	}
+	diags := d.checkGoVersion(module, newVersion, newGo)
 
-	old, oldSigs, err := astdiff.ParseExports(ctx, oldRoot, module)
+	old, new, err := loadPair(ctx, func(ctx context.Context, isOld bool) (*snapshot.Snapshot, error) {
+		if isOld {
+			return d.parseSnapshot(ctx, oldRoot, module, oldVersion)
+		}
+		return d.parseSnapshot(ctx, newRoot, module, newVersion)
+	})
 	if err != nil {
-		return changespec.ChangeSpec{}, fmt.Errorf("parsing exports from %s: %w", oldVersion, err)
+		return changespec.ChangeSpec{}, err
 	}
+	return d.diffSnapshots(ctx, old, new, diags)
+}
 
-	new, newSigs, err := astdiff.ParseExports(ctx, newRoot, module)
+// ComputeVersionChanges diffs two versions of module, fetching and parsing only
+// the versions missing from the snapshot cache. Without a cache it is equivalent
+// to FetchSource for both versions followed by ComputeChanges. The checks of
+// ComputeChanges run first on the versions' go.mod files alone, so a mismatched
+// module path fails before any zip is downloaded.
+func (d *Driver) ComputeVersionChanges(ctx context.Context, module, oldVersion, newVersion string) (changespec.ChangeSpec, error) {
+	diags, err := d.checkGoMods(ctx, module, oldVersion, newVersion)
 	if err != nil {
-		return changespec.ChangeSpec{}, fmt.Errorf("parsing exports from %s: %w", newVersion, err)
+		return changespec.ChangeSpec{}, err
 	}
+	old, new, err := loadPair(ctx, func(ctx context.Context, isOld bool) (*snapshot.Snapshot, error) {
+		if isOld {
+			return d.fetchExports(ctx, module, oldVersion)
+		}
+		return d.fetchExports(ctx, module, newVersion)
+	})
+	if err != nil {
+		return changespec.ChangeSpec{}, err
+	}
+	return d.diffSnapshots(ctx, old, new, diags)
+}
 
-	changes := astdiff.DiffExports(old, new, oldSigs, newSigs)
+// checkGoMods validates the go.mod files of both versions: each must declare
+// module. It returns the go version diagnostic of the new one. go.mod files
+// come from the module cache or the proxy. Versions in the snapshot cache,
+// which need no download, are only checked when the module cache holds their
+// go.mod, and so are all versions in offline mode.
+func (d *Driver) checkGoMods(ctx context.Context, module, oldVersion, newVersion string) ([]changespec.Diagnostic, error) {
+	var diags []changespec.Diagnostic
+	for _, version := range []string{oldVersion, newVersion} {
+		_, cached := d.cachedExports(module, version)
+		data, ok, err := d.goMod(ctx, module, version, !cached && !d.opts.Offline)
+		if err != nil {
+			return nil, fmt.Errorf("fetching go.mod of %s@%s: %w", module, version, err)
+		}
+		if !ok {
+			continue
+		}
+		f, err := modfile.ParseLax("go.mod", data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("parsing go.mod of %s@%s: %w", module, version, err)
+		}
+		if f.Module == nil {
+			return nil, fmt.Errorf("go.mod of %s@%s has no module directive", module, version)
+		}
+		if got := f.Module.Mod.Path; got != module {
+			return nil, fmt.Errorf("module mismatch in %s: want %s, go.mod declares %s", version, module, got)
+		}
+		if version == newVersion && f.Go != nil {
+			diags = d.checkGoVersion(module, version, f.Go.Version)
+		}
+	}
+	return diags, nil
+}
+
+// goMod returns the go.mod of module@version without fetching its source: from
+// the module cache when it holds it, otherwise from the proxy if download is
+// set. ok is false when neither applies. Both are checked against the "/go.mod"
+// line of GoSum when it lists one; the proxy client also consults the checksum
+// database.
+func (d *Driver) goMod(ctx context.Context, module, version string, download bool) (data []byte, ok bool, err error) {
+	if d.opts.ModCache != nil {
+		data, ok, err := d.opts.ModCache.GoMod(module, version)
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			if err := d.opts.GoSum.CheckGoMod(module, version, data); err != nil {
+				return nil, false, err
+			}
+			return data, true, nil
+		}
+	}
+	if !download {
+		return nil, false, nil
+	}
+	data, err = d.proxyClient.GoMod(ctx, module, version)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// checkGoVersion returns a DiagnosticGoVersion diagnostic when the go
+// directive of module@version, goVersion, is newer than Options.GoVersion.
+func (d *Driver) checkGoVersion(module, version, goVersion string) []changespec.Diagnostic {
+	if d.opts.GoVersion == "" || goVersion == "" {
+		return nil
+	}
+	if goversion.Compare("go"+goVersion, "go"+d.opts.GoVersion) <= 0 {
+		return nil
+	}
+	return []changespec.Diagnostic{{
+		Kind:    changespec.DiagnosticGoVersion,
+		Version: version,
+		Package: module,
+		Message: fmt.Sprintf("requires go >= %s, but the consuming module declares go %s; upgrading raises its go line", goVersion, d.opts.GoVersion),
+	}}
+}
+
+// fetchExports returns the exports of module@version from the snapshot cache,
+// downloading and parsing the source on a miss. Only source from FetchSource,
+// verified downloads and module cache entries, is parsed into the cache; a
+// failed write only costs a re-parse next time, so it is not reported.
+func (d *Driver) fetchExports(ctx context.Context, module, version string) (*snapshot.Snapshot, error) {
+	if snap, ok := d.cachedExports(module, version); ok {
+		return snap, nil
+	}
+
+	dir, cleanup, err := d.FetchSource(ctx, module, version)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	root, err := astdiff.FindSourceRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("finding module root in %s: %w", version, err)
+	}
+	got, err := gomod.FindModulePath(root)
+	if err != nil {
+		return nil, fmt.Errorf("reading module path from %s: %w", version, err)
+	}
+	if got != module {
+		return nil, fmt.Errorf("module mismatch in %s: want %s, go.mod declares %s", version, module, got)
+	}
+	snap, err := d.parseSnapshot(ctx, root, module, version)
+	if err != nil {
+		return nil, err
+	}
+	if d.opts.Cache != nil {
+		_ = d.opts.Cache.Put(d.snapshotKey(module, version), snap)
+	}
+	return snap, nil
+}
+
+// snapshotKey returns the cache key for module@version under the driver's parse settings.
+func (d *Driver) snapshotKey(module, version string) snapshot.Key {
+	return snapshot.Key{
+		Module:   module,
+		Version:  version,
+		TagKeys:  d.opts.TagKeys,
+		Behavior: d.opts.Heuristics,
+	}
+}
+
+// cachedExports looks up module@version in the snapshot cache, if one is configured.
+func (d *Driver) cachedExports(module, version string) (*snapshot.Snapshot, bool) {
+	if d.opts.Cache == nil {
+		return nil, false
+	}
+	return d.opts.Cache.Get(d.snapshotKey(module, version))
+}
+
+// parseSnapshot parses the exports of the module at root into a snapshot.
+func (d *Driver) parseSnapshot(ctx context.Context, root, module, version string) (*snapshot.Snapshot, error) {
+	snap := &snapshot.Snapshot{
+		ParserVersion: astdiff.ParserVersion,
+		Module:        module,
+		Version:       version,
+	}
+	exports, err := d.parseModule(ctx, root, module, version)
+	if err != nil {
+		return nil, err
+	}
+	snap.Root = exports
+	return snap, nil
+}
+
+// parseModule parses the exports of one module rooted at dir.
+func (d *Driver) parseModule(ctx context.Context, dir, module, version string) (snapshot.Exports, error) {
+	parseOpts := astdiff.ParseOptions{TagKeys: d.opts.TagKeys, Behavior: d.opts.Heuristics}
+	syms, sigs, err := astdiff.ParseExportsWithOptions(ctx, dir, module, parseOpts)
+	if err != nil {
+		return snapshot.Exports{}, fmt.Errorf("parsing exports of %s from %s: %w", module, version, err)
+	}
+	return snapshot.Exports{Path: module, Symbols: syms, Sigs: sigs}, nil
+}
+
+// loadPair runs load for the old and new version concurrently. A failure on one
+// side cancels the other, and the root cause is reported rather than the
+// cancellation it triggered.
+func loadPair(ctx context.Context, load func(ctx context.Context, isOld bool) (*snapshot.Snapshot, error)) (old, new *snapshot.Snapshot, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	return changespec.ChangeSpec{
-		Module:     module,
-		OldVersion: oldVersion,
-		NewVersion: newVersion,
-		Changes:    changes,
-	}, nil
+	var (
+		wg             sync.WaitGroup
+		oldErr, newErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		old, oldErr = load(ctx, true)
+		if oldErr != nil {
+			cancel()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		new, newErr = load(ctx, false)
+		if newErr != nil {
+			cancel()
+		}
+	}()
+	wg.Wait()
+
+	switch {
+	case oldErr != nil && (newErr == nil || !errors.Is(oldErr, context.Canceled)):
+		return nil, nil, oldErr
+	case newErr != nil:
+		return nil, nil, newErr
+	}
+	return old, new, nil
+}
+
+// diffSnapshots builds the change spec between two parsed versions. With an
+// upstream repository configured, its history between the versions is used as
+// rename evidence; when it cannot be read, the diff proceeds without it and a
+// diagnostic says so. diags are appended to the spec's diagnostics; they
+// concern the upgrade rather than the source, so strict mode does not fail on
+// them.
+func (d *Driver) diffSnapshots(ctx context.Context, old, new *snapshot.Snapshot, diags []changespec.Diagnostic) (changespec.ChangeSpec, error) {
+	spec := changespec.ChangeSpec{
+		Module:     old.Module,
+		OldVersion: old.Version,
+		NewVersion: new.Version,
+	}
+
+	diffOpts := astdiff.DiffOptions{Synonyms: d.opts.Synonyms}
+	if d.opts.UpstreamRepo != "" {
+		history, err := githistory.Mine(ctx, d.opts.UpstreamRepo, old.Module, old.Version, new.Version)
+		switch {
+		case ctx.Err() != nil:
+			return changespec.ChangeSpec{}, ctx.Err()
+		case err != nil:
+			diags = append(diags, changespec.Diagnostic{
+				Kind:    changespec.DiagnosticHistory,
+				Package: old.Module,
+				Message: fmt.Sprintf("upstream history not used: %v", err),
+			})
+		default:
+			diffOpts.History = history
+		}
+	}
+	diffExports(&spec, old.Root, new.Root, diffOpts)
+
+	if d.opts.Strict && len(spec.Diagnostics) > 0 {
+		return changespec.ChangeSpec{}, fmt.Errorf("strict mode: %d source diagnostics, first: %s", len(spec.Diagnostics), spec.Diagnostics[0])
+	}
+	spec.Diagnostics = append(spec.Diagnostics, diags...)
+	return spec, nil
+}
+
+// diffExports diffs one module's exports and appends the changes, advisories and
+// diagnostics to spec. Advisories are only produced when both versions were
+// parsed with body summaries.
+func diffExports(spec *changespec.ChangeSpec, old, new snapshot.Exports, opts astdiff.DiffOptions) {
+	spec.Changes = append(spec.Changes, astdiff.DiffExportsWithOptions(old.Symbols, new.Symbols, old.Sigs, new.Sigs, opts)...)
+	spec.Advisories = append(spec.Advisories, astdiff.CompareBehavior(old.Symbols, new.Symbols)...)
+	spec.Diagnostics = append(spec.Diagnostics, withVersion(old.Symbols.Diagnostics, spec.OldVersion)...)
+	spec.Diagnostics = append(spec.Diagnostics, withVersion(new.Symbols.Diagnostics, spec.NewVersion)...)
+}
+
+// withVersion stamps each diagnostic with the module version it came from.
+func withVersion(diags []changespec.Diagnostic, version string) []changespec.Diagnostic {
+	out := make([]changespec.Diagnostic, len(diags))
+	for i, d := range diags {
+		d.Version = version
+		out[i] = d
+	}
+	return out
 }
 
 // ApplyChanges applies breaking change fixes to Go source files.
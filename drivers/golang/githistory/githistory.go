@@ -0,0 +1,171 @@
+// Package githistory mines a local clone of a module's upstream repository for
+// evidence that symbols were renamed or moved between two versions: commit
+// messages ("rename Dial to Connect"), file renames detected by git, and diffs
+// that rewrite a declaration's name while leaving the rest of it intact.
+package githistory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/module"
+
+	"github.com/emenda-labs/emenda/drivers/golang/astdiff"
+	"github.com/emenda-labs/emenda/pkg/gomod"
+)
+
+// Mine returns the rename and move evidence recorded in the git repository at
+// repoDir between oldVersion and newVersion of modulePath. Versions resolve to
+// tags (with the module's subdirectory as prefix for modules not at the
+// repository root) or, for pseudo-versions, to the embedded commit.
+func Mine(ctx context.Context, repoDir, modulePath, oldVersion, newVersion string) (*astdiff.History, error) {
+	top, err := git(ctx, repoDir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a git repository: %w", repoDir, err)
+	}
+	root := strings.TrimSpace(string(top))
+
+	modDir, err := findModuleDir(root, modulePath)
+	if err != nil {
+		return nil, err
+	}
+
+	oldRev, err := resolveVersion(ctx, root, modDir, oldVersion)
+	if err != nil {
+		return nil, err
+	}
+	newRev, err := resolveVersion(ctx, root, modDir, newVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	pathspec := "."
+	if modDir != "" {
+		pathspec = modDir
+	}
+	pkgs := packageMapper{module: modulePath, dir: modDir}
+
+	log, err := git(ctx, root, "log", "-M", "--name-status", "--format=%x1e%h%x00%B%x00", oldRev+".."+newRev, "--", pathspec)
+	if err != nil {
+		return nil, fmt.Errorf("reading history %s..%s: %w", oldVersion, newVersion, err)
+	}
+	history := parseLog(string(log), pkgs)
+
+	diff, err := git(ctx, root, "diff", "-M", "-U0", "--no-color", oldRev, newRev, "--", pathspec)
+	if err != nil {
+		return nil, fmt.Errorf("diffing %s..%s: %w", oldVersion, newVersion, err)
+	}
+	history.Renames = append(history.Renames, parseDiff(string(diff), pkgs)...)
+	return history, nil
+}
+
+// git runs a git command in dir and returns its standard output.
+func git(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// findModuleDir returns the slash-separated directory of modulePath's go.mod
+// relative to the repository root, "" for the root itself.
+func findModuleDir(root, modulePath string) (string, error) {
+	found := ""
+	ok := false
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && (d.Name() == ".git" || d.Name() == "vendor" || d.Name() == "testdata") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "go.mod" {
+			return nil
+		}
+		dir := filepath.Dir(p)
+		if got, err := gomod.FindModulePath(dir); err == nil && got == modulePath {
+			rel, err := filepath.Rel(root, dir)
+			if err != nil {
+				return err
+			}
+			found, ok = filepath.ToSlash(rel), true
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("searching %s for module %s: %w", root, modulePath, err)
+	}
+	if !ok {
+		return "", fmt.Errorf("module %s not found in %s", modulePath, root)
+	}
+	if found == "." {
+		found = ""
+	}
+	return found, nil
+}
+
+// resolveVersion returns the commit of version: the commit embedded in a
+// pseudo-version, or the tag named after the version.
+func resolveVersion(ctx context.Context, root, modDir, version string) (string, error) {
+	ref := strings.TrimSuffix(version, "+incompatible")
+	if module.IsPseudoVersion(version) {
+		rev, err := module.PseudoVersionRev(version)
+		if err != nil {
+			return "", fmt.Errorf("parsing pseudo-version %s: %w", version, err)
+		}
+		ref = rev
+	} else if modDir != "" {
+		ref = path.Join(modDir, ref)
+	}
+
+	out, err := git(ctx, root, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("resolving %s in upstream repository: no tag or commit %s", version, ref)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// packageMapper converts repository file paths to import paths.
+type packageMapper struct {
+	module string
+	dir    string // module directory relative to the repository root, "" for the root
+}
+
+// packageOf returns the import path of the package containing the Go source
+// file at repository path p. It reports false for paths outside the module and
+// for test files.
+func (m packageMapper) packageOf(p string) (string, bool) {
+	if !strings.HasSuffix(p, ".go") || strings.HasSuffix(p, "_test.go") {
+		return "", false
+	}
+	rel := p
+	if m.dir != "" {
+		var ok bool
+		rel, ok = strings.CutPrefix(p, m.dir+"/")
+		if !ok {
+			return "", false
+		}
+	}
+	dir := path.Dir(rel)
+	if dir == "." {
+		return m.module, true
+	}
+	return m.module + "/" + dir, true
+}
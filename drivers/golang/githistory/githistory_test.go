@@ -0,0 +1,243 @@
+package githistory
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/emenda-labs/emenda/drivers/golang/astdiff"
+)
+
+func TestMessageRenames(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want [][2]string
+	}{
+		{"rename_to", "client: rename Dial to Connect", [][2]string{{"Dial", "Connect"}}},
+		{"backquoted_member", "Rename `Client.Do()` to `Client.Send()`", [][2]string{{"Client.Do", "Client.Send"}}},
+		{"renamed_to", "Options was renamed to Config.", [][2]string{{"Options", "Config"}}},
+		{"arrow", "api: rename NewClient -> New, Get => Fetch", [][2]string{{"NewClient", "New"}, {"Get", "Fetch"}}},
+		{"arrow_verb_after", "api: Client.Do → Client.Send (renamed)", [][2]string{{"Client.Do", "Client.Send"}}},
+		{"arrow_without_verb", "api: NewClient -> New\n\nOld -> New flow is documented.", nil},
+		{"package_qualifier", "rename http.Get to http.Fetch", [][2]string{{"Get", "Fetch"}}},
+		{"unexported", "rename dial to connect", nil},
+		{"prose", "fix a -> b mapping in docs", nil},
+		{"multiple", "Rename Foo to Bar\n\nAlso rename Baz to Qux.", [][2]string{{"Foo", "Bar"}, {"Baz", "Qux"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := messageRenames(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("messageRenames = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDecl(t *testing.T) {
+	tests := []struct {
+		line     string
+		wantName string
+		wantOK   bool
+	}{
+		{"func Dial(addr string) error {", "Dial", true},
+		{"func (c *Client) Do(req *Request) error {", "Client.Do", true},
+		{"func (l *List[T]) Push(v T) {", "List.Push", true},
+		{"func (Client) Close() error {", "Client.Close", true},
+		{"type Options struct {", "Options", true},
+		{"const DefaultPort = 80", "DefaultPort", true},
+		{"\treturn nil", "", false},
+	}
+	for _, tt := range tests {
+		d, ok := parseDecl(tt.line)
+		if ok != tt.wantOK || d.name != tt.wantName {
+			t.Errorf("parseDecl(%q) = %q, %v; want %q, %v", tt.line, d.name, ok, tt.wantName, tt.wantOK)
+		}
+	}
+}
+
+func TestParseDiff(t *testing.T) {
+	diff := `diff --git a/client/client.go b/client/client.go
+index 1111111..2222222 100644
+--- a/client/client.go
++++ b/client/client.go
+@@ -3 +3 @@
+-func Dial(addr string) error {
++func Connect(addr string) error {
+@@ -7,2 +7,2 @@
+-func (c *Client) Do(r *Request) error {
+-func Helper() {}
++func (c *Client) Send(r *Request) error {
++func Helper(n int) {}
+@@ -12 +12 @@
+-func Changed(a int) {}
++func Other(a string) {}
+diff --git a/client/client_test.go b/client/client_test.go
+--- a/client/client_test.go
++++ b/client/client_test.go
+@@ -1 +1 @@
+-func TestA(t *testing.T) {}
++func TestB(t *testing.T) {}
+diff --git a/old.go b/old.go
+deleted file mode 100644
+--- a/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-func Gone() {}
+`
+	got := parseDiff(diff, packageMapper{module: "example.com/m"})
+	want := []astdiff.RenameHint{
+		{OldName: "Client.Do", NewName: "Client.Send", OldPackage: "example.com/m/client", NewPackage: "example.com/m/client", Source: "declaration rewritten in client/client.go"},
+		{OldName: "Dial", NewName: "Connect", OldPackage: "example.com/m/client", NewPackage: "example.com/m/client", Source: "declaration rewritten in client/client.go"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDiff =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestResolveMoves(t *testing.T) {
+	moves := map[string]map[string]bool{
+		"m/a":     {"m/b": true},
+		"m/b":     {"m/c": true},
+		"m/split": {"m/x": true, "m/y": true},
+		"m/p":     {"m/q": true},
+		"m/q":     {"m/p": true},
+	}
+	want := map[string]string{"m/a": "m/c", "m/b": "m/c", "m/p": "m/q", "m/q": "m/p"}
+	if got := resolveMoves(moves); !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveMoves = %v, want %v", got, want)
+	}
+}
+
+func TestPackageOf(t *testing.T) {
+	m := packageMapper{module: "example.com/sdk", dir: "sdk"}
+	tests := []struct {
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{"sdk/client.go", "example.com/sdk", true},
+		{"sdk/net/client/client.go", "example.com/sdk/net/client", true},
+		{"sdk/client_test.go", "", false},
+		{"other/client.go", "", false},
+		{"sdk/README.md", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := m.packageOf(tt.path)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("packageOf(%q) = %q, %v; want %q, %v", tt.path, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+// gitRepo creates a repository for tests, skipping when git is unavailable.
+func gitRepo(t *testing.T) (dir string, run func(args ...string)) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir = t.TempDir()
+	run = func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+			"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("init", "-q")
+	return dir, run
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// clientSource renders a small client package; enough lines stay identical
+// across versions for git to detect the file as renamed.
+func clientSource(optionsName, dialName string) string {
+	return "package client\n\n" +
+		"import \"net\"\n\n" +
+		"type " + optionsName + " struct{ Addr string }\n\n" +
+		"// Client is a connection to a server.\n" +
+		"type Client struct {\n\tconn net.Conn\n}\n\n" +
+		"// Close closes the connection.\n" +
+		"func (c *Client) Close() error {\n\treturn c.conn.Close()\n}\n\n" +
+		"func " + dialName + "(addr string) error { return nil }\n"
+}
+
+func TestMine(t *testing.T) {
+	dir, run := gitRepo(t)
+	writeFile(t, filepath.Join(dir, "README.md"), "repo\n")
+	writeFile(t, filepath.Join(dir, "sdk/go.mod"), "module example.com/sdk\n")
+	writeFile(t, filepath.Join(dir, "sdk/client/client.go"), clientSource("Options", "Dial"))
+	run("add", "-A")
+	run("commit", "-q", "-m", "initial")
+	run("tag", "sdk/v1.0.0")
+
+	run("mv", "sdk/client", "sdk/transport")
+	run("commit", "-q", "-m", "move client package under transport")
+	writeFile(t, filepath.Join(dir, "sdk/transport/client.go"), clientSource("Config", "Connect"))
+	run("commit", "-q", "-am", "Rename Options to Config")
+	run("tag", "sdk/v1.1.0")
+
+	history, err := Mine(context.Background(), dir, "example.com/sdk", "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatalf("Mine: %v", err)
+	}
+
+	if want := map[string]string{"example.com/sdk/client": "example.com/sdk/transport"}; !reflect.DeepEqual(history.PackageMoves, want) {
+		t.Errorf("PackageMoves = %v, want %v", history.PackageMoves, want)
+	}
+
+	find := func(oldName, newName, sourcePrefix string) (astdiff.RenameHint, bool) {
+		for _, h := range history.Renames {
+			if h.OldName == oldName && h.NewName == newName && strings.HasPrefix(h.Source, sourcePrefix) {
+				return h, true
+			}
+		}
+		return astdiff.RenameHint{}, false
+	}
+	if _, ok := find("Options", "Config", "commit "); !ok {
+		t.Errorf("missing commit message hint Options -> Config: %+v", history.Renames)
+	}
+	for _, names := range [][2]string{{"Options", "Config"}, {"Dial", "Connect"}} {
+		h, ok := find(names[0], names[1], "declaration rewritten")
+		if !ok {
+			t.Errorf("missing declaration hint %s -> %s: %+v", names[0], names[1], history.Renames)
+			continue
+		}
+		if h.OldPackage != "example.com/sdk/client" || h.NewPackage != "example.com/sdk/transport" {
+			t.Errorf("%s -> %s packages = %s -> %s", names[0], names[1], h.OldPackage, h.NewPackage)
+		}
+	}
+}
+
+func TestMine_UnknownVersion(t *testing.T) {
+	dir, run := gitRepo(t)
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/m\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "initial")
+	run("tag", "v1.0.0")
+
+	if _, err := Mine(context.Background(), dir, "example.com/m", "v1.0.0", "v1.2.0"); err == nil {
+		t.Error("expected error for a version with no tag")
+	}
+	if _, err := Mine(context.Background(), dir, "example.com/other", "v1.0.0", "v1.0.0"); err == nil {
+		t.Error("expected error for a module not in the repository")
+	}
+}
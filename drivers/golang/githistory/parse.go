@@ -0,0 +1,281 @@
+package githistory
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/emenda-labs/emenda/drivers/golang/astdiff"
+)
+
+// ident matches an optionally qualified, optionally backquoted Go identifier:
+// Dial, Client.Do, `http.Get`.
+const ident = "`?((?:[A-Za-z_][A-Za-z0-9_]*\\.)?[A-Za-z_][A-Za-z0-9_]*)(?:\\(\\))?`?"
+
+// messagePatterns recognize renames announced in commit messages.
+var messagePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\brenam(?:e|es|ed|ing)\s+` + ident + `\s+(?:to|into|as|->|=>|→)\s+` + ident),
+	regexp.MustCompile(ident + `\s+(?:(?:is|was|has\s+been)\s+)?renamed\s+to\s+` + ident),
+}
+
+// arrowPattern recognizes "Old -> New" pairs. Arrows are common in prose, so
+// they only count on lines that also carry a rename verb (see renameVerb).
+var arrowPattern = regexp.MustCompile(ident + `\s*(?:->|=>|→)\s*` + ident)
+
+// renameVerb matches a line that announces a rename.
+var renameVerb = regexp.MustCompile(`(?i)\brenam(?:e|es|ed|ing)\b`)
+
+// maxMoveChain bounds how many successive directory moves are followed.
+const maxMoveChain = 8
+
+// parseLog extracts rename hints from commit messages and package moves from
+// file renames in the output of git log --name-status with records formatted
+// as "\x1e<hash>\x00<body>\x00".
+func parseLog(out string, pkgs packageMapper) *astdiff.History {
+	history := &astdiff.History{}
+	moves := make(map[string]map[string]bool)
+
+	for _, record := range strings.Split(out, "\x1e") {
+		hash, rest, ok := strings.Cut(record, "\x00")
+		if !ok {
+			continue
+		}
+		body, files, _ := strings.Cut(rest, "\x00")
+
+		source := fmt.Sprintf("commit %s: %q", hash, firstLine(body))
+		for _, names := range messageRenames(body) {
+			history.Renames = append(history.Renames, astdiff.RenameHint{
+				OldName: names[0],
+				NewName: names[1],
+				Source:  source,
+			})
+		}
+
+		for _, line := range strings.Split(files, "\n") {
+			fields := strings.Split(line, "\t")
+			if len(fields) != 3 || !strings.HasPrefix(fields[0], "R") {
+				continue
+			}
+			oldPkg, ok := pkgs.packageOf(fields[1])
+			if !ok {
+				continue
+			}
+			newPkg, ok := pkgs.packageOf(fields[2])
+			if !ok || oldPkg == newPkg {
+				continue
+			}
+			if moves[oldPkg] == nil {
+				moves[oldPkg] = make(map[string]bool)
+			}
+			moves[oldPkg][newPkg] = true
+		}
+	}
+
+	history.PackageMoves = resolveMoves(moves)
+	return history
+}
+
+// resolveMoves keeps packages whose moved files all went to a single package and
+// follows chains of moves (a -> b, later b -> c) to their final destination.
+func resolveMoves(moves map[string]map[string]bool) map[string]string {
+	next := make(map[string]string)
+	for from, targets := range moves {
+		if len(targets) != 1 {
+			continue
+		}
+		for to := range targets {
+			next[from] = to
+		}
+	}
+	if len(next) == 0 {
+		return nil
+	}
+
+	resolved := make(map[string]string, len(next))
+	for from, to := range next {
+		for range maxMoveChain {
+			further, ok := next[to]
+			if !ok || further == from {
+				break
+			}
+			to = further
+		}
+		if to != from {
+			resolved[from] = to
+		}
+	}
+	return resolved
+}
+
+// messageRenames returns the old/new name pairs announced in a commit message.
+func messageRenames(body string) [][2]string {
+	var matches [][]string
+	for _, re := range messagePatterns {
+		matches = append(matches, re.FindAllStringSubmatch(body, -1)...)
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if renameVerb.MatchString(line) {
+			matches = append(matches, arrowPattern.FindAllStringSubmatch(line, -1)...)
+		}
+	}
+
+	var out [][2]string
+	seen := make(map[[2]string]bool)
+	for _, m := range matches {
+		oldName, okOld := exportedName(m[1])
+		newName, okNew := exportedName(m[2])
+		if !okOld || !okNew || oldName == newName {
+			continue
+		}
+		pair := [2]string{oldName, newName}
+		if !seen[pair] {
+			seen[pair] = true
+			out = append(out, pair)
+		}
+	}
+	return out
+}
+
+// exportedName normalizes an identifier from free text: a lowercase package
+// qualifier is dropped (http.Get -> Get), a Type.Member pair is kept. It
+// reports false unless the resulting name is exported.
+func exportedName(s string) (string, bool) {
+	if qual, name, ok := strings.Cut(s, "."); ok && !isExported(qual) {
+		s = name
+	}
+	last := s[strings.LastIndex(s, ".")+1:]
+	return s, isExported(s) && isExported(last)
+}
+
+// isExported reports whether name starts with an upper-case letter.
+func isExported(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
+
+// firstLine returns the first line of a commit message.
+func firstLine(body string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(body), "\n")
+	return line
+}
+
+var (
+	// funcDecl matches a function or method declaration line and captures the
+	// receiver type (if any) and the function name.
+	funcDecl = regexp.MustCompile(`^func\s+(?:\(\s*(?:[A-Za-z_]\w*\s+)?\*?([A-Za-z_]\w*)(?:\[[^\]]*\])?\s*\)\s*)?([A-Za-z_]\w*)`)
+
+	// typeDecl matches a single type, const or var declaration line.
+	typeDecl = regexp.MustCompile(`^(?:type|const|var)\s+([A-Za-z_]\w*)`)
+)
+
+// declaration is a declaration line from a diff hunk with its name blanked out.
+type declaration struct {
+	name     string // Recv.Name for methods
+	template string
+}
+
+// parseDecl recognizes a top-level declaration line.
+func parseDecl(line string) (declaration, bool) {
+	if m := funcDecl.FindStringSubmatchIndex(line); m != nil {
+		name := line[m[4]:m[5]]
+		if m[2] >= 0 {
+			name = line[m[2]:m[3]] + "." + name
+		}
+		return declaration{name: name, template: line[:m[4]] + "\x00" + line[m[5]:]}, true
+	}
+	if m := typeDecl.FindStringSubmatchIndex(line); m != nil {
+		return declaration{name: line[m[2]:m[3]], template: line[:m[2]] + "\x00" + line[m[3]:]}, true
+	}
+	return declaration{}, false
+}
+
+// parseDiff extracts rename hints from a zero-context unified diff between the
+// two versions: within one hunk, a removed and an added declaration that are
+// identical apart from the declared name.
+func parseDiff(out string, pkgs packageMapper) []astdiff.RenameHint {
+	var hints []astdiff.RenameHint
+	var oldPath, newPath string
+	var removed, added []declaration
+	inHeader := false
+
+	flush := func() {
+		defer func() { removed, added = nil, nil }()
+		oldPkg, okOld := pkgs.packageOf(oldPath)
+		newPkg, okNew := pkgs.packageOf(newPath)
+		if !okOld || !okNew {
+			return
+		}
+		byTemplate := make(map[string][]declaration)
+		for _, d := range added {
+			byTemplate[d.template] = append(byTemplate[d.template], d)
+		}
+		counts := make(map[string]int)
+		for _, d := range removed {
+			counts[d.template]++
+		}
+		for _, d := range removed {
+			matches := byTemplate[d.template]
+			if counts[d.template] != 1 || len(matches) != 1 || matches[0].name == d.name {
+				continue
+			}
+			if !isExported(d.name[strings.LastIndex(d.name, ".")+1:]) || !isExported(matches[0].name[strings.LastIndex(matches[0].name, ".")+1:]) {
+				continue
+			}
+			hints = append(hints, astdiff.RenameHint{
+				OldName:    d.name,
+				NewName:    matches[0].name,
+				OldPackage: oldPkg,
+				NewPackage: newPkg,
+				Source:     fmt.Sprintf("declaration rewritten in %s", newPath),
+			})
+		}
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			oldPath, newPath = "", ""
+			inHeader = true
+		case inHeader && strings.HasPrefix(line, "--- "):
+			oldPath = diffPath(line[4:], "a/")
+		case inHeader && strings.HasPrefix(line, "+++ "):
+			newPath = diffPath(line[4:], "b/")
+		case strings.HasPrefix(line, "@@"):
+			flush()
+			inHeader = false
+		case inHeader:
+			// index, mode and similarity lines
+		case strings.HasPrefix(line, "-"):
+			if d, ok := parseDecl(line[1:]); ok {
+				removed = append(removed, d)
+			}
+		case strings.HasPrefix(line, "+"):
+			if d, ok := parseDecl(line[1:]); ok {
+				added = append(added, d)
+			}
+		}
+	}
+	flush()
+
+	sort.SliceStable(hints, func(i, j int) bool {
+		if hints[i].OldPackage != hints[j].OldPackage {
+			return hints[i].OldPackage < hints[j].OldPackage
+		}
+		return hints[i].OldName < hints[j].OldName
+	})
+	return hints
+}
+
+// diffPath strips the a/ or b/ prefix from a diff file header. /dev/null
+// (added or deleted files) yields "".
+func diffPath(header, prefix string) string {
+	header = strings.TrimSpace(header)
+	if header == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(header, prefix)
+}
@@ -0,0 +1,187 @@
+// Package snapshot persists the parsed export surface of module versions so
+// repeated runs against the same immutable version skip download and parsing.
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/semver"
+
+	"github.com/emenda-labs/emenda/drivers/golang/astdiff"
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+// Exports is the parsed export surface of one module.
+type Exports struct {
+	Path    string             `json:"path"`
+	Symbols symbols.Symbols    `json:"symbols"`
+	Sigs    astdiff.FuncSigMap `json:"sigs"`
+}
+
+// Snapshot is the parse result for one module version.
+type Snapshot struct {
+	ParserVersion int     `json:"parser_version"`
+	Module        string  `json:"module"`
+	Version       string  `json:"version"`
+	Root          Exports `json:"root"`
+}
+
+// Key identifies a snapshot: the module version and every parser setting that
+// affects the parse result.
+type Key struct {
+	Module  string
+	Version string
+	// TagKeys are the struct tag keys recorded on fields. Nil means astdiff.DefaultTagKeys.
+	TagKeys []string
+	// Behavior reports whether function bodies are summarized for heuristics.
+	Behavior bool
+}
+
+// Cacheable reports whether the key names an immutable module version. Only
+// semantic versions (including pseudo-versions) are cached; branch names and
+// other queries may resolve to different source over time.
+func (k Key) Cacheable() bool {
+	return k.Module != "" && semver.IsValid(k.Version)
+}
+
+// digest returns the content address of the key.
+func (k Key) digest() string {
+	tagKeys := k.TagKeys
+	if tagKeys == nil {
+		tagKeys = astdiff.DefaultTagKeys
+	}
+	tagKeys = slices.Clone(tagKeys)
+	slices.Sort(tagKeys)
+	tagKeys = slices.Compact(tagKeys)
+
+	material, _ := json.Marshal(struct {
+		ParserVersion int      `json:"parser_version"`
+		Module        string   `json:"module"`
+		Version       string   `json:"version"`
+		TagKeys       []string `json:"tag_keys"`
+		Behavior      bool     `json:"behavior"`
+	}{astdiff.ParserVersion, k.Module, k.Version, tagKeys, k.Behavior})
+	sum := sha256.Sum256(material)
+	return hex.EncodeToString(sum[:])
+}
+
+// Cache is an on-disk snapshot store. Entries live in a subdirectory per parser
+// version; opening a cache removes the subdirectories of other parser versions.
+type Cache struct {
+	dir string
+}
+
+// DefaultDir returns the default cache location under the user cache directory.
+func DefaultDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "emenda", "snapshots"), nil
+}
+
+// staleAge is how long the entries of another parser version go unwritten
+// before Open removes them. Other versions may belong to another emenda
+// binary still in use, so they are not removed on sight.
+const staleAge = 30 * 24 * time.Hour
+
+// Open returns a cache rooted at dir, creating it if needed and dropping the
+// entries of other parser versions that have not been written for staleAge.
+func Open(dir string) (*Cache, error) {
+	versionDir := filepath.Join(dir, parserDirName(astdiff.ParserVersion))
+	if err := os.MkdirAll(versionDir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating snapshot cache: %w", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot cache: %w", err)
+	}
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), "parser-") || e.Name() == filepath.Base(versionDir) {
+			continue
+		}
+		if info, err := e.Info(); err != nil || time.Since(info.ModTime()) < staleAge {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, e.Name())); err != nil {
+			return nil, fmt.Errorf("removing stale snapshots: %w", err)
+		}
+	}
+
+	return &Cache{dir: versionDir}, nil
+}
+
+// parserDirName names the cache subdirectory for a parser version.
+func parserDirName(version int) string {
+	return fmt.Sprintf("parser-%d", version)
+}
+
+// path returns the file holding the snapshot for key.
+func (c *Cache) path(key Key) string {
+	return filepath.Join(c.dir, key.digest()+".json")
+}
+
+// Get returns the cached snapshot for key. Missing, unreadable or mismatched
+// entries are reported as a miss; corrupt entries are removed.
+func (c *Cache) Get(key Key) (*Snapshot, bool) {
+	if !key.Cacheable() {
+		return nil, false
+	}
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil ||
+		snap.ParserVersion != astdiff.ParserVersion || snap.Module != key.Module || snap.Version != key.Version {
+		os.Remove(path)
+		return nil, false
+	}
+	return &snap, true
+}
+
+// Put stores snap under key. Entries are written to a temp file and renamed into
+// place so concurrent readers never observe a partial file. Keys that are not
+// cacheable are ignored.
+func (c *Cache) Put(key Key, snap *Snapshot) error {
+	if !key.Cacheable() {
+		return nil
+	}
+	stored := *snap
+	stored.ParserVersion = astdiff.ParserVersion
+	stored.Module = key.Module
+	stored.Version = key.Version
+
+	data, err := json.Marshal(&stored)
+	if err != nil {
+		return fmt.Errorf("encoding snapshot for %s@%s: %w", key.Module, key.Version, err)
+	}
+
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("writing snapshot for %s@%s: %w", key.Module, key.Version, err)
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing snapshot for %s@%s: %w", key.Module, key.Version, err)
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing snapshot for %s@%s: %w", key.Module, key.Version, err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/emenda-labs/emenda/drivers/golang/astdiff"
+	"github.com/emenda-labs/emenda/drivers/golang/symbols"
+)
+
+func testSnapshot() *Snapshot {
+	return &Snapshot{
+		Root: Exports{
+			Path: "github.com/acme/foo",
+			Symbols: symbols.Symbols{
+				Module: "github.com/acme/foo",
+				Entries: []symbols.Symbol{
+					{Name: "New", Kind: symbols.SymbolFunc, Package: "github.com/acme/foo", Signature: "() *Client"},
+				},
+			},
+			Sigs: astdiff.FuncSigMap{},
+		},
+	}
+}
+
+func TestCache_RoundTrip(t *testing.T) {
+	cache, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	key := Key{Module: "github.com/acme/foo", Version: "v1.2.0"}
+
+	if _, ok := cache.Get(key); ok {
+		t.Fatal("unexpected hit on empty cache")
+	}
+	if err := cache.Put(key, testSnapshot()); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	got, ok := cache.Get(key)
+	if !ok {
+		t.Fatal("expected hit after Put")
+	}
+	if got.Module != key.Module || got.Version != key.Version || got.ParserVersion != astdiff.ParserVersion {
+		t.Errorf("header = %s@%s parser %d", got.Module, got.Version, got.ParserVersion)
+	}
+	if !reflect.DeepEqual(got.Root, testSnapshot().Root) {
+		t.Errorf("root mismatch:\n got %+v\nwant %+v", got.Root, testSnapshot().Root)
+	}
+}
+
+func TestCache_KeyedByParserSettings(t *testing.T) {
+	cache, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	key := Key{Module: "github.com/acme/foo", Version: "v1.2.0"}
+	if err := cache.Put(key, testSnapshot()); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	misses := []Key{
+		{Module: "github.com/acme/foo", Version: "v1.3.0"},
+		{Module: "github.com/acme/bar", Version: "v1.2.0"},
+		{Module: "github.com/acme/foo", Version: "v1.2.0", TagKeys: []string{"json"}},
+		{Module: "github.com/acme/foo", Version: "v1.2.0", Behavior: true},
+	}
+	for _, k := range misses {
+		if _, ok := cache.Get(k); ok {
+			t.Errorf("unexpected hit for %+v", k)
+		}
+	}
+
+	// Nil tag keys and the explicit defaults in any order parse identically.
+	reordered := append([]string(nil), astdiff.DefaultTagKeys...)
+	reordered[0], reordered[len(reordered)-1] = reordered[len(reordered)-1], reordered[0]
+	if _, ok := cache.Get(Key{Module: key.Module, Version: key.Version, TagKeys: reordered}); !ok {
+		t.Error("expected hit for reordered default tag keys")
+	}
+}
+
+func TestCache_NonCacheableVersions(t *testing.T) {
+	cache, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	for _, version := range []string{"master", "latest", "", "1.2.0"} {
+		key := Key{Module: "github.com/acme/foo", Version: version}
+		if err := cache.Put(key, testSnapshot()); err != nil {
+			t.Fatalf("Put(%q): %v", version, err)
+		}
+		if _, ok := cache.Get(key); ok {
+			t.Errorf("version %q should not be cached", version)
+		}
+	}
+}
+
+func TestCache_CorruptEntry(t *testing.T) {
+	cache, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	key := Key{Module: "github.com/acme/foo", Version: "v1.2.0"}
+	if err := os.WriteFile(cache.path(key), []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get(key); ok {
+		t.Fatal("corrupt entry reported as a hit")
+	}
+	if _, err := os.Stat(cache.path(key)); !os.IsNotExist(err) {
+		t.Error("corrupt entry was not removed")
+	}
+}
+
+func TestOpen_DropsStaleParserVersions(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, parserDirName(astdiff.ParserVersion-1))
+	recent := filepath.Join(dir, parserDirName(astdiff.ParserVersion+1))
+	for _, d := range []string{stale, recent} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(d, "entry.json"), []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * staleAge)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+	unrelated := filepath.Join(dir, "other")
+	if err := os.MkdirAll(unrelated, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(dir); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("stale parser version directory was not removed")
+	}
+	if _, err := os.Stat(filepath.Join(recent, "entry.json")); err != nil {
+		t.Errorf("recently used parser version directory removed: %v", err)
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Errorf("unrelated directory removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, parserDirName(astdiff.ParserVersion))); err != nil {
+		t.Errorf("current parser version directory missing: %v", err)
+	}
+}
@@ -1,5 +1,7 @@
 package symbols
 
+import "github.com/emenda-labs/emenda/core/changespec"
+
 // SymbolKind identifies what kind of exported Go symbol this is.
 type SymbolKind string
 
@@ -20,6 +22,21 @@ type Symbol struct {
 	Package   string     `json:"package"`
 	Receiver  string     `json:"receiver,omitempty"`
 	Signature string     `json:"signature,omitempty"`
+	// Tags holds struct tag values for field symbols, keyed by tag key (e.g. "json").
+	// Only the keys selected at parse time are recorded.
+	Tags map[string]string `json:"tags,omitempty"`
+	// AliasOf is the fully qualified target of a type alias (e.g. "github.com/acme/foo/bar.Config").
+	// Predeclared targets are unqualified (e.g. "int"). Empty for non-alias symbols.
+	AliasOf string `json:"alias_of,omitempty"`
+	// Location is the declaration position relative to the module root.
+	Location *changespec.Location `json:"location,omitempty"`
+	// Doc is an excerpt of the declaration's doc comment.
+	Doc string `json:"doc,omitempty"`
+	// Refs lists the exported declarations of the same module referenced by the
+	// symbol's type, fully qualified (e.g. "github.com/acme/foo.Config"). For
+	// functions and methods these are parameter and result types; the receiver
+	// is not included.
+	Refs []string `json:"refs,omitempty"`
 }
 
 // Symbols is the full set of exports from a Go module version.
@@ -27,6 +44,50 @@ type Symbols struct {
 	Module  string   `json:"module"`
 	Version string   `json:"version"`
 	Entries []Symbol `json:"entries"`
+	// Diagnostics lists source that could not be fully parsed.
+	Diagnostics []changespec.Diagnostic `json:"diagnostics,omitempty"`
+	// Behavior summarizes function bodies for behavioral heuristics. Nil unless
+	// requested at parse time.
+	Behavior *Behavior `json:"behavior,omitempty"`
+}
+
+// Behavior records side effects visible in function bodies that the exported
+// API does not reveal.
+type Behavior struct {
+	Inits  []InitFunc     `json:"inits,omitempty"`
+	Funcs  []FuncBehavior `json:"funcs,omitempty"`
+	Consts []ConstValue   `json:"consts,omitempty"`
+}
+
+// InitFunc is a package init function.
+type InitFunc struct {
+	Package  string               `json:"package"`
+	Location *changespec.Location `json:"location,omitempty"`
+}
+
+// FuncBehavior summarizes the body of an exported function or method.
+type FuncBehavior struct {
+	// Name uses the same format as Symbol.Name (e.g. Client.Do for methods).
+	Name     string               `json:"name"`
+	Package  string               `json:"package"`
+	Location *changespec.Location `json:"location,omitempty"`
+	// EnvReads lists the keys passed to os.Getenv and os.LookupEnv. Keys that
+	// are not string literals are recorded as their source text (e.g. "key").
+	EnvReads []string `json:"env_reads,omitempty"`
+	// Goroutines counts go statements.
+	Goroutines int `json:"goroutines,omitempty"`
+	// Panics lists the argument of each panic call as source text.
+	Panics []string `json:"panics,omitempty"`
+	// Consts lists the module constants referenced, fully qualified.
+	Consts []string `json:"consts,omitempty"`
+}
+
+// ConstValue is a package-level constant with an explicit value, exported or not.
+type ConstValue struct {
+	Name    string `json:"name"`
+	Package string `json:"package"`
+	// Value is the source text of the value expression, e.g. "30 * time.Second".
+	Value string `json:"value"`
 }
 
 // AliasMap maps Go file paths to their import aliases.
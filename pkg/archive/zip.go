@@ -2,7 +2,6 @@ package archive
 
 import (
 	"archive/zip"
-	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -16,12 +15,13 @@ const (
 	maxFileCount   = 50000              // maximum number of files in archive
 )
 
-// ExtractZip unpacks a zip archive to a temp directory.
+// ExtractZip unpacks the zip archive of the given size read from r (typically an
+// *os.File) to a temp directory.
 // Returns the path to the extracted directory and a cleanup function
 // that removes the temp directory.
 // Validates all paths to prevent zip-slip (path traversal) attacks.
 // Enforces size limits to prevent zip bomb attacks.
-func ExtractZip(data []byte, prefix string) (dir string, cleanup func(), err error) {
+func ExtractZip(r io.ReaderAt, size int64, prefix string) (dir string, cleanup func(), err error) {
 	tmpDir, err := os.MkdirTemp("", "emenda-"+prefix+"-*")
 	if err != nil {
 		return "", nil, fmt.Errorf("failed to create temp directory: %w", err)
@@ -29,7 +29,7 @@ func ExtractZip(data []byte, prefix string) (dir string, cleanup func(), err err
 
 	cleanupFn := func() { os.RemoveAll(tmpDir) }
 
-	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	reader, err := zip.NewReader(r, size)
 	if err != nil {
 		cleanupFn()
 		return "", nil, fmt.Errorf("failed to read zip archive: %w", err)
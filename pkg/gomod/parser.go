@@ -72,3 +72,27 @@ func FindModulePath(dir string) (string, error) {
 
 	return f.Module.Mod.Path, nil
 }
+
+// FindGoVersion reads the go.mod in the given directory and returns the
+// version in its go directive, or "" when it has none.
+func FindGoVersion(dir string) (string, error) {
+	gomodPath := filepath.Join(dir, "go.mod")
+
+	data, err := os.ReadFile(gomodPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("no go.mod found at %s", gomodPath)
+		}
+		return "", fmt.Errorf("reading go.mod: %w", err)
+	}
+
+	f, err := modfile.ParseLax(gomodPath, data, nil)
+	if err != nil {
+		return "", fmt.Errorf("parsing go.mod: %w", err)
+	}
+
+	if f.Go == nil {
+		return "", nil
+	}
+	return f.Go.Version, nil
+}
@@ -0,0 +1,566 @@
+package goproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const defaultAuth = "netrc"
+
+// authenticator adds credentials to requests like the go command: user
+// information in GOPROXY URLs, and for https requests, the headers supplied by
+// the GOAUTH methods. GOAUTH is a ";"-separated list of
+//
+//   - "netrc": basic auth from $NETRC or ~/.netrc
+//   - "git dir": 'git credential fill', run in the absolute directory dir
+//   - any other command, run with no arguments up front and again with the
+//     request URL and response on a 4xx response; it prints URL prefixes, a
+//     blank line, their header lines and another blank line, repeatedly
+//   - "off", alone, to send no credentials
+//
+// Earlier methods take precedence. Credentials are stored by URL prefix without
+// the scheme and apply to every URL below it.
+type authenticator struct {
+	methods []string
+	getenv  func(string) string
+	// configErr is an invalid GOAUTH, reported by every request.
+	configErr error
+
+	// loadOnce runs the GOAUTH methods up front, on the first https request.
+	loadOnce sync.Once
+
+	mu sync.Mutex
+	// proxies holds the basic auth header of each GOPROXY URL given with user
+	// information; the URL itself is stored without it.
+	proxies map[string]http.Header
+	creds   map[string]http.Header
+	// failures are the errors of the last GOAUTH run.
+	failures []error
+	// secrets are the credential values to hide in error messages.
+	secrets []string
+}
+
+// newAuthenticator parses goauth, which is GOAUTH syntax; empty selects
+// "netrc". getenv supplies NETRC and HOME.
+func newAuthenticator(goauth string, getenv func(string) string) *authenticator {
+	if strings.TrimSpace(goauth) == "" {
+		goauth = defaultAuth
+	}
+	a := &authenticator{getenv: getenv, proxies: make(map[string]http.Header), creds: make(map[string]http.Header)}
+	for _, method := range strings.Split(goauth, ";") {
+		method = strings.TrimSpace(method)
+		words := strings.Fields(method)
+		switch {
+		case len(words) == 0:
+			a.configErr = fmt.Errorf("GOAUTH=%s contains an empty method", goauth)
+		case words[0] == "off" && strings.TrimSpace(goauth) != "off":
+			a.configErr = fmt.Errorf("GOAUTH=off cannot be combined with other methods (GOAUTH=%s)", goauth)
+		case words[0] == "git" && (len(words) != 2 || !filepath.IsAbs(words[1])):
+			a.configErr = fmt.Errorf("GOAUTH=%s: the git method requires an absolute path to a git working directory", method)
+		}
+		if a.configErr != nil {
+			return a
+		}
+		if method != "off" {
+			a.methods = append(a.methods, method)
+		}
+	}
+	return a
+}
+
+// stripUserinfo removes the user information from proxy, a GOPROXY entry or
+// GOSUMDB URL, and keeps it as the basic auth of requests to that URL.
+func (a *authenticator) stripUserinfo(proxy string) string {
+	u, err := url.Parse(proxy)
+	if err != nil || u.User == nil {
+		return proxy
+	}
+	userinfo := u.User
+	password, _ := userinfo.Password()
+	req := &http.Request{Header: make(http.Header)}
+	req.SetBasicAuth(userinfo.Username(), password)
+	u.User = nil
+	stripped := u.String()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.proxies[stripped] = req.Header
+	a.addSecrets(userinfo.String(), password, req.Header)
+	return stripped
+}
+
+// addCredentials sets the headers stored for req's URL. GOAUTH methods run on
+// the first https request.
+func (a *authenticator) addCredentials(ctx context.Context, req *http.Request) error {
+	if a == nil {
+		return nil
+	}
+	if a.configErr != nil {
+		return a.configErr
+	}
+	target := req.URL.String()
+
+	a.mu.Lock()
+	for proxy, header := range a.proxies {
+		if target == proxy || strings.HasPrefix(target, proxy+"/") {
+			a.mu.Unlock()
+			setHeaders(req, header)
+			return nil
+		}
+	}
+	a.mu.Unlock()
+	if req.URL.Scheme != "https" {
+		return nil
+	}
+	a.loadOnce.Do(func() {
+		creds, failures := a.run(ctx, "", nil)
+		a.store(creds, failures)
+	})
+
+	a.mu.Lock()
+	header := a.lookup(target)
+	a.mu.Unlock()
+	if header != nil {
+		setHeaders(req, header)
+	}
+	return nil
+}
+
+// refresh runs the GOAUTH methods again for a request to target that got the
+// 4xx response resp. It reports whether credentials for target are now
+// known, in which case the request is worth repeating.
+func (a *authenticator) refresh(ctx context.Context, req *http.Request, resp *http.Response) bool {
+	if a == nil || a.configErr != nil || req.URL.Scheme != "https" || len(a.methods) == 0 {
+		return false
+	}
+	target := req.URL.String()
+
+	a.mu.Lock()
+	before := a.lookup(target)
+	a.mu.Unlock()
+
+	creds, failures := a.run(ctx, target, resp)
+	a.store(creds, failures)
+
+	a.mu.Lock()
+	after := a.lookup(target)
+	a.mu.Unlock()
+	return after != nil && !headersEqual(before, after)
+}
+
+// failure returns the errors of the last GOAUTH run, or nil.
+func (a *authenticator) failure() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.failures) == 0 {
+		return nil
+	}
+	return errors.Join(a.failures...)
+}
+
+// run runs the GOAUTH methods for target, or with no target up front, and
+// returns the credentials they supply by URL prefix and the errors of those
+// that failed. It does not hold a.mu: methods may run commands that prompt.
+func (a *authenticator) run(ctx context.Context, target string, resp *http.Response) (creds map[string]http.Header, failures []error) {
+	creds = make(map[string]http.Header)
+	// Run in reverse so that the credentials of earlier methods win.
+	for _, method := range slices.Backward(a.methods) {
+		var supplied map[string]http.Header
+		var err error
+		words := strings.Fields(method)
+		switch words[0] {
+		case "netrc":
+			supplied, err = a.netrc()
+		case "git":
+			if target == "" {
+				continue
+			}
+			supplied, err = gitCredentials(ctx, words[1], target)
+		default:
+			supplied, err = runAuthCommand(ctx, method, target, resp)
+		}
+		if err != nil {
+			failures = append(failures, fmt.Errorf("GOAUTH=%s: %w", method, err))
+			continue
+		}
+		for prefix, header := range supplied {
+			creds[credentialKey(prefix)] = header
+		}
+	}
+	return creds, failures
+}
+
+// store records the result of a GOAUTH run: creds, keyed by credentialKey,
+// and failures, which replace those of the previous run.
+func (a *authenticator) store(creds map[string]http.Header, failures []error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.failures = failures
+	for key, header := range creds {
+		a.creds[key] = header
+		a.addSecrets("", "", header)
+	}
+}
+
+// lookup returns the headers stored for target or the longest prefix of its
+// path that has some. a.mu must be held.
+func (a *authenticator) lookup(target string) http.Header {
+	key := credentialKey(target)
+	for {
+		if header, ok := a.creds[key]; ok {
+			return header
+		}
+		i := strings.LastIndexByte(key, '/')
+		if i < 0 {
+			return nil
+		}
+		key = key[:i]
+	}
+}
+
+// addSecrets records credential values to redact. a.mu must be held.
+func (a *authenticator) addSecrets(userinfo, password string, header http.Header) {
+	for _, s := range []string{userinfo, password} {
+		a.addSecret(s)
+	}
+	for _, values := range header {
+		for _, v := range values {
+			// Hide "Basic <token>" and "Bearer <token>" values by their token
+			// too, in case only that part is echoed.
+			_, token, _ := strings.Cut(v, " ")
+			a.addSecret(v)
+			a.addSecret(token)
+		}
+	}
+}
+
+// addSecret records s unless it is too short to be a credential worth hiding
+// or already known. a.mu must be held.
+func (a *authenticator) addSecret(s string) {
+	if len(s) >= 4 && !slices.Contains(a.secrets, s) {
+		a.secrets = append(a.secrets, s)
+	}
+}
+
+// userinfoPattern matches the user information of URLs.
+var userinfoPattern = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://)[^/?#@\s]+@`)
+
+// redact replaces URL user information and known credentials in msg.
+func (a *authenticator) redact(msg string) string {
+	msg = userinfoPattern.ReplaceAllString(msg, "${1}xxxxx@")
+	if a == nil {
+		return msg
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, s := range a.secrets {
+		msg = strings.ReplaceAll(msg, s, "xxxxx")
+	}
+	return msg
+}
+
+// redactErr wraps err so that its message hides credentials.
+func (a *authenticator) redactErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &redactedError{err: err, msg: a.redact(err.Error())}
+}
+
+// redactedError is an error whose message has credentials removed.
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// netrc returns the basic auth credentials in the netrc file.
+func (a *authenticator) netrc() (map[string]http.Header, error) {
+	path, err := netrcPath(a.getenv)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	creds := make(map[string]http.Header)
+	lines := parseNetrc(string(data))
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// The first entry for a machine wins.
+	for _, l := range slices.Backward(lines) {
+		req := &http.Request{Header: make(http.Header)}
+		req.SetBasicAuth(l.login, l.password)
+		creds[l.machine] = req.Header
+		a.addSecret(l.password)
+	}
+	return creds, nil
+}
+
+// netrcPath returns $NETRC, else the .netrc file in the home directory
+// (_netrc on Windows when it exists).
+func netrcPath(getenv func(string) string) (string, error) {
+	if path := getenv("NETRC"); path != "" {
+		return path, nil
+	}
+	home := getenv("HOME")
+	if runtime.GOOS == "windows" {
+		home = getenv("USERPROFILE")
+	}
+	if home == "" {
+		return "", errors.New("cannot locate .netrc: home directory unknown")
+	}
+	if runtime.GOOS == "windows" {
+		legacy := filepath.Join(home, "_netrc")
+		if _, err := os.Stat(legacy); err == nil {
+			return legacy, nil
+		}
+	}
+	return filepath.Join(home, ".netrc"), nil
+}
+
+type netrcLine struct {
+	machine  string
+	login    string
+	password string
+}
+
+// parseNetrc returns the complete machine entries of a netrc file. Macro
+// definitions are skipped and parsing stops at the default entry.
+func parseNetrc(data string) []netrcLine {
+	var lines []netrcLine
+	var l netrcLine
+	inMacro := false
+	for line := range strings.SplitSeq(data, "\n") {
+		if inMacro {
+			if line == "" {
+				inMacro = false
+			}
+			continue
+		}
+		f := strings.Fields(line)
+		for i := 0; i < len(f); i += 2 {
+			if f[i] == "default" {
+				// The default entry comes last and matches any machine; it
+				// is not used.
+				return lines
+			}
+			if i+1 == len(f) {
+				break
+			}
+			switch f[i] {
+			case "machine":
+				l = netrcLine{machine: f[i+1]}
+			case "login":
+				l.login = f[i+1]
+			case "password":
+				l.password = f[i+1]
+			case "macdef":
+				inMacro = true
+			}
+			if l.machine != "" && l.login != "" && l.password != "" {
+				lines = append(lines, l)
+				l = netrcLine{}
+			}
+		}
+	}
+	return lines
+}
+
+// runAuthCommand runs a GOAUTH command, passing target as its argument and
+// resp on stdin when set, and parses the credentials it prints.
+func runAuthCommand(ctx context.Context, command, target string, resp *http.Response) (map[string]http.Header, error) {
+	words, err := splitQuoted(command)
+	if err != nil {
+		return nil, err
+	}
+	if target != "" {
+		words = append(words, target)
+	}
+	cmd := exec.CommandContext(ctx, words[0], words[1:]...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if resp != nil {
+		var in strings.Builder
+		fmt.Fprintf(&in, "%s %s\n", resp.Proto, resp.Status)
+		for name, values := range resp.Header {
+			fmt.Fprintf(&in, "%s: %s\n", name, strings.Join(values, ", "))
+		}
+		in.WriteString("\n")
+		cmd.Stdin = strings.NewReader(in.String())
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running command: %w\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+	creds, err := parseAuthOutput(string(out))
+	if err != nil {
+		return nil, fmt.Errorf("parsing command output: %w", err)
+	}
+	return creds, nil
+}
+
+// parseAuthOutput parses the output of a GOAUTH command: blocks of URL lines,
+// a blank line, header lines and another blank line.
+func parseAuthOutput(data string) (map[string]http.Header, error) {
+	creds := make(map[string]http.Header)
+	for data != "" {
+		var line string
+		var ok bool
+		var prefixes []string
+		for {
+			line, data, ok = strings.Cut(data, "\n")
+			if !ok {
+				return nil, errors.New("missing blank line after URLs")
+			}
+			if line == "" {
+				break
+			}
+			u, err := url.ParseRequestURI(line)
+			if err != nil {
+				return nil, fmt.Errorf("invalid URL %q", line)
+			}
+			prefixes = append(prefixes, u.String())
+		}
+		header := make(http.Header)
+		for {
+			line, data, ok = strings.Cut(data, "\n")
+			if !ok {
+				return nil, errors.New("missing blank line after headers")
+			}
+			if line == "" {
+				break
+			}
+			name, value, ok := strings.Cut(line, ": ")
+			if !ok || name == "" || strings.ContainsAny(name, " \t:") {
+				// Do not echo the line: it likely holds a credential.
+				return nil, errors.New("invalid header line")
+			}
+			header.Add(name, strings.TrimSpace(value))
+		}
+		for _, p := range prefixes {
+			creds[p] = header.Clone()
+		}
+	}
+	return creds, nil
+}
+
+// gitCredentials asks 'git credential fill', run in dir, for the credentials
+// of target.
+func gitCredentials(ctx context.Context, dir, target string) (map[string]http.Header, error) {
+	cmd := exec.CommandContext(ctx, "git", "credential", "fill")
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader("url=" + target + "\n")
+	// Never prompt: the upgrade may run unattended.
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git credential fill: %w", err)
+	}
+	prefix := new(url.URL)
+	var username, password string
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "protocol":
+			prefix.Scheme = value
+		case "host":
+			prefix.Host = value
+		case "path":
+			prefix.Path = value
+		case "username":
+			username = value
+		case "password":
+			password = value
+		case "url":
+			if u, err := url.ParseRequestURI(value); err == nil {
+				prefix = u
+			}
+		}
+	}
+	if prefix.Host == "" || !strings.HasPrefix(target, prefix.String()) {
+		return nil, fmt.Errorf("git credential fill returned credentials for %s, not %s", prefix.Redacted(), target)
+	}
+	req := &http.Request{Header: make(http.Header)}
+	req.SetBasicAuth(username, password)
+	return map[string]http.Header{prefix.String(): req.Header}, nil
+}
+
+// credentialKey returns the key credentials for target are stored under: the
+// URL without "https://" and trailing slash.
+func credentialKey(target string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(target, "https://"), "/")
+}
+
+// setHeaders replaces req's values for the headers in header.
+func setHeaders(req *http.Request, header http.Header) {
+	for name, values := range header {
+		req.Header[name] = slices.Clone(values)
+	}
+}
+
+func headersEqual(a, b http.Header) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, values := range a {
+		if !slices.Equal(values, b[name]) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitQuoted splits a GOAUTH command into words. Words may be quoted with
+// single or double quotes to include spaces.
+func splitQuoted(s string) ([]string, error) {
+	var words []string
+	for {
+		s = strings.TrimLeft(s, " \t\n")
+		if s == "" {
+			break
+		}
+		if q := s[0]; q == '\'' || q == '"' {
+			end := strings.IndexByte(s[1:], q)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated %c quote in command %s", q, s)
+			}
+			words = append(words, s[1:1+end])
+			s = s[2+end:]
+			continue
+		}
+		end := strings.IndexAny(s, " \t\n")
+		if end < 0 {
+			end = len(s)
+		}
+		words = append(words, s[:end])
+		s = s[end:]
+	}
+	if len(words) == 0 {
+		return nil, errors.New("empty command")
+	}
+	return words, nil
+}
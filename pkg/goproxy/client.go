@@ -2,11 +2,16 @@ package goproxy
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"math/rand/v2"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/mod/module"
@@ -14,117 +19,782 @@ import (
 
 const (
 	defaultProxy        = "https://proxy.golang.org,direct"
-	httpClientTimeout   = 30 * time.Second
 	defaultUserAgent    = "emenda/0.1.0"
 	statusNotFound      = http.StatusNotFound
 	statusGone          = http.StatusGone
 	maxZipDownloadSize  = 512 * 1024 * 1024 // 512 MB
 )
 
+const (
+	// responseHeaderTimeout bounds the wait for a proxy to start responding.
+	// Whole requests are bounded by the caller's context instead, so large
+	// downloads on slow links are not cut off.
+	responseHeaderTimeout = 30 * time.Second
+
+	// defaultStallTimeout fails a download that receives no data for this
+	// long; the retry resumes it.
+	defaultStallTimeout = 30 * time.Second
+
+	// maxMetadataSize bounds the list, info and go.mod responses kept in memory.
+	maxMetadataSize = 16 * 1024 * 1024
+)
+
 // Client downloads module zip files from the Go module proxy.
 type Client struct {
 	httpClient *http.Client
 	userAgent  string
-	proxies    []string
+	proxies    []proxyEntry
+	retry      retryPolicy
+	stall      time.Duration
+	maxZipSize int64
+	progress   ProgressFunc
+	auth       *authenticator
+	repos      RepoResolver
+	private    PrivatePatterns
+	goSum      GoSum
+	sumdb      *checksumDB
+	sumdbErr   error
+
+	// metadata caches responses that never change once published: the .mod
+	// and .info files of exact versions, keyed by module and proxy path.
+	metadataMu sync.Mutex
+	metadata   map[string][]byte
+}
+
+// ClientOptions configures a Client. The zero value selects the defaults.
+type ClientOptions struct {
+	// Proxy is the proxy chain in GOPROXY syntax. Empty reads the GOPROXY
+	// environment variable, falling back to "https://proxy.golang.org,direct".
+	// file:// entries name a directory in proxy layout, such as a module
+	// cache's cache/download directory, and are read from disk.
+	Proxy string
+
+	// Repos locates the repository of a module fetched in direct mode. Nil
+	// resolves module paths the way the go command does.
+	Repos RepoResolver
+
+	// Private selects modules that bypass the proxy chain or may be fetched
+	// insecurely. Nil reads GOPRIVATE, GONOPROXY, GONOSUMDB and GOINSECURE.
+	Private *PrivatePatterns
+
+	// GoSum holds trusted checksums, typically the consumer module's go.sum.
+	// Downloads of listed versions must match them; other versions are checked
+	// against the checksum database.
+	GoSum GoSum
+
+	// Auth selects where request credentials come from, in GOAUTH syntax.
+	// Empty reads GOAUTH, defaulting to "netrc". Credentials in Proxy URLs
+	// are used as well.
+	Auth string
+
+	// Progress receives zip download progress. Nil disables reporting.
+	Progress ProgressFunc
+
+	// SumDB is the checksum database in GOSUMDB syntax; "off" disables it.
+	// Empty reads GOSUMDB, defaulting to sum.golang.org. GOFLAGS=-insecure
+	// also disables it.
+	SumDB string
+
+	// SumDBDir holds the checksum database's latest signed tree head between
+	// runs. Empty uses DefaultSumDBDir, or memory when there is no user cache
+	// directory.
+	SumDBDir string
 }
 
 // NewClient creates a Client that reads the GOPROXY environment variable to
 // determine the proxy chain. If GOPROXY is unset, it defaults to
 // "https://proxy.golang.org,direct".
 func NewClient() *Client {
-	goproxy := os.Getenv("GOPROXY")
+	return NewClientWithOptions(ClientOptions{})
+}
+
+// NewClientWithOptions creates a Client with the given options.
+// Settings not given in opts are read from the environment, falling back to
+// the settings written by 'go env -w'.
+func NewClientWithOptions(opts ClientOptions) *Client {
+	getenv := GoEnv(os.Getenv, os.UserConfigDir)
+
+	goproxy := opts.Proxy
+	if strings.TrimSpace(goproxy) == "" {
+		goproxy = getenv("GOPROXY")
+	}
 	if strings.TrimSpace(goproxy) == "" {
 		goproxy = defaultProxy
 	}
 
-	// The GOPROXY value is a comma- or pipe-separated list of proxy URLs.
-	replacer := strings.NewReplacer("|", ",")
-	normalized := replacer.Replace(goproxy)
+	goauth := opts.Auth
+	if goauth == "" {
+		goauth = getenv("GOAUTH")
+	}
+	auth := newAuthenticator(goauth, os.Getenv)
+	proxies := parseProxyChain(goproxy)
+	for i, p := range proxies {
+		proxies[i].url = auth.stripUserinfo(p.url)
+	}
 
-	parts := strings.Split(normalized, ",")
-	proxies := make([]string, 0, len(parts))
-	for _, p := range parts {
-		trimmed := strings.TrimSpace(p)
-		if trimmed != "" {
-			proxies = append(proxies, trimmed)
-		}
+	private := privatePatterns(getenv)
+	if opts.Private != nil {
+		private = *opts.Private
 	}
 
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = responseHeaderTimeout
+	httpClient := &http.Client{Transport: transport}
+	repos := opts.Repos
+	if repos == nil {
+		repos = &goImportResolver{httpClient: httpClient, userAgent: defaultUserAgent, auth: auth, insecure: private.AllowInsecure}
+	}
+
+	gosumdb := opts.SumDB
+	if gosumdb == "" {
+		gosumdb = getenv("GOSUMDB")
+	}
+	if gosumdb == "" {
+		gosumdb = defaultSumDB
+	}
+	if insecureGOFLAGS(getenv("GOFLAGS")) {
+		gosumdb = "off"
+	}
+	// An invalid GOSUMDB is reported by the first download that needs it.
+	sumdbClient := &http.Client{Transport: transport, Timeout: sumdbTimeout}
+	sumdbDir := opts.SumDBDir
+	if sumdbDir == "" {
+		sumdbDir, _ = DefaultSumDBDir()
+	}
+	sumdb, sumdbErr := newChecksumDB(gosumdb, sumdbClient, defaultUserAgent, auth, proxies, sumdbDir)
+
 	return &Client{
-		httpClient: &http.Client{Timeout: httpClientTimeout},
+		httpClient: httpClient,
 		userAgent:  defaultUserAgent,
 		proxies:    proxies,
+		retry:      defaultRetry,
+		stall:      defaultStallTimeout,
+		maxZipSize: maxZipDownloadSize,
+		progress:   opts.Progress,
+		auth:       auth,
+		repos:      repos,
+		private:    private,
+		goSum:      opts.GoSum,
+		sumdb:      sumdb,
+		sumdbErr:   sumdbErr,
+		metadata:   make(map[string][]byte),
 	}
 }
 
+// Progress reports the state of a zip download.
+type Progress struct {
+	Module  string
+	Version string
+
+	// Downloaded counts the bytes received so far, including those kept from
+	// an interrupted attempt that was resumed.
+	Downloaded int64
+
+	// Total is the size of the zip, or -1 when the proxy did not report it.
+	Total int64
+
+	// Done is set on the last report of a download, successful or not.
+	Done bool
+}
+
+// ProgressFunc receives download progress. Concurrent downloads report from
+// their own goroutines.
+type ProgressFunc func(Progress)
+
 // DownloadZip fetches the zip archive for the given module and version from the
-// proxy chain. It returns the raw zip bytes on success.
+// proxy chain. It returns the raw zip bytes on success. The zip is verified
+// against GoSum or the checksum database; a mismatch is a *ChecksumError.
+// Large zips are better fetched with DownloadZipFile.
 func (c *Client) DownloadZip(ctx context.Context, mod, version string) ([]byte, error) {
+	f, cleanup, err := c.DownloadZipFile(ctx, mod, version)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	return os.ReadFile(f.Name())
+}
+
+// DownloadZipFile streams the zip archive for the given module and version
+// from the proxy chain to a temp file, verifies it like DownloadZip and
+// returns the open file. The cleanup function closes and removes it.
+// Interrupted downloads are resumed with range requests; the caller's context
+// bounds the whole download.
+func (c *Client) DownloadZipFile(ctx context.Context, mod, version string) (f *os.File, cleanup func(), err error) {
+	f, err = os.CreateTemp("", "emenda-zip-*.zip")
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating temp file: %w", err)
+	}
+	cleanup = func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+
+	if err := c.downloadZip(ctx, mod, version, f); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("reading downloaded zip: %w", err)
+	}
+	if err := c.verifyZip(ctx, mod, version, f, info.Size()); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return f, cleanup, nil
+}
+
+// downloadZip writes a module zip to f without verifying it.
+func (c *Client) downloadZip(ctx context.Context, mod, version string, f *os.File) error {
+	escapedMod, err := module.EscapePath(mod)
+	if err != nil {
+		return fmt.Errorf("escaping module path %q: %w", mod, err)
+	}
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return fmt.Errorf("escaping version %q: %w", version, err)
+	}
+
+	report := func(Progress) {}
+	if c.progress != nil {
+		last := Progress{Module: mod, Version: version, Total: -1}
+		report = func(p Progress) {
+			p.Module, p.Version = mod, version
+			last = p
+			c.progress(p)
+		}
+		defer func() {
+			last.Done = true
+			c.progress(last)
+		}()
+	}
+
+	return c.tryChain(ctx, mod, mod+"@"+version, func(proxy string) error {
+		if err := resetFile(f); err != nil {
+			return err
+		}
+		switch {
+		case proxy == "direct":
+			return c.downloadDirect(ctx, mod, version, f)
+		case isFileProxy(proxy):
+			return c.copyFileProxy(proxy, escapedMod+"/@v/"+escapedVersion+".zip", f, report)
+		}
+		return c.fetchFile(ctx, fmt.Sprintf("%s/%s/@v/%s.zip", proxy, escapedMod, escapedVersion), f, report)
+	})
+}
+
+// get fetches path, relative to the module's directory on a proxy (e.g.
+// "@v/list"), from mod's proxy chain. A "direct" entry calls direct instead,
+// which must return the same content a proxy would. what names the request in
+// errors.
+func (c *Client) get(ctx context.Context, mod, what, path string, direct func() ([]byte, error)) ([]byte, error) {
 	escapedMod, err := module.EscapePath(mod)
 	if err != nil {
 		return nil, fmt.Errorf("escaping module path %q: %w", mod, err)
 	}
+	var data []byte
+	err = c.tryChain(ctx, mod, what, func(proxy string) error {
+		var err error
+		switch {
+		case proxy == "direct":
+			data, err = direct()
+		case isFileProxy(proxy):
+			data, err = readFileProxy(proxy, escapedMod+"/"+path)
+		default:
+			data, err = c.fetch(ctx, fmt.Sprintf("%s/%s/%s", proxy, escapedMod, path))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
 
-	for i, proxy := range c.proxies {
-		switch proxy {
-		case "direct":
-			fmt.Fprintf(os.Stderr, "goproxy: direct mode not supported yet, skipping\n")
+// getCached is get for immutable content, served from and added to the
+// client's metadata cache. Failures are not cached.
+func (c *Client) getCached(ctx context.Context, mod, what, path string, direct func() ([]byte, error)) ([]byte, error) {
+	key := mod + "/" + path
+	c.metadataMu.Lock()
+	data, ok := c.metadata[key]
+	c.metadataMu.Unlock()
+	if ok {
+		return data, nil
+	}
+
+	data, err := c.get(ctx, mod, what, path, direct)
+	if err != nil {
+		return nil, err
+	}
+	c.metadataMu.Lock()
+	c.metadata[key] = data
+	c.metadataMu.Unlock()
+	return data, nil
+}
+
+// proxyEntry is one element of the GOPROXY chain.
+type proxyEntry struct {
+	// url is the proxy base URL, "direct" or "off".
+	url string
+
+	// fallBackOnError is set when the entry is followed by "|": any failure
+	// moves on to the next entry. After "," only a 404 or 410 response does.
+	fallBackOnError bool
+}
+
+// parseProxyChain splits a GOPROXY value into entries like the go command:
+// empty entries are ignored, and entries other than "direct" and "off" without
+// a scheme get https://.
+func parseProxyChain(goproxy string) []proxyEntry {
+	var proxies []proxyEntry
+	for goproxy != "" {
+		url, fallBackOnError := goproxy, false
+		if i := strings.IndexAny(goproxy, ",|"); i >= 0 {
+			url, fallBackOnError, goproxy = goproxy[:i], goproxy[i] == '|', goproxy[i+1:]
+		} else {
+			goproxy = ""
+		}
+		url = strings.TrimSpace(url)
+		switch {
+		case url == "":
 			continue
-		case "off":
-			fmt.Fprintf(os.Stderr, "goproxy: proxy chain contains 'off', stopping\n")
-			return nil, fmt.Errorf("module %s@%s not found on any proxy", mod, version)
+		case url == "direct" || url == "off":
+		case !strings.Contains(url, ":/"):
+			url = "https://" + url
 		}
+		proxies = append(proxies, proxyEntry{url: strings.TrimSuffix(url, "/"), fallBackOnError: fallBackOnError})
+	}
+	return proxies
+}
+
+// FetchError reports that no entry of the proxy chain could serve a request. It
+// lists the failure of every entry tried, in order.
+type FetchError struct {
+	// What names the request, e.g. "example.com/lib@v1.2.3".
+	What     string
+	Failures []ProxyFailure
+}
+
+// ProxyFailure is the failure of one proxy chain entry.
+type ProxyFailure struct {
+	// Proxy is the proxy URL, "direct" or "off". Credentials are removed from
+	// the URL and from the message of Err.
+	Proxy string
+	Err   error
+}
+
+func (e *FetchError) Error() string {
+	if len(e.Failures) == 1 {
+		return fmt.Sprintf("fetching %s from %s: %v", e.What, e.Failures[0].Proxy, e.Failures[0].Err)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "fetching %s:", e.What)
+	for _, f := range e.Failures {
+		fmt.Fprintf(&b, "\n\t%s: %v", f.Proxy, f.Err)
+	}
+	return b.String()
+}
+
+// Unwrap returns the failures of the individual entries.
+func (e *FetchError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f.Err
+	}
+	return errs
+}
 
-		zipURL := fmt.Sprintf("%s/%s/@v/%s.zip", proxy, escapedMod, version)
+// errProxyOff is the failure of an "off" entry.
+var errProxyOff = errors.New("module lookup disabled by GOPROXY=off")
 
-		data, tryNext, fetchErr := c.fetch(ctx, zipURL)
-		if fetchErr == nil {
-			return data, nil
+// tryChain calls try with each entry of mod's proxy chain (a proxy URL or
+// "direct") until one succeeds. Failures move on to the next entry as the
+// separator after the failing entry allows; the returned *FetchError lists
+// every entry tried. what names the request in errors.
+func (c *Client) tryChain(ctx context.Context, mod, what string, try func(proxy string) error) error {
+	fetchErr := &FetchError{What: what}
+	for _, proxy := range c.proxiesFor(mod) {
+		var err error
+		if proxy.url == "off" {
+			err = errProxyOff
+		} else {
+			err = try(proxy.url)
 		}
+		if err == nil {
+			return nil
+		}
+		fetchErr.Failures = append(fetchErr.Failures, ProxyFailure{Proxy: proxy.url, Err: c.auth.redactErr(err)})
 
-		if tryNext && i < len(c.proxies)-1 {
-			continue
+		if proxy.url == "off" || ctx.Err() != nil {
+			break
+		}
+		if !proxy.fallBackOnError && !isNotFound(err) {
+			break
 		}
+	}
+	if len(fetchErr.Failures) == 0 {
+		return fmt.Errorf("fetching %s: GOPROXY lists no proxies", what)
+	}
+	return fetchErr
+}
 
-		return nil, fetchErr
+// proxiesFor returns the proxy chain for mod. Modules matching GONOPROXY skip
+// the proxies and are fetched directly, unless GOPROXY=off disables fetching.
+func (c *Client) proxiesFor(mod string) []proxyEntry {
+	if !c.private.BypassProxy(mod) {
+		return c.proxies
 	}
+	if len(c.proxies) > 0 && c.proxies[0].url == "off" {
+		return c.proxies[:1]
+	}
+	return []proxyEntry{{url: "direct"}}
+}
+
+// lookupDisabled reports whether GOPROXY=off disables fetching mod.
+func (c *Client) lookupDisabled(mod string) bool {
+	chain := c.proxiesFor(mod)
+	return len(chain) > 0 && chain[0].url == "off"
+}
 
-	return nil, fmt.Errorf("module %s@%s not found on any proxy", mod, version)
+// statusError is an unsuccessful HTTP response from a proxy.
+type statusError struct {
+	url        string
+	statusCode int
+	// retryAfter is the delay requested by a Retry-After header, or zero.
+	retryAfter time.Duration
 }
 
-// fetch performs a single HTTP GET for the given URL.
-// It returns (data, tryNext, error).
-// tryNext signals that the caller should attempt the next proxy in the chain.
-func (c *Client) fetch(ctx context.Context, url string) ([]byte, bool, error) {
+func (e *statusError) Error() string {
+	if e.statusCode == statusNotFound || e.statusCode == statusGone {
+		return fmt.Sprintf("proxy returned %d for %s", e.statusCode, e.url)
+	}
+	return fmt.Sprintf("unexpected status %d from %s", e.statusCode, e.url)
+}
+
+// isNotFound reports whether err is a 404 or 410 response, or a missing file
+// in a file:// proxy: the only failures that move past an entry followed by
+// ",".
+func isNotFound(err error) bool {
+	var se *statusError
+	return errors.As(err, &se) && (se.statusCode == statusNotFound || se.statusCode == statusGone) ||
+		errors.Is(err, fs.ErrNotExist)
+}
+
+// retryPolicy bounds the retries of one proxy request.
+type retryPolicy struct {
+	// attempts is the total number of attempts, including the first.
+	attempts int
+	// base is the backoff before the first retry; it doubles for each retry.
+	base time.Duration
+	// max caps a single wait. A Retry-After asking for longer ends the retries.
+	max time.Duration
+}
+
+var defaultRetry = retryPolicy{attempts: 3, base: 500 * time.Millisecond, max: 10 * time.Second}
+
+// wait returns how long to wait before retry number attempt+1: the server's
+// Retry-After when given, otherwise exponential backoff with jitter in
+// [d/2, d]. ok is false when the server asks for longer than max.
+func (p retryPolicy) wait(attempt int, retryAfter time.Duration) (d time.Duration, ok bool) {
+	if retryAfter > 0 {
+		return retryAfter, retryAfter <= p.max
+	}
+	d = p.base << attempt
+	if d <= 0 || d > p.max {
+		d = p.max
+	}
+	return d/2 + rand.N(d/2+1), true
+}
+
+// withRetry calls attempt until it succeeds, fails with retry unset, or the
+// client's retry policy is exhausted, waiting between attempts.
+func (c *Client) withRetry(ctx context.Context, attempt func() (retry bool, err error)) error {
+	for n := 0; ; n++ {
+		retry, err := attempt()
+		if err == nil {
+			return nil
+		}
+		if !retry || ctx.Err() != nil || n+1 >= c.retry.attempts {
+			if n > 0 {
+				return fmt.Errorf("%w (after %d attempts)", err, n+1)
+			}
+			return err
+		}
+
+		var retryAfter time.Duration
+		var se *statusError
+		if errors.As(err, &se) {
+			retryAfter = se.retryAfter
+		}
+		wait, ok := c.retry.wait(n, retryAfter)
+		if !ok {
+			return fmt.Errorf("%w (server asked to retry after %s)", err, retryAfter)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (retry canceled: %w)", err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// fetch GETs url into memory, retrying transient failures (network errors,
+// stalls, 429 and 5xx responses) as the client's retry policy allows.
+func (c *Client) fetch(ctx context.Context, url string) ([]byte, error) {
+	var data []byte
+	err := c.withRetry(ctx, func() (bool, error) {
+		resp, retry, err := c.open(ctx, url, 0)
+		if err != nil {
+			return retry, err
+		}
+		defer resp.close()
+
+		data, err = io.ReadAll(io.LimitReader(resp.body, maxMetadataSize+1))
+		if err != nil {
+			return true, fmt.Errorf("reading response body from %s: %w", url, err)
+		}
+		if len(data) > maxMetadataSize {
+			return false, fmt.Errorf("response from %s exceeds maximum size of %d bytes", url, maxMetadataSize)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// fetchFile streams url into f, retrying like fetch. A retry after an
+// interrupted transfer asks only for the missing bytes; when the server does
+// not honor the range, the transfer starts over.
+func (c *Client) fetchFile(ctx context.Context, url string, f *os.File, report func(Progress)) error {
+	return c.withRetry(ctx, func() (bool, error) {
+		offset, err := f.Seek(0, io.SeekEnd)
+		if err != nil {
+			return false, err
+		}
+		resp, retry, err := c.open(ctx, url, offset)
+		if err != nil {
+			var se *statusError
+			if offset > 0 && (errors.Is(err, errRangeMismatch) || errors.As(err, &se) && se.statusCode == http.StatusRequestedRangeNotSatisfiable) {
+				// The partial file does not match what the server has now.
+				if resetErr := resetFile(f); resetErr != nil {
+					return false, resetErr
+				}
+				return true, err
+			}
+			return retry, err
+		}
+		defer resp.close()
+
+		if offset > 0 && resp.StatusCode != http.StatusPartialContent {
+			if err := resetFile(f); err != nil {
+				return false, err
+			}
+			offset = 0
+		}
+		total := int64(-1)
+		if resp.ContentLength >= 0 {
+			total = offset + resp.ContentLength
+		}
+		if total > c.maxZipSize {
+			return false, fmt.Errorf("response from %s exceeds maximum size of %d bytes", url, c.maxZipSize)
+		}
+
+		w := &progressWriter{w: f, p: Progress{Downloaded: offset, Total: total}, report: report}
+		report(w.p)
+		n, err := io.Copy(w, io.LimitReader(resp.body, c.maxZipSize-offset+1))
+		if err != nil {
+			return true, fmt.Errorf("reading response body from %s: %w", url, err)
+		}
+		if offset+n > c.maxZipSize {
+			return false, fmt.Errorf("response from %s exceeds maximum size of %d bytes", url, c.maxZipSize)
+		}
+		return false, nil
+	})
+}
+
+var (
+	// errStalled reports a response that delivered no data for the stall timeout.
+	errStalled = errors.New("download stalled")
+
+	// errRangeMismatch reports a range response that does not continue a
+	// partial download.
+	errRangeMismatch = errors.New("range response does not start")
+)
+
+// response is an open proxy response. Reads from body fail with errStalled
+// once no data has arrived for the client's stall timeout.
+type response struct {
+	*http.Response
+	body  io.Reader
+	close func()
+}
+
+// open GETs url, asking for the bytes from offset on when it is positive. Only
+// 200 and, for range requests, 206 responses are returned; other statuses are
+// a *statusError. retry reports whether a failure is transient.
+func (c *Client) open(ctx context.Context, url string, offset int64) (resp *response, retry bool, err error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	stall := time.AfterFunc(c.stall, func() { cancel(errStalled) })
+	closeAll := func() {
+		stall.Stop()
+		cancel(nil)
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
+		closeAll()
 		return nil, false, fmt.Errorf("building request for %s: %w", url, err)
 	}
 	req.Header.Set("User-Agent", c.userAgent)
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	if err := c.auth.addCredentials(ctx, req); err != nil {
+		closeAll()
+		return nil, false, err
+	}
 
-	resp, err := c.httpClient.Do(req)
+	httpResp, err := c.httpClient.Do(req)
+	if err == nil && httpResp.StatusCode/100 == 4 && c.auth.refresh(ctx, req, httpResp) {
+		// GOAUTH supplied new credentials for the URL; try them once.
+		httpResp.Body.Close()
+		req = req.Clone(ctx)
+		if err := c.auth.addCredentials(ctx, req); err != nil {
+			closeAll()
+			return nil, false, err
+		}
+		httpResp, err = c.httpClient.Do(req)
+	}
 	if err != nil {
-		// Network-level error; let the caller decide whether to try the next proxy.
+		closeAll()
+		if errors.Is(context.Cause(ctx), errStalled) {
+			err = fmt.Errorf("%s: %w", url, errStalled)
+		}
+		// Network-level error; worth another attempt.
 		return nil, true, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == statusNotFound || resp.StatusCode == statusGone {
-		return nil, true, fmt.Errorf("proxy returned %d for %s", resp.StatusCode, url)
+	ok := httpResp.StatusCode == http.StatusOK ||
+		(offset > 0 && httpResp.StatusCode == http.StatusPartialContent && rangeStart(httpResp) == offset)
+	if !ok {
+		httpResp.Body.Close()
+		closeAll()
+		if httpResp.StatusCode == http.StatusPartialContent {
+			return nil, true, fmt.Errorf("%s: %w at byte %d", url, errRangeMismatch, offset)
+		}
+		se := &statusError{url: url, statusCode: httpResp.StatusCode, retryAfter: parseRetryAfter(httpResp.Header.Get("Retry-After"))}
+		if code := httpResp.StatusCode; code == http.StatusUnauthorized || code == http.StatusForbidden {
+			if authErr := c.auth.failure(); authErr != nil {
+				return nil, false, fmt.Errorf("%w\n%w", se, authErr)
+			}
+		}
+		return nil, isRetryableStatus(httpResp.StatusCode), se
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, false, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
-	}
+	stall.Reset(c.stall)
+	return &response{
+		Response: httpResp,
+		body:     &stallReader{ctx: ctx, r: httpResp.Body, timer: stall, timeout: c.stall},
+		close: func() {
+			httpResp.Body.Close()
+			closeAll()
+		},
+	}, false, nil
+}
 
-	limitedBody := io.LimitReader(resp.Body, maxZipDownloadSize+1)
-	data, err := io.ReadAll(limitedBody)
+// rangeStart returns the first byte position of a 206 response's
+// Content-Range, or -1.
+func rangeStart(resp *http.Response) int64 {
+	spec, ok := strings.CutPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	if !ok {
+		return -1
+	}
+	first, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(first, 10, 64)
 	if err != nil {
-		return nil, false, fmt.Errorf("reading response body from %s: %w", url, err)
+		return -1
+	}
+	return n
+}
+
+// stallReader pushes back the stall deadline on every read that returns data
+// and reports reads cut off by it as errStalled.
+type stallReader struct {
+	ctx     context.Context
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (s *stallReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	if n > 0 {
+		s.timer.Reset(s.timeout)
+	}
+	if err != nil && err != io.EOF && errors.Is(context.Cause(s.ctx), errStalled) {
+		err = errStalled
+	}
+	return n, err
+}
+
+// progressWriter reports the bytes written through it.
+type progressWriter struct {
+	w      io.Writer
+	p      Progress
+	report func(Progress)
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	n, err := pw.w.Write(b)
+	pw.p.Downloaded += int64(n)
+	pw.report(pw.p)
+	return n, err
+}
+
+// resetFile empties f for a fresh transfer.
+func resetFile(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("truncating %s: %w", f.Name(), err)
 	}
-	if int64(len(data)) > maxZipDownloadSize {
-		return nil, false, fmt.Errorf("response from %s exceeds maximum size of %d bytes", url, maxZipDownloadSize)
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("rewinding %s: %w", f.Name(), err)
 	}
+	return nil
+}
 
-	return data, false, nil
+// isRetryableStatus reports whether a response status is worth retrying:
+// rate limiting and temporary server failures.
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP
+// date. It returns zero when the header is absent or invalid.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
 }
@@ -0,0 +1,357 @@
+package goproxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseProxyChain(t *testing.T) {
+	tests := []struct {
+		goproxy string
+		want    []proxyEntry
+	}{
+		{"https://a.example.com,https://b.example.com|direct", []proxyEntry{
+			{url: "https://a.example.com"},
+			{url: "https://b.example.com", fallBackOnError: true},
+			{url: "direct"},
+		}},
+		{"a.example.com/", []proxyEntry{{url: "https://a.example.com"}}},
+		{" http://a.example.com/proxy/ | off", []proxyEntry{{url: "http://a.example.com/proxy", fallBackOnError: true}, {url: "off"}}},
+		{",,|direct,", []proxyEntry{{url: "direct"}}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := parseProxyChain(tt.goproxy); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseProxyChain(%q) = %+v, want %+v", tt.goproxy, got, tt.want)
+		}
+	}
+}
+
+// standIn is an httptest proxy answering every request with the next status
+// from statuses (repeating the last), serving body on 200.
+type standIn struct {
+	*httptest.Server
+	requests atomic.Int32
+}
+
+func newStandIn(t *testing.T, body []byte, header http.Header, statuses ...int) *standIn {
+	t.Helper()
+	s := &standIn{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(s.requests.Add(1))
+		status := statuses[min(n, len(statuses))-1]
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			w.Write(body)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// fastRetry keeps retry waits short in tests.
+var fastRetry = retryPolicy{attempts: 3, base: time.Millisecond, max: 100 * time.Millisecond}
+
+func testClient(goproxy string) *Client {
+	c := NewClientWithOptions(ClientOptions{Proxy: goproxy, SumDB: "off", Private: &PrivatePatterns{}})
+	c.retry = fastRetry
+	return c
+}
+
+func TestGet_FallbackSemantics(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		sep       string
+		wantFirst int32
+		wantOK    bool // the second proxy served the request
+	}{
+		{"comma_404", http.StatusNotFound, ",", 1, true},
+		{"comma_410", http.StatusGone, ",", 1, true},
+		{"comma_403", http.StatusForbidden, ",", 1, false},
+		{"comma_503_after_retries", http.StatusServiceUnavailable, ",", 3, false},
+		{"pipe_403", http.StatusForbidden, "|", 1, true},
+		{"pipe_503_after_retries", http.StatusServiceUnavailable, "|", 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := newStandIn(t, nil, nil, tt.status)
+			second := newStandIn(t, []byte("second"), nil, http.StatusOK)
+			c := testClient(first.URL + tt.sep + second.URL)
+
+			data, err := c.get(context.Background(), "example.com/lib", "example.com/lib@v/list", "@v/list", nil)
+			if got := first.requests.Load(); got != tt.wantFirst {
+				t.Errorf("first proxy got %d requests, want %d", got, tt.wantFirst)
+			}
+			if tt.wantOK {
+				if err != nil || string(data) != "second" {
+					t.Errorf("get = %q, %v; want the second proxy's response", data, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("get succeeded, want the first proxy's error")
+			}
+			if n := second.requests.Load(); n != 0 {
+				t.Errorf("second proxy got %d requests, want none", n)
+			}
+		})
+	}
+}
+
+func TestGet_AggregatesFailures(t *testing.T) {
+	first := newStandIn(t, nil, nil, http.StatusForbidden)
+	second := newStandIn(t, nil, nil, http.StatusNotFound)
+	c := testClient(first.URL + "|" + second.URL + ",off")
+
+	_, err := c.get(context.Background(), "example.com/lib", "example.com/lib@v1.0.0", "@v/v1.0.0.info", nil)
+	var fetchErr *FetchError
+	if !errors.As(err, &fetchErr) {
+		t.Fatalf("get error = %v, want *FetchError", err)
+	}
+	var proxies []string
+	for _, f := range fetchErr.Failures {
+		proxies = append(proxies, f.Proxy)
+	}
+	if want := []string{first.URL, second.URL, "off"}; !reflect.DeepEqual(proxies, want) {
+		t.Errorf("failures from %q, want %q", proxies, want)
+	}
+	for _, want := range []string{"unexpected status 403", "proxy returned 404", "disabled by GOPROXY=off"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+	if !errors.Is(err, errProxyOff) {
+		t.Error("FetchError does not unwrap to the entries' errors")
+	}
+}
+
+func TestFetch_Retries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		header       http.Header
+		wantRequests int32
+		wantErr      bool
+	}{
+		{"recovers_from_503", []int{503, 502, 200}, nil, 3, false},
+		{"recovers_from_429", []int{429, 200}, http.Header{"Retry-After": {"0"}}, 2, false},
+		{"exhausts_attempts", []int{500}, nil, 3, true},
+		{"no_retry_on_400", []int{400}, nil, 1, true},
+		{"retry_after_too_long", []int{429}, http.Header{"Retry-After": {"3600"}}, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStandIn(t, []byte("ok"), tt.header, tt.statuses...)
+			c := testClient(s.URL)
+			data, err := c.fetch(context.Background(), s.URL+"/example.com/lib/@v/list")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fetch = %q, %v; want error %v", data, err, tt.wantErr)
+			}
+			if got := s.requests.Load(); got != tt.wantRequests {
+				t.Errorf("got %d requests, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestFetch_NetworkErrorRetried(t *testing.T) {
+	s := newStandIn(t, nil, nil, http.StatusOK)
+	url := s.URL
+	s.Close()
+	c := testClient(url)
+	_, err := c.fetch(context.Background(), url+"/example.com/lib/@v/list")
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("fetch error = %v, want failure after 3 attempts", err)
+	}
+}
+
+func TestRetryPolicy_Wait(t *testing.T) {
+	p := retryPolicy{attempts: 5, base: 100 * time.Millisecond, max: time.Second}
+	for attempt := 0; attempt < 6; attempt++ {
+		d, ok := p.wait(attempt, 0)
+		full := min(p.base<<attempt, p.max)
+		if !ok || d < full/2 || d > full {
+			t.Errorf("wait(%d) = %s, %v; want within [%s, %s]", attempt, d, ok, full/2, full)
+		}
+	}
+	if d, ok := p.wait(0, 700*time.Millisecond); !ok || d != 700*time.Millisecond {
+		t.Errorf("wait with Retry-After = %s, %v; want 700ms", d, ok)
+	}
+	if _, ok := p.wait(0, 2*time.Second); ok {
+		t.Error("wait accepted a Retry-After above max")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("7"); got != 7*time.Second {
+		t.Errorf("parseRetryAfter(7) = %s", got)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got < 50*time.Second || got > time.Minute {
+		t.Errorf("parseRetryAfter(%q) = %s, want about a minute", date, got)
+	}
+	for _, h := range []string{"", "-3", "soon", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)} {
+		if got := parseRetryAfter(h); got != 0 {
+			t.Errorf("parseRetryAfter(%q) = %s, want 0", h, got)
+		}
+	}
+}
+
+// largeZip returns a module zip with incompressible content, so that a
+// truncated transfer leaves a partial file behind.
+func largeZip(t *testing.T) []byte {
+	t.Helper()
+	random := make([]byte, 256*1024)
+	rand.Read(random)
+	return moduleZip(t, "example.com/lib", "v1.0.0", map[string]string{"go.mod": "module example.com/lib\n", "data.bin": string(random)})
+}
+
+// interruptedProxy serves data for example.com/lib@v1.0.0, breaking off the
+// first response halfway through via interrupt. Later requests are answered
+// by serve. It records the Range header of every request.
+func interruptedProxy(t *testing.T, data []byte, interrupt func(w http.ResponseWriter, r *http.Request), serve http.HandlerFunc) (srv *httptest.Server, ranges func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var seen []string
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Get("Range"))
+		n := len(seen)
+		mu.Unlock()
+		if n == 1 {
+			w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+			w.Write(data[:len(data)/2])
+			w.(http.Flusher).Flush()
+			interrupt(w, r)
+			return
+		}
+		serve(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(seen)
+	}
+}
+
+func abortConnection(w http.ResponseWriter, r *http.Request) { panic(http.ErrAbortHandler) }
+
+func TestDownloadZipFile_Resume(t *testing.T) {
+	data := largeZip(t)
+	serveRange := func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "v1.0.0.zip", time.Time{}, bytes.NewReader(data))
+	}
+	serveFull := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		w.Write(data)
+	}
+	stall := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}
+
+	half := fmt.Sprintf("bytes=%d-", len(data)/2)
+	tests := []struct {
+		name       string
+		interrupt  func(w http.ResponseWriter, r *http.Request)
+		serve      http.HandlerFunc
+		wantRanges []string
+	}{
+		{"range_resume", abortConnection, serveRange, []string{"", half}},
+		{"range_ignored", abortConnection, serveFull, []string{"", half}},
+		{"stall_resume", stall, serveRange, []string{"", half}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, ranges := interruptedProxy(t, data, tt.interrupt, tt.serve)
+			var progress []Progress
+			c := NewClientWithOptions(ClientOptions{
+				Proxy: srv.URL, SumDB: "off", Private: &PrivatePatterns{},
+				Progress: func(p Progress) { progress = append(progress, p) },
+			})
+			c.retry = fastRetry
+			c.stall = 100 * time.Millisecond
+
+			f, cleanup, err := c.DownloadZipFile(context.Background(), "example.com/lib", "v1.0.0")
+			if err != nil {
+				t.Fatalf("DownloadZipFile: %v", err)
+			}
+			defer cleanup()
+			got, err := os.ReadFile(f.Name())
+			if err != nil || !bytes.Equal(got, data) {
+				t.Fatalf("downloaded %d bytes (%v), want the %d served", len(got), err, len(data))
+			}
+			if got := ranges(); !reflect.DeepEqual(got, tt.wantRanges) {
+				t.Errorf("Range headers = %q, want %q", got, tt.wantRanges)
+			}
+
+			last := progress[len(progress)-1]
+			if !last.Done || last.Downloaded != int64(len(data)) || last.Total != int64(len(data)) || last.Module != "example.com/lib" {
+				t.Errorf("last progress = %+v", last)
+			}
+		})
+	}
+}
+
+func TestDownloadZipFile_SizeLimit(t *testing.T) {
+	body := bytes.Repeat([]byte("x"), 1000)
+	for _, chunked := range []bool{false, true} {
+		s := newStandIn(t, nil, nil, http.StatusOK)
+		s.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s.requests.Add(1)
+			if chunked {
+				w.(http.Flusher).Flush()
+			}
+			w.Write(body)
+		})
+		c := testClient(s.URL)
+		c.maxZipSize = 100
+		_, _, err := c.DownloadZipFile(context.Background(), "example.com/lib", "v1.0.0")
+		if err == nil || !strings.Contains(err.Error(), "exceeds maximum size") {
+			t.Errorf("chunked=%v: error = %v, want size limit", chunked, err)
+		}
+		if n := s.requests.Load(); n != 1 {
+			t.Errorf("chunked=%v: %d requests, want no retries", chunked, n)
+		}
+	}
+}
+
+func TestDownloadZipFile_ContextDeadline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err := testClient(srv.URL).DownloadZipFile(ctx, "example.com/lib", "v1.0.0")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want context deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("download returned after %s, want the context deadline to stop it", elapsed)
+	}
+}
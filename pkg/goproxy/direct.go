@@ -0,0 +1,476 @@
+package goproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	modzip "golang.org/x/mod/zip"
+)
+
+// errNoGoMod reports that a revision has no go.mod in the requested directory.
+var errNoGoMod = errors.New("no go.mod")
+
+// downloadDirect writes the zip for mod@version, built from the module's git
+// repository, as the go command does for GOPROXY=direct. Release versions
+// resolve to tags (prefixed with the module's subdirectory for modules outside
+// the repository root); pseudo-versions resolve to the commit they embed.
+func (c *Client) downloadDirect(ctx context.Context, mod, version string, w io.Writer) error {
+	if !semver.IsValid(version) {
+		return fmt.Errorf("version %q is not a semantic version", version)
+	}
+	m, err := c.locateDirect(ctx, mod)
+	if err != nil {
+		return err
+	}
+	if err := module.CheckPathMajor(version, m.pathMajor); err != nil {
+		return err
+	}
+
+	dir, cleanup, err := newWorkRepo(ctx)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	rev, err := fetchRevision(ctx, dir, m.repo.URL, m.codeDir, version, m.insecure)
+	if err != nil {
+		return err
+	}
+	subdir, err := moduleSubdir(ctx, dir, rev, m.codeDir, m.pathMajor, mod, version)
+	if err != nil {
+		return err
+	}
+
+	return modzip.CreateFromVCS(w, module.Version{Path: mod, Version: version}, dir, rev, subdir)
+}
+
+// directModule is a module located in its repository for direct mode.
+type directModule struct {
+	repo      Repo
+	codeDir   string // module directory relative to the repository root
+	pathMajor string // major version suffix of path, e.g. "/v2"
+	insecure  bool   // GOINSECURE matches the module path
+}
+
+// locateDirect resolves the repository of mod and checks that its URL may be
+// used.
+func (c *Client) locateDirect(ctx context.Context, mod string) (directModule, error) {
+	prefix, pathMajor, ok := module.SplitPathVersion(mod)
+	if !ok {
+		return directModule{}, fmt.Errorf("invalid module path %s", mod)
+	}
+	repo, err := c.repos.ResolveRepo(ctx, mod)
+	if err != nil {
+		return directModule{}, fmt.Errorf("locating repository: %w", err)
+	}
+	if strings.HasPrefix(repo.URL, "-") {
+		return directModule{}, fmt.Errorf("invalid repository URL %q", repo.URL)
+	}
+	insecure := c.private.AllowInsecure(mod)
+	if isInsecureURL(repo.URL) && !insecure {
+		return directModule{}, fmt.Errorf("repository URL %s is insecure; add %s to GOINSECURE to allow it", repo.URL, mod)
+	}
+	codeDir, ok := repoSubdir(repo.Root, prefix)
+	if !ok {
+		return directModule{}, fmt.Errorf("repository root %s does not contain %s", repo.Root, mod)
+	}
+	return directModule{repo: repo, codeDir: codeDir, pathMajor: pathMajor, insecure: insecure}, nil
+}
+
+// newWorkRepo creates an empty git repository to fetch into.
+func newWorkRepo(ctx context.Context) (dir string, cleanup func(), err error) {
+	dir, err = os.MkdirTemp("", "emenda-direct-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("creating temp directory: %w", err)
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+	if _, err := runGit(ctx, dir, "init", "-q"); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return dir, cleanup, nil
+}
+
+// tagVersion returns the module version a tag names: the tag without the
+// module's directory prefix, when that is a canonical version valid for the
+// module's major version suffix.
+func (m directModule) tagVersion(tag string) (string, bool) {
+	v := tag
+	if m.codeDir != "" {
+		var ok bool
+		if v, ok = strings.CutPrefix(tag, m.codeDir+"/"); !ok {
+			return "", false
+		}
+	}
+	if !semver.IsValid(v) || semver.Canonical(v) != v || module.CheckPathMajor(v, m.pathMajor) != nil {
+		return "", false
+	}
+	return v, true
+}
+
+// versions returns the highest version first among tags that name versions of m.
+func (m directModule) versions(tags []string) []string {
+	var versions []string
+	for _, tag := range tags {
+		if v, ok := m.tagVersion(tag); ok {
+			versions = append(versions, v)
+		}
+	}
+	semver.Sort(versions)
+	slices.Reverse(versions)
+	return versions
+}
+
+// remoteTags lists the tags of the module's repository without fetching.
+func (m directModule) remoteTags(ctx context.Context) ([]string, error) {
+	args := []string{"ls-remote", "-q", "--tags", "--refs", "--", m.repo.URL}
+	if m.insecure {
+		args = append([]string{"-c", "http.sslVerify=false"}, args...)
+	}
+	out, err := runGit(ctx, "", args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing tags of %s: %w", m.repo.URL, err)
+	}
+	var tags []string
+	for _, line := range strings.Split(string(out), "\n") {
+		_, ref, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if tag, isTag := strings.CutPrefix(ref, "refs/tags/"); ok && isTag {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// listDirect serves @v/list from the module's repository tags.
+func (c *Client) listDirect(ctx context.Context, mod string) ([]byte, error) {
+	m, err := c.locateDirect(ctx, mod)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := m.remoteTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var list strings.Builder
+	for _, v := range m.versions(tags) {
+		list.WriteString(v + "\n")
+	}
+	return []byte(list.String()), nil
+}
+
+// latestDirect serves @latest: the highest release tag, else the highest
+// pre-release tag, else a pseudo-version for the default branch.
+func (c *Client) latestDirect(ctx context.Context, mod string) ([]byte, error) {
+	m, err := c.locateDirect(ctx, mod)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := m.remoteTags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions := m.versions(tags)
+	if v := highestVersion(versions, ""); v != "" {
+		return c.infoDirect(ctx, mod, v)
+	}
+	return c.infoDirect(ctx, mod, "HEAD")
+}
+
+// infoDirect serves @v/<rev>.info. A version resolves to its tag or embedded
+// commit; any other revision (a branch, tag or commit hash) resolves to the
+// version tagged on its commit, else to a pseudo-version based on the highest
+// version tagged on an ancestor.
+func (c *Client) infoDirect(ctx context.Context, mod, rev string) ([]byte, error) {
+	m, err := c.locateDirect(ctx, mod)
+	if err != nil {
+		return nil, err
+	}
+	dir, cleanup, err := newWorkRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	var commit, version string
+	if IsExactVersion(rev) {
+		if err := module.CheckPathMajor(rev, m.pathMajor); err != nil {
+			return nil, err
+		}
+		version = rev
+		if commit, err = fetchRevision(ctx, dir, m.repo.URL, m.codeDir, rev, m.insecure); err != nil {
+			return nil, err
+		}
+	} else {
+		if commit, err = fetchBranchOrCommit(ctx, dir, m.repo.URL, rev, m.insecure); err != nil {
+			return nil, err
+		}
+		if version, err = m.versionAt(ctx, dir, commit); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := moduleSubdir(ctx, dir, commit, m.codeDir, m.pathMajor, mod, version); err != nil {
+		return nil, err
+	}
+	t, err := revTime(ctx, dir, commit)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(RevInfo{Version: version, Time: t})
+}
+
+// goModDirect serves @v/<version>.mod from the module's repository. A version
+// without a go.mod gets the synthesized file the go command uses.
+func (c *Client) goModDirect(ctx context.Context, mod, version string) ([]byte, error) {
+	m, err := c.locateDirect(ctx, mod)
+	if err != nil {
+		return nil, err
+	}
+	if err := module.CheckPathMajor(version, m.pathMajor); err != nil {
+		return nil, err
+	}
+	dir, cleanup, err := newWorkRepo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	rev, err := fetchRevision(ctx, dir, m.repo.URL, m.codeDir, version, m.insecure)
+	if err != nil {
+		return nil, err
+	}
+	subdir, err := moduleSubdir(ctx, dir, rev, m.codeDir, m.pathMajor, mod, version)
+	if err != nil {
+		return nil, err
+	}
+	data, err := runGit(ctx, dir, "cat-file", "blob", rev+":"+path.Join(subdir, "go.mod"))
+	if err != nil {
+		return []byte("module " + modfile.AutoQuote(mod) + "\n"), nil
+	}
+	return data, nil
+}
+
+// fetchBranchOrCommit fetches all branches and tags from url and resolves rev,
+// trying it as a branch (or HEAD), a tag, then a commit hash.
+func fetchBranchOrCommit(ctx context.Context, dir, url, rev string, insecure bool) (string, error) {
+	fetch := []string{"fetch", "-q"}
+	if insecure {
+		fetch = []string{"-c", "http.sslVerify=false", "fetch", "-q"}
+	}
+	refspecs := []string{"+HEAD:refs/remotes/origin/HEAD", "+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*"}
+	if _, err := runGit(ctx, dir, append(append(fetch, "--", url), refspecs...)...); err != nil {
+		return "", fmt.Errorf("fetching %s: %w", url, err)
+	}
+	for _, name := range []string{"refs/remotes/origin/" + rev, "refs/tags/" + rev, rev} {
+		if out, err := runGit(ctx, dir, "rev-parse", "--verify", "--quiet", name+"^{commit}"); err == nil {
+			return strings.TrimSpace(string(out)), nil
+		}
+	}
+	return "", fmt.Errorf("unknown revision %s in %s", rev, url)
+}
+
+// versionAt returns the version for commit: the highest version tagged on it,
+// else a pseudo-version based on the highest version tagged on an ancestor.
+func (m directModule) versionAt(ctx context.Context, dir, commit string) (string, error) {
+	out, err := runGit(ctx, dir, "tag", "--points-at", commit)
+	if err != nil {
+		return "", err
+	}
+	if tagged := m.versions(strings.Fields(string(out))); len(tagged) > 0 {
+		return tagged[0], nil
+	}
+
+	out, err = runGit(ctx, dir, "tag", "--merged", commit)
+	if err != nil {
+		return "", err
+	}
+	var base string
+	if ancestors := m.versions(strings.Fields(string(out))); len(ancestors) > 0 {
+		base = ancestors[0]
+	}
+	t, err := revTime(ctx, dir, commit)
+	if err != nil {
+		return "", err
+	}
+	major := strings.TrimLeft(m.pathMajor, "/.")
+	return module.PseudoVersion(major, base, t, commit[:12]), nil
+}
+
+// repoSubdir returns the directory of the module path prefix (the module path
+// without its major version suffix) relative to the repository root.
+func repoSubdir(root, prefix string) (string, bool) {
+	if prefix == root {
+		return "", true
+	}
+	dir, ok := strings.CutPrefix(prefix, root+"/")
+	return dir, ok
+}
+
+// isInsecureURL reports whether a repository URL uses a transport without
+// authentication of the server.
+func isInsecureURL(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "git://")
+}
+
+// fetchRevision fetches the commit for version from url into the repository at
+// dir and returns its hash. Tags are fetched shallowly; a pseudo-version's
+// commit may not be reachable from any tag, so branches are fetched in full.
+// insecure disables TLS certificate verification.
+func fetchRevision(ctx context.Context, dir, url, codeDir, version string, insecure bool) (string, error) {
+	fetch := []string{"fetch", "-q"}
+	if insecure {
+		fetch = []string{"-c", "http.sslVerify=false", "fetch", "-q"}
+	}
+
+	if module.IsPseudoVersion(version) {
+		short, err := module.PseudoVersionRev(version)
+		if err != nil {
+			return "", err
+		}
+		if _, err := runGit(ctx, dir, append(fetch, "--", url, "+refs/heads/*:refs/remotes/origin/*", "+refs/tags/*:refs/tags/*")...); err != nil {
+			return "", fmt.Errorf("fetching %s: %w", url, err)
+		}
+		out, err := runGit(ctx, dir, "rev-parse", "--verify", "--quiet", short+"^{commit}")
+		if err != nil {
+			return "", fmt.Errorf("commit %s not found in %s", short, url)
+		}
+		rev := strings.TrimSpace(string(out))
+		if err := checkPseudoVersionTime(ctx, dir, rev, version); err != nil {
+			return "", err
+		}
+		return rev, nil
+	}
+
+	tag := strings.TrimSuffix(version, "+incompatible")
+	if codeDir != "" {
+		tag = codeDir + "/" + tag
+	}
+	ref := "refs/tags/" + tag
+	if _, err := runGit(ctx, dir, append(fetch, "--depth=1", "--", url, ref+":"+ref)...); err != nil {
+		return "", fmt.Errorf("fetching tag %s from %s: %w", tag, url, err)
+	}
+	out, err := runGit(ctx, dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("tag %s does not point to a commit", tag)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// checkPseudoVersionTime rejects a pseudo-version whose timestamp does not
+// match the commit time of rev.
+func checkPseudoVersionTime(ctx context.Context, dir, rev, version string) error {
+	want, err := module.PseudoVersionTime(version)
+	if err != nil {
+		return err
+	}
+	got, err := revTime(ctx, dir, rev)
+	if err != nil {
+		return err
+	}
+	if !got.Equal(want) {
+		return fmt.Errorf("pseudo-version %s does not match commit time %s", version, got.Format("20060102150405"))
+	}
+	return nil
+}
+
+// revTime returns the committer time of rev.
+func revTime(ctx context.Context, dir, rev string) (time.Time, error) {
+	out, err := runGit(ctx, dir, "show", "-s", "--format=%ct", rev)
+	if err != nil {
+		return time.Time{}, err
+	}
+	secs, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("reading commit time of %s: %w", rev, err)
+	}
+	return time.Unix(secs, 0).UTC(), nil
+}
+
+// moduleSubdir returns the directory holding mod's go.mod at rev. A /vN module
+// lives either in a vN subdirectory of codeDir or in codeDir itself on a major
+// version branch; the go.mod found must declare mod. Without a go.mod, only
+// v0, v1 and +incompatible versions are valid.
+func moduleSubdir(ctx context.Context, dir, rev, codeDir, pathMajor, mod, version string) (string, error) {
+	var candidates []string
+	if strings.HasPrefix(pathMajor, "/") {
+		candidates = append(candidates, path.Join(codeDir, pathMajor[1:]))
+	}
+	candidates = append(candidates, codeDir)
+
+	for _, subdir := range candidates {
+		declared, err := goModPath(ctx, dir, rev, subdir)
+		if errors.Is(err, errNoGoMod) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if declared != mod {
+			return "", fmt.Errorf("%s/go.mod declares module %s, not %s", displayDir(subdir), declared, mod)
+		}
+		if strings.HasSuffix(version, "+incompatible") {
+			return "", fmt.Errorf("version %s is +incompatible but the module has a go.mod", version)
+		}
+		return subdir, nil
+	}
+
+	if pathMajor != "" {
+		return "", fmt.Errorf("no go.mod for %s at %s", mod, version)
+	}
+	return codeDir, nil
+}
+
+// goModPath returns the module path declared by subdir/go.mod at rev.
+func goModPath(ctx context.Context, dir, rev, subdir string) (string, error) {
+	file := path.Join(subdir, "go.mod")
+	if _, err := runGit(ctx, dir, "cat-file", "-e", rev+":"+file); err != nil {
+		return "", errNoGoMod
+	}
+	data, err := runGit(ctx, dir, "cat-file", "blob", rev+":"+file)
+	if err != nil {
+		return "", err
+	}
+	declared := modfile.ModulePath(data)
+	if declared == "" {
+		return "", fmt.Errorf("%s has no module directive", file)
+	}
+	return declared, nil
+}
+
+// displayDir names a repository directory in messages.
+func displayDir(dir string) string {
+	if dir == "" {
+		return "."
+	}
+	return dir
+}
+
+// runGit runs a git command in dir and returns its standard output. Prompts for
+// credentials are disabled so an inaccessible repository fails instead of hanging.
+func runGit(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,265 @@
+package goproxy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/mod/module"
+)
+
+func TestParseGoImports(t *testing.T) {
+	page := `<html><head>
+<meta name="go-import" content="corp.example.com/lib git https://git.corp.example.com/lib.git">
+<meta content='corp.example.com/tools mod https://proxy.corp.example.com' name='go-import'/>
+<meta name="go-source" content="corp.example.com/lib _ _ _">
+<meta name="go-import" content="malformed">
+</head></html>`
+	want := []goImport{
+		{prefix: "corp.example.com/lib", vcs: "git", url: "https://git.corp.example.com/lib.git"},
+		{prefix: "corp.example.com/tools", vcs: "mod", url: "https://proxy.corp.example.com"},
+	}
+	if got := parseGoImports(page); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGoImports = %+v, want %+v", got, want)
+	}
+}
+
+func TestMatchGoImport(t *testing.T) {
+	imports := []goImport{
+		{prefix: "corp.example.com/lib", vcs: "git", url: "https://git.example.com/lib"},
+		{prefix: "corp.example.com/lib/big", vcs: "git", url: "https://git.example.com/big"},
+		{prefix: "corp.example.com/tools", vcs: "mod", url: "https://proxy.example.com"},
+	}
+	tests := []struct {
+		module  string
+		want    Repo
+		wantErr bool
+	}{
+		{"corp.example.com/lib", Repo{Root: "corp.example.com/lib", URL: "https://git.example.com/lib"}, false},
+		{"corp.example.com/lib/sub/v2", Repo{Root: "corp.example.com/lib", URL: "https://git.example.com/lib"}, false},
+		{"corp.example.com/lib/big/x", Repo{Root: "corp.example.com/lib/big", URL: "https://git.example.com/big"}, false},
+		{"corp.example.com/library", Repo{}, true},
+		{"corp.example.com/tools", Repo{}, true},
+	}
+	for _, tt := range tests {
+		got, err := matchGoImport(imports, tt.module, false)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("matchGoImport(%s) = %+v, %v; want %+v, error %v", tt.module, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	dup := append(imports[:1:1], goImport{prefix: "corp.example.com/lib", vcs: "git", url: "https://mirror.example.com/lib"})
+	if _, err := matchGoImport(dup, "corp.example.com/lib", false); err == nil {
+		t.Error("expected error for duplicate go-import entries")
+	}
+}
+
+func TestMatchGoImport_UnsafeURLs(t *testing.T) {
+	tests := []struct {
+		url      string
+		insecure bool
+		wantErr  bool
+	}{
+		{"https://git.example.com/lib", false, false},
+		{"ssh://git@git.example.com/lib", false, false},
+		{"git+ssh://git@git.example.com/lib", false, false},
+		{"--upload-pack=touch${IFS}/tmp/pwned;", false, true},
+		{"-oProxyCommand=id", true, true},
+		{"ssh://-oProxyCommand=id/lib", false, true},
+		{"file:///etc", false, true},
+		{"file:///etc", true, true},
+		{"ext::sh -c id", false, true},
+		{"/srv/git/lib", false, true},
+		{"http://git.example.com/lib", false, true},
+		{"http://git.example.com/lib", true, false},
+		{"git://git.example.com/lib", true, false},
+	}
+	for _, tt := range tests {
+		imports := []goImport{{prefix: "corp.example.com/lib", vcs: "git", url: tt.url}}
+		_, err := matchGoImport(imports, "corp.example.com/lib", tt.insecure)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("matchGoImport with URL %q (insecure %v) error = %v, want error %v", tt.url, tt.insecure, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLocateDirect_DashURL(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "pwned")
+	repos := RepoResolverFunc(func(ctx context.Context, modulePath string) (Repo, error) {
+		return Repo{Root: "example.com/lib", URL: "--upload-pack=touch " + marker + ";"}, nil
+	})
+	client := NewClientWithOptions(ClientOptions{Proxy: "direct", Repos: repos, SumDB: "off", Private: &PrivatePatterns{}})
+	if _, err := client.Versions(context.Background(), "example.com/lib"); err == nil {
+		t.Error("Versions succeeded with a dash-prefixed repository URL")
+	}
+	if _, err := client.DownloadZip(context.Background(), "example.com/lib", "v1.0.0"); err == nil {
+		t.Error("DownloadZip succeeded with a dash-prefixed repository URL")
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("git ran the command from the repository URL")
+	}
+}
+
+func TestGoImportResolver_KnownHosts(t *testing.T) {
+	r := &goImportResolver{}
+	got, err := r.ResolveRepo(context.Background(), "github.com/acme/sdk/storage/v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Repo{Root: "github.com/acme/sdk", URL: "https://github.com/acme/sdk"}); got != want {
+		t.Errorf("ResolveRepo = %+v, want %+v", got, want)
+	}
+	if _, err := r.ResolveRepo(context.Background(), "github.com/acme"); err == nil {
+		t.Error("expected error for a path without repository name")
+	}
+}
+
+// commitTime is the fixed author and committer time of test commits.
+var commitTime = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// gitRepo creates a repository for tests, skipping when git is unavailable.
+func gitRepo(t *testing.T) (dir string, run func(args ...string) string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir = t.TempDir()
+	date := commitTime.Format(time.RFC3339)
+	run = func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+			"GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date,
+			"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1",
+		)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	run("init", "-q")
+	return dir, run
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// zipNames returns the sorted file names in a module zip.
+func zipNames(t *testing.T, data []byte) []string {
+	t.Helper()
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("reading zip: %v", err)
+	}
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestDownloadZip_Direct(t *testing.T) {
+	dir, run := gitRepo(t)
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/lib\n")
+	writeFile(t, filepath.Join(dir, "lib.go"), "package lib\n")
+	writeFile(t, filepath.Join(dir, "LICENSE"), "license\n")
+	writeFile(t, filepath.Join(dir, "sub/go.mod"), "module example.com/lib/sub\n")
+	writeFile(t, filepath.Join(dir, "sub/sub.go"), "package sub\n")
+	writeFile(t, filepath.Join(dir, "v2/go.mod"), "module example.com/lib/v2\n")
+	writeFile(t, filepath.Join(dir, "v2/lib.go"), "package lib\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "initial")
+	run("tag", "v1.0.0")
+	run("tag", "sub/v1.1.0")
+	run("tag", "v2.0.0")
+
+	writeFile(t, filepath.Join(dir, "extra.go"), "package lib\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "untagged")
+	head := run("rev-parse", "HEAD")
+	pseudo := module.PseudoVersion("v1", "v1.0.0", commitTime, head[:12])
+
+	repos := RepoResolverFunc(func(ctx context.Context, modulePath string) (Repo, error) {
+		return Repo{Root: "example.com/lib", URL: "file://" + dir}, nil
+	})
+	client := NewClientWithOptions(ClientOptions{Proxy: "direct", Repos: repos, SumDB: "off"})
+
+	tests := []struct {
+		module, version string
+		want            []string
+	}{
+		{"example.com/lib", "v1.0.0", []string{"LICENSE", "go.mod", "lib.go"}},
+		{"example.com/lib/sub", "v1.1.0", []string{"LICENSE", "go.mod", "sub.go"}},
+		{"example.com/lib/v2", "v2.0.0", []string{"LICENSE", "go.mod", "lib.go"}},
+		{"example.com/lib", pseudo, []string{"LICENSE", "extra.go", "go.mod", "lib.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.module+"@"+tt.version, func(t *testing.T) {
+			data, err := client.DownloadZip(context.Background(), tt.module, tt.version)
+			if err != nil {
+				t.Fatalf("DownloadZip: %v", err)
+			}
+			var want []string
+			for _, name := range tt.want {
+				want = append(want, tt.module+"@"+tt.version+"/"+name)
+			}
+			if got := zipNames(t, data); !reflect.DeepEqual(got, want) {
+				t.Errorf("zip files = %q, want %q", got, want)
+			}
+		})
+	}
+
+	failures := []struct {
+		name, module, version string
+	}{
+		{"missing_tag", "example.com/lib", "v1.9.0"},
+		{"wrong_major", "example.com/lib", "v2.0.0"},
+		{"unknown_commit", "example.com/lib", "v1.0.1-0.20240301120000-0123456789ab"},
+		{"pseudo_time_mismatch", "example.com/lib", module.PseudoVersion("v1", "v1.0.0", commitTime.Add(time.Hour), head[:12])},
+		{"not_semver", "example.com/lib", "main"},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := client.DownloadZip(context.Background(), tt.module, tt.version); err == nil {
+				t.Errorf("DownloadZip(%s@%s) succeeded, want error", tt.module, tt.version)
+			}
+		})
+	}
+}
+
+func TestDownloadZip_DirectModulePathMismatch(t *testing.T) {
+	dir, run := gitRepo(t)
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/other\n")
+	writeFile(t, filepath.Join(dir, "lib.go"), "package lib\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "initial")
+	run("tag", "v1.0.0")
+
+	repos := RepoResolverFunc(func(ctx context.Context, modulePath string) (Repo, error) {
+		return Repo{Root: "example.com/lib", URL: "file://" + dir}, nil
+	})
+	client := NewClientWithOptions(ClientOptions{Proxy: "direct", Repos: repos, SumDB: "off"})
+	_, err := client.DownloadZip(context.Background(), "example.com/lib", "v1.0.0")
+	if err == nil || !strings.Contains(err.Error(), "declares module example.com/other") {
+		t.Errorf("DownloadZip error = %v, want module path mismatch", err)
+	}
+}
@@ -0,0 +1,89 @@
+package goproxy
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// isFileProxy reports whether a GOPROXY entry is a file:// URL. Such a proxy
+// is a directory laid out like a proxy's URL space (module/@v/list,
+// module/@v/<version>.info and so on), for example the cache/download
+// directory of a module cache.
+func isFileProxy(proxy string) bool {
+	return strings.HasPrefix(proxy, "file://")
+}
+
+// fileProxyPath returns the local path of name, a slash-separated path
+// relative to the file:// proxy.
+func fileProxyPath(proxy, name string) (string, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return "", fmt.Errorf("invalid file proxy %s: %w", proxy, err)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("file proxy %s: non-local host %q", proxy, u.Host)
+	}
+	dir := u.Path
+	if runtime.GOOS == "windows" {
+		// file:///C:/proxy names C:\proxy.
+		dir = strings.TrimPrefix(dir, "/")
+	}
+	if dir == "" {
+		return "", fmt.Errorf("file proxy %s: missing path", proxy)
+	}
+	return filepath.Join(filepath.FromSlash(dir), filepath.FromSlash(name)), nil
+}
+
+// readFileProxy reads name from a file:// proxy. A missing file fails with an
+// error matching fs.ErrNotExist, which falls through to the next entry like a
+// 404 response.
+func readFileProxy(proxy, name string) ([]byte, error) {
+	path, err := fileProxyPath(proxy, name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxMetadataSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxMetadataSize {
+		return nil, fmt.Errorf("%s exceeds maximum size of %d bytes", path, maxMetadataSize)
+	}
+	return data, nil
+}
+
+// copyFileProxy copies the zip name from a file:// proxy to f.
+func (c *Client) copyFileProxy(proxy, name string, f *os.File, report func(Progress)) error {
+	path, err := fileProxyPath(proxy, name)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > c.maxZipSize {
+		return fmt.Errorf("%s exceeds maximum size of %d bytes", path, c.maxZipSize)
+	}
+	w := &progressWriter{w: f, p: Progress{Total: info.Size()}, report: report}
+	report(w.p)
+	if _, err := io.Copy(w, io.LimitReader(src, c.maxZipSize+1)); err != nil {
+		return fmt.Errorf("copying %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package goproxy
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fileProxy lays out example.com/Lib versions in a directory in proxy layout
+// and returns its file:// URL.
+func fileProxy(t *testing.T, versions ...string) (url string, zips map[string][]byte) {
+	t.Helper()
+	dir := t.TempDir()
+	v := filepath.Join(dir, "example.com", "!lib", "@v")
+	zips = make(map[string][]byte)
+	for _, version := range versions {
+		gomod := "module example.com/Lib\n"
+		writeFile(t, filepath.Join(v, version+".info"), `{"Version":"`+version+`","Time":"2024-03-01T12:00:00Z"}`)
+		writeFile(t, filepath.Join(v, version+".mod"), gomod)
+		zips[version] = moduleZip(t, "example.com/Lib", version, map[string]string{"go.mod": gomod, "lib.go": "package lib\n"})
+		writeFile(t, filepath.Join(v, version+".zip"), string(zips[version]))
+	}
+	writeFile(t, filepath.Join(v, "list"), strings.Join(versions, "\n")+"\n")
+	return "file://" + filepath.ToSlash(dir), zips
+}
+
+func TestFileProxy(t *testing.T) {
+	proxy, zips := fileProxy(t, "v1.0.0", "v1.1.0")
+	var reports []Progress
+	c := NewClientWithOptions(ClientOptions{
+		Proxy:    proxy,
+		SumDB:    "off",
+		Private:  &PrivatePatterns{},
+		Progress: func(p Progress) { reports = append(reports, p) },
+	})
+	ctx := context.Background()
+
+	versions, err := c.Versions(ctx, "example.com/Lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"v1.0.0", "v1.1.0"}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("Versions = %q, want %q", versions, want)
+	}
+	info, err := c.Query(ctx, "example.com/Lib", "latest")
+	if err != nil || info.Version != "v1.1.0" {
+		t.Errorf("Query(latest) = %+v, %v; want v1.1.0", info, err)
+	}
+
+	data, err := c.DownloadZip(ctx, "example.com/Lib", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, zips["v1.0.0"]) {
+		t.Error("DownloadZip returned different bytes than the file proxy holds")
+	}
+	if last := reports[len(reports)-1]; !last.Done || last.Downloaded != int64(len(data)) || last.Total != int64(len(data)) {
+		t.Errorf("last progress = %+v, want done with %d bytes", last, len(data))
+	}
+
+	if _, err := c.DownloadZip(ctx, "example.com/Lib", "v2.0.0"); err == nil || !isNotFound(err) {
+		t.Errorf("DownloadZip(missing) error = %v, want not found", err)
+	}
+}
+
+func TestFileProxy_Fallback(t *testing.T) {
+	empty := "file://" + filepath.ToSlash(t.TempDir())
+	proxy, _ := fileProxy(t, "v1.0.0")
+
+	// A missing file moves past ",", like a 404.
+	c := testClient(empty + "," + proxy)
+	if _, err := c.Versions(context.Background(), "example.com/Lib"); err != nil {
+		t.Errorf("Versions through %s: %v", empty+","+proxy, err)
+	}
+
+	// Other failures stop at ",".
+	c = testClient("file://remote.example.com/proxy," + proxy)
+	if _, err := c.Versions(context.Background(), "example.com/Lib"); err == nil || !strings.Contains(err.Error(), "non-local host") {
+		t.Errorf("Versions error = %v, want non-local host", err)
+	}
+}
+
+func TestFileProxy_NoList(t *testing.T) {
+	// A module cache's cache/download directory has no @v/list files.
+	proxy, _ := fileProxy(t, "v1.0.0")
+	list, err := fileProxyPath(proxy, "example.com/!lib/@v/list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(list); err != nil {
+		t.Fatal(err)
+	}
+
+	c := testClient(proxy)
+	if info, err := c.Query(context.Background(), "example.com/Lib", "v1.0.0"); err != nil || info.Version != "v1.0.0" {
+		t.Errorf("Query(v1.0.0) = %+v, %v; want v1.0.0", info, err)
+	}
+	if _, err := c.Query(context.Background(), "example.com/Lib", "latest"); err == nil {
+		t.Error("Query(latest) succeeded without a version list")
+	}
+}
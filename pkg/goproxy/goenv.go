@@ -0,0 +1,54 @@
+package goproxy
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+)
+
+// GoEnv returns a getenv for go command settings. As in the go command, a
+// non-empty environment variable wins; otherwise the value comes from the
+// GOENV file written by 'go env -w', by default go/env in the user config
+// directory. GOENV=off disables the file. Callers normally pass os.Getenv and
+// os.UserConfigDir.
+func GoEnv(getenv func(string) string, configDir func() (string, error)) func(string) string {
+	file := getenv("GOENV")
+	if file == "" {
+		if dir, err := configDir(); err == nil && dir != "" {
+			file = filepath.Join(dir, "go", "env")
+		}
+	}
+
+	var values map[string]string
+	if file != "" && file != "off" {
+		// A missing or unreadable file leaves only the environment, as with go env.
+		if data, err := os.ReadFile(file); err == nil {
+			values = parseGoEnvFile(data)
+		}
+	}
+
+	return func(key string) string {
+		if v := getenv(key); v != "" {
+			return v
+		}
+		return values[key]
+	}
+}
+
+// parseGoEnvFile parses the KEY=VALUE lines of a GOENV file. Lines that do not
+// start with an uppercase letter are ignored, and the first setting of a key
+// wins, following the go command's reader.
+func parseGoEnvFile(data []byte) map[string]string {
+	values := make(map[string]string)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		key, value, ok := bytes.Cut(line, []byte("="))
+		if !ok || len(key) == 0 || key[0] < 'A' || key[0] > 'Z' {
+			continue
+		}
+		if _, dup := values[string(key)]; !dup {
+			values[string(key)] = string(value)
+		}
+	}
+	return values
+}
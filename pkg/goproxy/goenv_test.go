@@ -0,0 +1,52 @@
+package goproxy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGoEnv(t *testing.T) {
+	configDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(configDir, "go"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	defaultFile := "GOPRIVATE=corp.example.com\r\nGOPROXY=https://proxy.corp\n# comment\nlower=x\nGOPROXY=https://other\nGOSUMDB=\n"
+	if err := os.WriteFile(filepath.Join(configDir, "go", "env"), []byte(defaultFile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	custom := filepath.Join(t.TempDir(), "env")
+	if err := os.WriteFile(custom, []byte("GOPRIVATE=custom.example.com\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		env       map[string]string
+		configErr error
+		key       string
+		want      string
+	}{
+		{"default_file", nil, nil, "GOPRIVATE", "corp.example.com"},
+		{"first_setting_wins", nil, nil, "GOPROXY", "https://proxy.corp"},
+		{"not_uppercase", nil, nil, "lower", ""},
+		{"environment_wins", map[string]string{"GOPRIVATE": "env.example.com"}, nil, "GOPRIVATE", "env.example.com"},
+		{"empty_environment_falls_back", map[string]string{"GOPRIVATE": ""}, nil, "GOPRIVATE", "corp.example.com"},
+		{"goenv_file", map[string]string{"GOENV": custom}, nil, "GOPRIVATE", "custom.example.com"},
+		{"goenv_off", map[string]string{"GOENV": "off"}, nil, "GOPRIVATE", ""},
+		{"goenv_missing", map[string]string{"GOENV": filepath.Join(configDir, "missing")}, nil, "GOPRIVATE", ""},
+		{"no_config_dir", nil, errors.New("no config dir"), "GOPRIVATE", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := GoEnv(
+				func(key string) string { return tt.env[key] },
+				func() (string, error) { return configDir, tt.configErr },
+			)
+			if got := getenv(tt.key); got != tt.want {
+				t.Errorf("getenv(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package goproxy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// GoSum maps module versions to the h1: hashes recorded in a go.sum file. Zip
+// hashes are keyed by the version, go.mod hashes by the version with a
+// "/go.mod" suffix, as in go.sum itself.
+type GoSum map[module.Version][]string
+
+// ParseGoSum parses the content of a go.sum file.
+func ParseGoSum(data []byte) (GoSum, error) {
+	sums := make(GoSum)
+	for i, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("go.sum line %d: malformed entry %q", i+1, line)
+		}
+		m := module.Version{Path: fields[0], Version: fields[1]}
+		sums[m] = append(sums[m], fields[2])
+	}
+	return sums, nil
+}
+
+// ReadGoSum reads and parses the go.sum file at path. A missing file yields an
+// empty GoSum.
+func ReadGoSum(path string) (GoSum, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return GoSum{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	sums, err := ParseGoSum(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return sums, nil
+}
+
+// Check reports a *ChecksumError when s lists mod@version and hash is not
+// among its hashes. Versions s does not list pass.
+func (s GoSum) Check(mod, version, hash string) error {
+	want, ok := s[module.Version{Path: mod, Version: version}]
+	if !ok || slices.Contains(want, hash) {
+		return nil
+	}
+	return &ChecksumError{Module: mod, Version: version, Got: hash, Want: want[0], Source: "go.sum"}
+}
+
+// CheckGoMod reports a *ChecksumError when s lists the go.mod of mod@version
+// and data, the go.mod content, does not match any of its hashes.
+func (s GoSum) CheckGoMod(mod, version string, data []byte) error {
+	hash, err := hashGoMod(data)
+	if err != nil {
+		return fmt.Errorf("hashing go.mod of %s@%s: %w", mod, version, err)
+	}
+	return s.Check(mod, version+"/go.mod", hash)
+}
+
+// Lists reports whether s records a zip hash for mod@version, or a go.mod hash
+// when version ends in "/go.mod".
+func (s GoSum) Lists(mod, version string) bool {
+	_, ok := s[module.Version{Path: mod, Version: version}]
+	return ok
+}
+
+// ChecksumError reports a module zip whose hash does not match the recorded
+// one. It is a security failure: the download must not be used.
+type ChecksumError struct {
+	Module  string
+	Version string
+	Got     string
+	Want    string
+	// Source names where Want came from: "go.sum" or the checksum database.
+	Source string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("verifying %s@%s: checksum mismatch\n\tgot:  %s\n\t%s: %s\nSECURITY ERROR: the module source does not match the recorded checksum", e.Module, e.Version, e.Got, e.Source, e.Want)
+}
+
+// hashZip computes the h1: dirhash of a module zip of the given size read from
+// r, like dirhash.HashZip.
+func hashZip(r io.ReaderAt, size int64) (string, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", err
+	}
+	var files []string
+	byName := make(map[string]*zip.File)
+	for _, f := range z.File {
+		files = append(files, f.Name)
+		byName[f.Name] = f
+	}
+	return dirhash.Hash1(files, func(name string) (io.ReadCloser, error) {
+		f := byName[name]
+		if f == nil {
+			return nil, fmt.Errorf("file %q not found in zip", name)
+		}
+		return f.Open()
+	})
+}
+
+// hashGoMod computes the h1: hash of a go.mod file as recorded in go.sum
+// "/go.mod" lines.
+func hashGoMod(data []byte) (string, error) {
+	return dirhash.Hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	})
+}
+
+// verifyZip checks the hash of a downloaded zip against GoSum when it lists the
+// version, and otherwise against the checksum database unless mod matches
+// GONOSUMDB or the database is off.
+func (c *Client) verifyZip(ctx context.Context, mod, version string, r io.ReaderAt, size int64) error {
+	got, err := hashZip(r, size)
+	if err != nil {
+		return fmt.Errorf("hashing zip for %s@%s: %w", mod, version, err)
+	}
+	return c.verifyHash(ctx, mod, version, got)
+}
+
+// verifyGoMod checks a downloaded go.mod file like verifyZip checks zips.
+func (c *Client) verifyGoMod(ctx context.Context, mod, version string, data []byte) error {
+	got, err := hashGoMod(data)
+	if err != nil {
+		return fmt.Errorf("hashing go.mod of %s@%s: %w", mod, version, err)
+	}
+	return c.verifyHash(ctx, mod, version+"/go.mod", got)
+}
+
+// verifyHash checks got, the hash of mod@version, against GoSum or the
+// checksum database. version ends in "/go.mod" for go.mod hashes.
+func (c *Client) verifyHash(ctx context.Context, mod, version, got string) error {
+	if c.goSum.Lists(mod, version) {
+		return c.goSum.Check(mod, version, got)
+	}
+
+	if c.private.SkipSumDB(mod) {
+		return nil
+	}
+	if c.sumdbErr != nil {
+		return fmt.Errorf("verifying %s@%s: %w", mod, version, c.sumdbErr)
+	}
+	if c.sumdb == nil {
+		return nil
+	}
+	want, err := c.sumdb.lookup(ctx, mod, version)
+	if err != nil {
+		return fmt.Errorf("verifying %s@%s: %w", mod, version, err)
+	}
+	if got != want {
+		return &ChecksumError{Module: mod, Version: version, Got: got, Want: want, Source: c.sumdb.name}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package goproxy
+
+import (
+	"os"
+
+	"golang.org/x/mod/module"
+)
+
+// PrivatePatterns holds the comma-separated glob lists that exempt modules from
+// the proxy chain and the checksum database, with the go command's matching
+// rules: a pattern matches any module path whose leading path elements match it
+// (see 'go help module-private').
+type PrivatePatterns struct {
+	// NoProxy lists modules fetched directly from their repositories even when
+	// GOPROXY names proxies (GONOPROXY, defaulting to GOPRIVATE). Setting it to
+	// "none" sends private modules through the proxy chain, e.g. an internal
+	// proxy listed ahead of the public one.
+	NoProxy string
+
+	// NoSumDB lists modules not verified against the checksum database
+	// (GONOSUMDB, defaulting to GOPRIVATE).
+	NoSumDB string
+
+	// Insecure lists modules that may be fetched directly over plain HTTP or
+	// without certificate verification (GOINSECURE).
+	Insecure string
+}
+
+// PrivatePatternsFromEnv reads GOPRIVATE, GONOPROXY, GONOSUMDB and GOINSECURE
+// from the environment, falling back to the settings written by 'go env -w'.
+func PrivatePatternsFromEnv() PrivatePatterns {
+	return privatePatterns(GoEnv(os.Getenv, os.UserConfigDir))
+}
+
+// privatePatterns builds PrivatePatterns from getenv. An empty GONOPROXY or
+// GONOSUMDB falls back to GOPRIVATE, as in the go command.
+func privatePatterns(getenv func(string) string) PrivatePatterns {
+	private := getenv("GOPRIVATE")
+	p := PrivatePatterns{
+		NoProxy:  getenv("GONOPROXY"),
+		NoSumDB:  getenv("GONOSUMDB"),
+		Insecure: getenv("GOINSECURE"),
+	}
+	if p.NoProxy == "" {
+		p.NoProxy = private
+	}
+	if p.NoSumDB == "" {
+		p.NoSumDB = private
+	}
+	return p
+}
+
+// BypassProxy reports whether mod must be fetched directly.
+func (p PrivatePatterns) BypassProxy(mod string) bool {
+	return module.MatchPrefixPatterns(p.NoProxy, mod)
+}
+
+// SkipSumDB reports whether mod is exempt from checksum database verification.
+func (p PrivatePatterns) SkipSumDB(mod string) bool {
+	return module.MatchPrefixPatterns(p.NoSumDB, mod)
+}
+
+// AllowInsecure reports whether mod may be fetched over insecure transports.
+func (p PrivatePatterns) AllowInsecure(mod string) bool {
+	return module.MatchPrefixPatterns(p.Insecure, mod)
+}
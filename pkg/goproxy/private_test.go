@@ -0,0 +1,130 @@
+package goproxy
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPrivatePatternsFromEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want PrivatePatterns
+	}{
+		{"unset", nil, PrivatePatterns{}},
+		{"goprivate_defaults", map[string]string{"GOPRIVATE": "corp.example.com"},
+			PrivatePatterns{NoProxy: "corp.example.com", NoSumDB: "corp.example.com"}},
+		{"explicit_overrides", map[string]string{"GOPRIVATE": "corp.example.com", "GONOPROXY": "none", "GONOSUMDB": "corp.example.com,*.internal"},
+			PrivatePatterns{NoProxy: "none", NoSumDB: "corp.example.com,*.internal"}},
+		{"insecure", map[string]string{"GOINSECURE": "git.lab"}, PrivatePatterns{Insecure: "git.lab"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := privatePatterns(func(key string) string { return tt.env[key] })
+			if got != tt.want {
+				t.Errorf("privatePatterns = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPrivatePatterns_Match mirrors the examples of 'go help module-private'
+// and the go command's prefix matching rules.
+func TestPrivatePatterns_Match(t *testing.T) {
+	tests := []struct {
+		globs  string
+		module string
+		want   bool
+	}{
+		{"corp.example.com", "corp.example.com", true},
+		{"corp.example.com", "corp.example.com/lib/v2", true},
+		{"corp.example.com", "corp.example.community/lib", false},
+		{"*.corp.example.com", "git.corp.example.com/team/lib", true},
+		{"*.corp.example.com", "corp.example.com/lib", false},
+		{"rsc.io/private", "rsc.io/private/quux", true},
+		{"rsc.io/private", "rsc.io/privateer", false},
+		{"rsc.io/private", "rsc.io", false},
+		{"github.com/acme/*", "github.com/acme/tools/cmd", true},
+		{"github.com/acme/*", "github.com/other/tools", false},
+		{"*/quote", "rsc.io/quote/v3", true},
+		{"corp.example.com/", "corp.example.com/lib", true},
+		{",,corp.example.com,", "corp.example.com/lib", true},
+		{"other.example.com,corp.example.com", "corp.example.com/lib", true},
+		{"[", "corp.example.com/lib", false},
+		{"none", "corp.example.com/lib", false},
+		{"", "corp.example.com/lib", false},
+	}
+	for _, tt := range tests {
+		p := PrivatePatterns{NoProxy: tt.globs, NoSumDB: tt.globs, Insecure: tt.globs}
+		if got := p.BypassProxy(tt.module); got != tt.want {
+			t.Errorf("BypassProxy(%q) with %q = %v, want %v", tt.module, tt.globs, got, tt.want)
+		}
+		if got := p.SkipSumDB(tt.module); got != tt.want {
+			t.Errorf("SkipSumDB(%q) with %q = %v, want %v", tt.module, tt.globs, got, tt.want)
+		}
+		if got := p.AllowInsecure(tt.module); got != tt.want {
+			t.Errorf("AllowInsecure(%q) with %q = %v, want %v", tt.module, tt.globs, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadZip_PrivateModulesBypassProxy(t *testing.T) {
+	var requests atomic.Int32
+	publicZip := moduleZip(t, "example.com/public", "v1.0.0", map[string]string{"go.mod": "module example.com/public\n"})
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write(publicZip)
+	}))
+	defer proxy.Close()
+
+	dir, run := gitRepo(t)
+	writeFile(t, filepath.Join(dir, "go.mod"), "module corp.example.com/lib\n")
+	writeFile(t, filepath.Join(dir, "lib.go"), "package lib\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "initial")
+	run("tag", "v1.0.0")
+
+	repos := RepoResolverFunc(func(ctx context.Context, modulePath string) (Repo, error) {
+		return Repo{Root: "corp.example.com/lib", URL: "file://" + dir}, nil
+	})
+	private := &PrivatePatterns{NoProxy: "corp.example.com", NoSumDB: "corp.example.com"}
+	client := NewClientWithOptions(ClientOptions{Proxy: proxy.URL + ",direct", Repos: repos, Private: private, SumDB: "off"})
+
+	data, err := client.DownloadZip(context.Background(), "corp.example.com/lib", "v1.0.0")
+	if err != nil {
+		t.Fatalf("DownloadZip private: %v", err)
+	}
+	if n := requests.Load(); n != 0 {
+		t.Errorf("proxy received %d requests for a private module", n)
+	}
+	if names := zipNames(t, data); len(names) != 2 {
+		t.Errorf("private zip files = %q", names)
+	}
+
+	data, err = client.DownloadZip(context.Background(), "example.com/public", "v1.0.0")
+	if err != nil || !bytes.Equal(data, publicZip) || requests.Load() != 1 {
+		t.Errorf("DownloadZip public = %q, %v after %d requests; want proxy response", data, err, requests.Load())
+	}
+
+	off := NewClientWithOptions(ClientOptions{Proxy: "off", Repos: repos, Private: private})
+	if _, err := off.DownloadZip(context.Background(), "corp.example.com/lib", "v1.0.0"); err == nil {
+		t.Error("expected GOPROXY=off to disable fetching private modules")
+	}
+}
+
+func TestDownloadZip_DirectInsecureURL(t *testing.T) {
+	repos := RepoResolverFunc(func(ctx context.Context, modulePath string) (Repo, error) {
+		return Repo{Root: "corp.example.com/lib", URL: "http://git.corp.example.com/lib"}, nil
+	})
+	client := NewClientWithOptions(ClientOptions{Proxy: "direct", Repos: repos, Private: &PrivatePatterns{}})
+	_, err := client.DownloadZip(context.Background(), "corp.example.com/lib", "v1.0.0")
+	if err == nil || !strings.Contains(err.Error(), "GOINSECURE") {
+		t.Errorf("DownloadZip error = %v, want insecure URL rejection", err)
+	}
+}
@@ -0,0 +1,256 @@
+package goproxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// RevInfo describes a module version, as served by the proxy's .info and
+// @latest endpoints.
+type RevInfo struct {
+	Version string
+	Time    time.Time
+}
+
+// RetractedError reports a version retracted by the module's author.
+type RetractedError struct {
+	Module    string
+	Version   string
+	Rationale string
+}
+
+func (e *RetractedError) Error() string {
+	if e.Rationale == "" {
+		return fmt.Sprintf("%s@%s has been retracted by the module author", e.Module, e.Version)
+	}
+	return fmt.Sprintf("%s@%s has been retracted by the module author: %s", e.Module, e.Version, e.Rationale)
+}
+
+// Versions returns the tagged versions of mod in semver order (@v/list).
+// Pseudo-versions are not listed.
+func (c *Client) Versions(ctx context.Context, mod string) ([]string, error) {
+	data, err := c.get(ctx, mod, mod+"@v/list", "@v/list", func() ([]byte, error) {
+		return c.listDirect(ctx, mod)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, v := range strings.Fields(string(data)) {
+		if semver.IsValid(v) {
+			versions = append(versions, v)
+		}
+	}
+	semver.Sort(versions)
+	return versions, nil
+}
+
+// Latest returns the version the proxy reports as latest (@latest). Proxies
+// typically serve it only for modules without tagged versions.
+func (c *Client) Latest(ctx context.Context, mod string) (RevInfo, error) {
+	data, err := c.get(ctx, mod, mod+"@latest", "@latest", func() ([]byte, error) {
+		return c.latestDirect(ctx, mod)
+	})
+	if err != nil {
+		return RevInfo{}, err
+	}
+	return parseRevInfo(mod, "latest", data)
+}
+
+// Info resolves rev, which may be a version, branch, tag or commit hash, to the
+// module version it names (@v/<rev>.info). Revisions that are not tagged
+// versions resolve to pseudo-versions. Results for exact versions are cached
+// for the client's lifetime; branches and other revisions may move.
+func (c *Client) Info(ctx context.Context, mod, rev string) (RevInfo, error) {
+	escapedRev, err := module.EscapeVersion(rev)
+	if err != nil {
+		return RevInfo{}, fmt.Errorf("escaping revision %q: %w", rev, err)
+	}
+	get := c.get
+	if IsExactVersion(rev) {
+		get = c.getCached
+	}
+	data, err := get(ctx, mod, mod+"@"+rev, "@v/"+escapedRev+".info", func() ([]byte, error) {
+		return c.infoDirect(ctx, mod, rev)
+	})
+	if err != nil {
+		return RevInfo{}, err
+	}
+	return parseRevInfo(mod, rev, data)
+}
+
+// GoMod returns the go.mod file of mod@version (@v/<version>.mod) without
+// downloading the module zip. For versions without a go.mod, proxies serve a
+// synthesized one holding only the module directive. The file is verified like
+// zips: against its "/go.mod" line in GoSum when listed, otherwise against the
+// checksum database. Results are cached for the client's lifetime.
+func (c *Client) GoMod(ctx context.Context, mod, version string) ([]byte, error) {
+	if !IsExactVersion(version) {
+		return nil, fmt.Errorf("%s@%s: go.mod needs an exact version", mod, version)
+	}
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return nil, fmt.Errorf("escaping version %q: %w", version, err)
+	}
+	data, err := c.getCached(ctx, mod, mod+"@"+version, "@v/"+escapedVersion+".mod", func() ([]byte, error) {
+		return c.goModDirect(ctx, mod, version)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.verifyGoMod(ctx, mod, version, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func parseRevInfo(mod, rev string, data []byte) (RevInfo, error) {
+	var info RevInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return RevInfo{}, fmt.Errorf("parsing info for %s@%s: %w", mod, rev, err)
+	}
+	if !semver.IsValid(info.Version) {
+		return RevInfo{}, fmt.Errorf("info for %s@%s has invalid version %q", mod, rev, info.Version)
+	}
+	return info, nil
+}
+
+// Query resolves a version query for mod like the go command does:
+//
+//   - "latest": the highest release, else the highest pre-release, else the
+//     proxy's @latest (a pseudo-version for untagged modules)
+//   - a major or minor prefix such as "v1" or "v1.4": the highest matching
+//     release, else pre-release
+//   - an exact version, branch, tag or commit hash, resolved through Info
+//
+// Retracted versions are skipped when choosing among versions and rejected with
+// a *RetractedError when named directly. Retractions are read from the go.mod
+// of the module's latest version. Exact versions resolve without retraction
+// checks when the proxy has no version list, as in a module cache's
+// cache/download directory used as a file:// proxy. With GOPROXY=off they
+// resolve to themselves without any lookup, so their source can still come from
+// a module cache.
+func (c *Client) Query(ctx context.Context, mod, query string) (RevInfo, error) {
+	if IsExactVersion(query) && c.lookupDisabled(mod) {
+		return RevInfo{Version: query}, nil
+	}
+	versions, err := c.Versions(ctx, mod)
+	if err != nil {
+		if isNotFound(err) && IsExactVersion(query) {
+			return c.Info(ctx, mod, query)
+		}
+		return RevInfo{}, fmt.Errorf("listing versions of %s: %w", mod, err)
+	}
+
+	var latest *RevInfo
+	latestVersion := highestVersion(versions, "")
+	if latestVersion == "" {
+		info, err := c.Latest(ctx, mod)
+		if err != nil {
+			return RevInfo{}, fmt.Errorf("resolving latest version of %s: %w", mod, err)
+		}
+		latest, latestVersion = &info, info.Version
+	}
+	retractions, err := c.retractions(ctx, mod, latestVersion)
+	if err != nil {
+		return RevInfo{}, err
+	}
+	var allowed []string
+	for _, v := range versions {
+		if retractedBy(retractions, v) == nil {
+			allowed = append(allowed, v)
+		}
+	}
+
+	var info RevInfo
+	switch {
+	case query == "latest" || isVersionPrefix(query):
+		prefix := ""
+		if query != "latest" {
+			prefix = query
+		}
+		v := highestVersion(allowed, prefix)
+		switch {
+		case v != "":
+			info, err = c.Info(ctx, mod, v)
+		case latest != nil && prefix == "":
+			info = *latest
+		default:
+			return RevInfo{}, fmt.Errorf("no matching versions for %s@%s", mod, query)
+		}
+	default:
+		info, err = c.Info(ctx, mod, query)
+	}
+	if err != nil {
+		return RevInfo{}, err
+	}
+
+	if r := retractedBy(retractions, info.Version); r != nil {
+		return RevInfo{}, &RetractedError{Module: mod, Version: info.Version, Rationale: r.Rationale}
+	}
+	return info, nil
+}
+
+// retractions returns the retract directives in the go.mod of mod@latest.
+func (c *Client) retractions(ctx context.Context, mod, latest string) ([]*modfile.Retract, error) {
+	data, err := c.GoMod(ctx, mod, latest)
+	if err != nil {
+		return nil, fmt.Errorf("loading retractions of %s: %w", mod, err)
+	}
+	f, err := modfile.ParseLax("go.mod", data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("parsing go.mod of %s@%s: %w", mod, latest, err)
+	}
+	return f.Retract, nil
+}
+
+// retractedBy returns the directive retracting version, or nil.
+func retractedBy(retractions []*modfile.Retract, version string) *modfile.Retract {
+	for _, r := range retractions {
+		if semver.Compare(version, r.Low) >= 0 && semver.Compare(version, r.High) <= 0 {
+			return r
+		}
+	}
+	return nil
+}
+
+// highestVersion returns the highest release among versions matching prefix
+// (e.g. "v1" or "v1.4"; empty matches all), else the highest pre-release.
+func highestVersion(versions []string, prefix string) string {
+	var release, prerelease string
+	for _, v := range versions {
+		if prefix != "" && !strings.HasPrefix(v, prefix+".") {
+			continue
+		}
+		if semver.Prerelease(v) == "" {
+			if semver.Compare(v, release) > 0 {
+				release = v
+			}
+		} else if semver.Compare(v, prerelease) > 0 {
+			prerelease = v
+		}
+	}
+	if release != "" {
+		return release
+	}
+	return prerelease
+}
+
+// IsExactVersion reports whether v is a complete canonical version, possibly
+// +incompatible, as opposed to a query such as "latest", "v1" or a branch.
+func IsExactVersion(v string) bool {
+	return semver.IsValid(v) && semver.Canonical(v) == strings.TrimSuffix(v, "+incompatible")
+}
+
+// isVersionPrefix reports whether q is a major or minor version prefix such as
+// "v1" or "v1.4".
+func isVersionPrefix(q string) bool {
+	return semver.IsValid(q) && (q == semver.Major(q) || q == semver.MajorMinor(q)) && q != semver.Canonical(q)
+}
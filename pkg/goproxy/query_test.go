@@ -0,0 +1,261 @@
+package goproxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/module"
+)
+
+// queryProxy serves @v/list, @latest, .info and .mod for example.com/lib.
+func queryProxy(t *testing.T, list []string, latestMod string) *httptest.Server {
+	t.Helper()
+	const pseudo = "v1.4.2-0.20240301120000-0123456789ab"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, "/example.com/lib/")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		switch {
+		case rest == "@v/list":
+			w.Write([]byte(strings.Join(list, "\n")))
+		case rest == "@latest" && len(list) == 0:
+			w.Write([]byte(`{"Version":"v0.0.0-20240301120000-0123456789ab","Time":"2024-03-01T12:00:00Z"}`))
+		case strings.HasSuffix(rest, ".mod"):
+			w.Write([]byte(latestMod))
+		case strings.HasSuffix(rest, ".info"):
+			rev := strings.TrimSuffix(strings.TrimPrefix(rest, "@v/"), ".info")
+			version := rev
+			if rev == "main" {
+				version = pseudo
+			}
+			if rev != "main" && !strings.Contains(strings.Join(list, " ")+" ", rev+" ") {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte(`{"Version":"` + version + `","Time":"2024-03-01T12:00:00Z"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestQuery(t *testing.T) {
+	list := []string{"v1.0.0", "v1.1.0", "v1.2.0", "v1.3.0-rc.1", "v1.4.0", "v1.4.1"}
+	latestMod := "module example.com/lib\n\nretract v1.4.1 // broken release\n\nretract [v1.1.0, v1.2.0] // data race\n"
+	srv := queryProxy(t, list, latestMod)
+	client := NewClientWithOptions(ClientOptions{Proxy: srv.URL, SumDB: "off", Private: &PrivatePatterns{}})
+
+	tests := []struct {
+		query         string
+		want          string
+		wantErr       bool
+		wantRetracted bool
+	}{
+		{query: "latest", want: "v1.4.0"},
+		{query: "v1", want: "v1.4.0"},
+		{query: "v1.4", want: "v1.4.0"},
+		{query: "v1.3", want: "v1.3.0-rc.1"},
+		{query: "v1.0.0", want: "v1.0.0"},
+		{query: "main", want: "v1.4.2-0.20240301120000-0123456789ab"},
+		{query: "v1.1", wantErr: true},
+		{query: "v2", wantErr: true},
+		{query: "v1.2.0", wantErr: true, wantRetracted: true},
+		{query: "v1.4.1", wantErr: true, wantRetracted: true},
+		{query: "v1.9.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			info, err := client.Query(context.Background(), "example.com/lib", tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Query(%q) = %+v, %v; want error %v", tt.query, info, err, tt.wantErr)
+			}
+			var retracted *RetractedError
+			if got := errors.As(err, &retracted); got != tt.wantRetracted {
+				t.Errorf("Query(%q) error %v is RetractedError = %v, want %v", tt.query, err, got, tt.wantRetracted)
+			}
+			if err == nil && info.Version != tt.want {
+				t.Errorf("Query(%q) = %s, want %s", tt.query, info.Version, tt.want)
+			}
+		})
+	}
+
+	var retracted *RetractedError
+	if _, err := client.Query(context.Background(), "example.com/lib", "v1.2.0"); errors.As(err, &retracted) && retracted.Rationale != "data race" {
+		t.Errorf("Rationale = %q, want %q", retracted.Rationale, "data race")
+	}
+}
+
+func TestQuery_Untagged(t *testing.T) {
+	srv := queryProxy(t, nil, "module example.com/lib\n")
+	client := NewClientWithOptions(ClientOptions{Proxy: srv.URL, SumDB: "off", Private: &PrivatePatterns{}})
+	info, err := client.Query(context.Background(), "example.com/lib", "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "v0.0.0-20240301120000-0123456789ab"; info.Version != want || !info.Time.Equal(commitTime) {
+		t.Errorf("Query(latest) = %+v, want %s at %s", info, want, commitTime)
+	}
+}
+
+func TestQuery_ProxyOff(t *testing.T) {
+	c := testClient("off")
+	ctx := context.Background()
+	info, err := c.Query(ctx, "example.com/lib", "v1.2.3")
+	if err != nil || info.Version != "v1.2.3" {
+		t.Errorf("Query(v1.2.3) = %+v, %v; want v1.2.3", info, err)
+	}
+	for _, q := range []string{"latest", "v1", "main"} {
+		if _, err := c.Query(ctx, "example.com/lib", q); !errors.Is(err, errProxyOff) {
+			t.Errorf("Query(%s) error = %v, want GOPROXY=off failure", q, err)
+		}
+	}
+}
+
+func TestIsVersionPrefix(t *testing.T) {
+	for q, want := range map[string]bool{
+		"v1": true, "v1.4": true, "v1.4.0": false, "v1.4.0-rc.1": false, "latest": false, "main": false, "1.4": false,
+	} {
+		if got := isVersionPrefix(q); got != want {
+			t.Errorf("isVersionPrefix(%q) = %v, want %v", q, got, want)
+		}
+	}
+}
+
+func TestQuery_Direct(t *testing.T) {
+	dir, run := gitRepo(t)
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/lib\n")
+	writeFile(t, filepath.Join(dir, "lib.go"), "package lib\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "initial")
+	run("tag", "v1.0.0")
+	run("tag", "sub/v1.5.0")
+	run("tag", "not-a-version")
+	writeFile(t, filepath.Join(dir, "a.go"), "package lib\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "a")
+	run("tag", "v1.1.0")
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/lib\n\nretract v1.1.0 // mistake\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "retract")
+	run("tag", "v1.2.0")
+	writeFile(t, filepath.Join(dir, "b.go"), "package lib\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "b")
+	run("branch", "feature")
+	head := run("rev-parse", "HEAD")
+	pseudo := module.PseudoVersion("", "v1.2.0", commitTime, head[:12])
+
+	repos := RepoResolverFunc(func(ctx context.Context, modulePath string) (Repo, error) {
+		return Repo{Root: "example.com/lib", URL: "file://" + dir}, nil
+	})
+	client := NewClientWithOptions(ClientOptions{Proxy: "direct", Repos: repos, SumDB: "off", Private: &PrivatePatterns{}})
+	ctx := context.Background()
+
+	versions, err := client.Versions(ctx, "example.com/lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"v1.0.0", "v1.1.0", "v1.2.0"}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("Versions = %q, want %q", versions, want)
+	}
+
+	tests := []struct {
+		query, want string
+	}{
+		{"latest", "v1.2.0"},
+		{"v1.0", "v1.0.0"},
+		{"feature", pseudo},
+		{head[:10], pseudo},
+		{"v1.2.0", "v1.2.0"},
+	}
+	for _, tt := range tests {
+		info, err := client.Query(ctx, "example.com/lib", tt.query)
+		if err != nil || info.Version != tt.want {
+			t.Errorf("Query(%q) = %+v, %v; want %s", tt.query, info, err, tt.want)
+		}
+	}
+
+	var retracted *RetractedError
+	if _, err := client.Query(ctx, "example.com/lib", "v1.1.0"); !errors.As(err, &retracted) || retracted.Rationale != "mistake" {
+		t.Errorf("Query(v1.1.0) error = %v, want retraction", err)
+	}
+	if _, err := client.Query(ctx, "example.com/lib", "no-such-branch"); err == nil {
+		t.Error("expected error for unknown revision")
+	}
+}
+
+func TestLatest_DirectUntagged(t *testing.T) {
+	dir, run := gitRepo(t)
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/lib\n")
+	run("add", "-A")
+	run("commit", "-q", "-m", "initial")
+	head := run("rev-parse", "HEAD")
+
+	repos := RepoResolverFunc(func(ctx context.Context, modulePath string) (Repo, error) {
+		return Repo{Root: "example.com/lib", URL: "file://" + dir}, nil
+	})
+	client := NewClientWithOptions(ClientOptions{Proxy: "direct", Repos: repos, SumDB: "off", Private: &PrivatePatterns{}})
+	info, err := client.Latest(context.Background(), "example.com/lib")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := module.PseudoVersion("", "", commitTime, head[:12]); info.Version != want {
+		t.Errorf("Latest = %s, want %s", info.Version, want)
+	}
+}
+
+func TestMetadataCache(t *testing.T) {
+	requests := make(map[string]int)
+	inner := queryProxy(t, []string{"v1.0.0"}, "module example.com/lib\n\ngo 1.22\n")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		resp, err := http.Get(inner.URL + r.URL.Path)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	}))
+	t.Cleanup(srv.Close)
+	client := NewClientWithOptions(ClientOptions{Proxy: srv.URL, SumDB: "off", Private: &PrivatePatterns{}})
+	ctx := context.Background()
+
+	for range 2 {
+		data, err := client.GoMod(ctx, "example.com/lib", "v1.0.0")
+		if err != nil || !strings.Contains(string(data), "go 1.22") {
+			t.Fatalf("GoMod = %q, %v", data, err)
+		}
+		if _, err := client.Info(ctx, "example.com/lib", "v1.0.0"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Info(ctx, "example.com/lib", "main"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := map[string]int{
+		"/example.com/lib/@v/v1.0.0.mod":  1,
+		"/example.com/lib/@v/v1.0.0.info": 1,
+		// Branches move, so their info is fetched every time.
+		"/example.com/lib/@v/main.info": 2,
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+
+	if _, err := client.GoMod(ctx, "example.com/lib", "main"); err == nil {
+		t.Error("GoMod(main) succeeded; want an exact version error")
+	}
+}
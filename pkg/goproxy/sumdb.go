@@ -0,0 +1,333 @@
+package goproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/note"
+)
+
+const (
+	defaultSumDB = "sum.golang.org"
+
+	// maxSumDBResponseSize bounds lookup and tile responses.
+	maxSumDBResponseSize = 10 * 1024 * 1024
+
+	// sumdbTimeout bounds each lookup and tile request, on top of the
+	// context of the verification that makes it.
+	sumdbTimeout = 30 * time.Second
+)
+
+// knownSumDBs maps checksum database names to their verifier keys, as in the
+// go command.
+var knownSumDBs = map[string]string{
+	"sum.golang.org": "sum.golang.org+033de0ae+Ac4zctda0e5eza+HJyk9SxEdh+s3Ux18htTTAD8OuAn8",
+}
+
+// checksumDB looks up module checksums in a transparency-logged checksum
+// database, verifying signed tree heads and inclusion proofs through tiles.
+type checksumDB struct {
+	name string
+	ops  *sumdbOps
+}
+
+// newChecksumDB configures a checksum database from a GOSUMDB value:
+// "off", a known name, "name+key", or "name+key url". It returns nil for "off".
+// Like the go command, the database is reached through the first proxy in
+// proxies that supports it, else directly. Its latest signed tree head is kept
+// in configDir, or in memory when configDir is empty.
+func newChecksumDB(gosumdb string, httpClient *http.Client, userAgent string, auth *authenticator, proxies []proxyEntry, configDir string) (*checksumDB, error) {
+	gosumdb = strings.TrimSpace(gosumdb)
+	if gosumdb == "sum.golang.google.cn" {
+		gosumdb = "sum.golang.org https://sum.golang.google.cn"
+	}
+	if gosumdb == "off" {
+		return nil, nil
+	}
+	fields := strings.Fields(gosumdb)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("missing GOSUMDB")
+	}
+	if len(fields) > 2 {
+		return nil, fmt.Errorf("invalid GOSUMDB: too many fields")
+	}
+	if key, ok := knownSumDBs[fields[0]]; ok {
+		fields[0] = key
+	}
+	verifier, err := note.NewVerifier(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid GOSUMDB: %w", err)
+	}
+	direct := "https://" + verifier.Name()
+	if len(fields) == 2 {
+		u, err := url.Parse(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid GOSUMDB URL %q", auth.redact(fields[1]))
+		}
+		if u.Scheme != "https" && u.Scheme != "http" {
+			return nil, fmt.Errorf("invalid GOSUMDB URL %q", u.Redacted())
+		}
+		// Credentials in the URL become the basic auth of requests to it.
+		direct = auth.stripUserinfo(strings.TrimSuffix(u.String(), "/"))
+	}
+
+	ops := &sumdbOps{
+		name:       verifier.Name(),
+		key:        fields[0],
+		direct:     direct,
+		proxies:    proxies,
+		httpClient: httpClient,
+		userAgent:  userAgent,
+		auth:       auth,
+		configDir:  configDir,
+		config:     make(map[string][]byte),
+		cache:      make(map[string][]byte),
+	}
+	return &checksumDB{name: verifier.Name(), ops: ops}, nil
+}
+
+// lookup returns the h1: hash of the zip of mod@version recorded in the
+// database, or of its go.mod file when version ends in "/go.mod". Its requests
+// stop when ctx is done. The sumdb client API takes no context, so each lookup
+// gets a client bound to ctx; they share the tree head and tiles kept in ops.
+func (db *checksumDB) lookup(ctx context.Context, mod, version string) (string, error) {
+	client := sumdb.NewClient(&sumdbRequestOps{sumdbOps: db.ops, ctx: ctx})
+	lines, err := client.Lookup(mod, version)
+	if err != nil {
+		if msg := db.ops.securityError(); msg != "" {
+			return "", fmt.Errorf("checksum database %s: %w: %s", db.name, err, msg)
+		}
+		return "", fmt.Errorf("checksum database %s: %w", db.name, err)
+	}
+	prefix := mod + " " + version + " "
+	for _, line := range lines {
+		if hash, ok := strings.CutPrefix(line, prefix); ok {
+			return hash, nil
+		}
+	}
+	return "", fmt.Errorf("checksum database %s has no hash for %s@%s", db.name, mod, version)
+}
+
+// insecureGOFLAGS reports whether GOFLAGS contains -insecure, which disables
+// checksum database verification.
+func insecureGOFLAGS(goflags string) bool {
+	for _, flag := range strings.Fields(goflags) {
+		flag = strings.TrimLeft(flag, "-")
+		if flag == "insecure" || flag == "insecure=true" {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultSumDBDir returns the default location of checksum database state
+// under the user cache directory.
+func DefaultSumDBDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "emenda", "sumdb"), nil
+}
+
+// sumdbOps implements sumdb.ClientOps. The latest signed tree head is persisted
+// in configDir, like the go command's pkg/sumdb directory, so every lookup is
+// checked for consistency with the tree seen by earlier runs. Tiles and records
+// are cached in memory.
+type sumdbOps struct {
+	name       string
+	key        string
+	direct     string // the database's own URL
+	proxies    []proxyEntry
+	httpClient *http.Client
+	userAgent  string
+	auth       *authenticator
+	configDir  string // empty keeps the configuration in memory
+
+	baseOnce sync.Once
+	base     string // direct, or a proxy's sumdb/<name> endpoint
+	baseErr  error
+
+	mu          sync.Mutex
+	config      map[string][]byte
+	cache       map[string][]byte
+	securityMsg string
+}
+
+// initBase picks the URL the database is read from: the first proxy in the
+// chain answering <proxy>/sumdb/<name>/supported, else the database itself.
+// A 404 or 410 (or any failure before "|") moves on to the next proxy, and
+// "direct" or "off" ends the search; other failures are final.
+func (o *sumdbOps) initBase(ctx context.Context) {
+	o.base = o.direct
+	for _, proxy := range o.proxies {
+		if proxy.url == "direct" || proxy.url == "off" {
+			return
+		}
+		endpoint := proxy.url + "/sumdb/" + o.name
+		_, err := o.get(ctx, endpoint, "supported")
+		if err == nil {
+			o.base = endpoint
+			return
+		}
+		if !isNotFound(err) && !proxy.fallBackOnError {
+			o.baseErr = fmt.Errorf("checking proxy support for checksum database %s: %w", o.name, err)
+			return
+		}
+	}
+}
+
+// sumdbRequestOps binds sumdbOps to the context of one lookup.
+type sumdbRequestOps struct {
+	*sumdbOps
+	ctx context.Context
+}
+
+// ReadRemote implements sumdb.ClientOps.
+func (o *sumdbRequestOps) ReadRemote(path string) ([]byte, error) {
+	o.baseOnce.Do(func() { o.initBase(o.ctx) })
+	if o.baseErr != nil {
+		return nil, o.baseErr
+	}
+	return o.get(o.ctx, o.base, strings.TrimPrefix(path, "/"))
+}
+
+// get reads name below base, a file:// proxy directory or an HTTP URL. A 404
+// or 410 response is a *statusError matching isNotFound.
+func (o *sumdbOps) get(ctx context.Context, base, name string) ([]byte, error) {
+	if isFileProxy(base) {
+		return readFileProxy(base, name)
+	}
+	target := base + "/" + name
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building request for %s: %w", target, err)
+	}
+	req.Header.Set("User-Agent", o.userAgent)
+	if err := o.auth.addCredentials(ctx, req); err != nil {
+		return nil, err
+	}
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, o.auth.redactErr(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{url: target, statusCode: resp.StatusCode}
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSumDBResponseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("reading response body from %s: %w", target, err)
+	}
+	if len(data) > maxSumDBResponseSize {
+		return nil, fmt.Errorf("response from %s exceeds maximum size of %d bytes", target, maxSumDBResponseSize)
+	}
+	return data, nil
+}
+
+// ReadConfig implements sumdb.ClientOps. A configuration file that does not
+// exist yet reads as empty.
+func (o *sumdbOps) ReadConfig(file string) ([]byte, error) {
+	if file == "key" {
+		return []byte(o.key), nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.readConfig(file)
+}
+
+func (o *sumdbOps) readConfig(file string) ([]byte, error) {
+	if o.configDir == "" {
+		return o.config[file], nil
+	}
+	data, err := os.ReadFile(filepath.Join(o.configDir, filepath.FromSlash(file)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return []byte{}, nil
+	}
+	return data, err
+}
+
+// WriteConfig implements sumdb.ClientOps. Files are replaced by rename, so
+// concurrent readers see either the old or the new content.
+func (o *sumdbOps) WriteConfig(file string, old, new []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	current, err := o.readConfig(file)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(current, old) {
+		return sumdb.ErrWriteConflict
+	}
+	if o.configDir == "" {
+		o.config[file] = new
+		return nil
+	}
+
+	path := filepath.Join(o.configDir, filepath.FromSlash(file))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, writeErr := tmp.Write(new)
+	closeErr := tmp.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// ReadCache implements sumdb.ClientOps.
+func (o *sumdbOps) ReadCache(file string) ([]byte, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if data, ok := o.cache[file]; ok {
+		return data, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+// WriteCache implements sumdb.ClientOps.
+func (o *sumdbOps) WriteCache(file string, data []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.cache[file] = data
+}
+
+// Log implements sumdb.ClientOps.
+func (o *sumdbOps) Log(msg string) {}
+
+// SecurityError implements sumdb.ClientOps. The message is reported with the
+// sumdb.ErrSecurity the lookup returns.
+func (o *sumdbOps) SecurityError(msg string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.securityMsg = msg
+}
+
+// securityError returns the last security error message.
+func (o *sumdbOps) securityError() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.securityMsg
+}
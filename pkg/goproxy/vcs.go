@@ -0,0 +1,194 @@
+package goproxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// maxDiscoveryPageSize bounds the ?go-get=1 page read during repository discovery.
+const maxDiscoveryPageSize = 1024 * 1024
+
+// Repo is the version control repository holding a module.
+type Repo struct {
+	// Root is the import path of the repository root, e.g. github.com/acme/foo
+	// for the module github.com/acme/foo/sdk/v2.
+	Root string
+
+	// URL is the git remote: https://, ssh:// or, from a RepoResolver other
+	// than the default, file://.
+	URL string
+}
+
+// RepoResolver locates the repository holding a module for direct mode.
+type RepoResolver interface {
+	ResolveRepo(ctx context.Context, modulePath string) (Repo, error)
+}
+
+// RepoResolverFunc adapts a function to RepoResolver.
+type RepoResolverFunc func(ctx context.Context, modulePath string) (Repo, error)
+
+// ResolveRepo calls f.
+func (f RepoResolverFunc) ResolveRepo(ctx context.Context, modulePath string) (Repo, error) {
+	return f(ctx, modulePath)
+}
+
+// knownHosts lists code hosts whose repository root is always host/owner/repo,
+// so no discovery request is needed.
+var knownHosts = []string{"github.com", "bitbucket.org"}
+
+// goImportResolver resolves module paths like the go command: known hosts by
+// path shape, everything else through the <meta name="go-import"> tag served at
+// https://<path>?go-get=1.
+type goImportResolver struct {
+	httpClient *http.Client
+	userAgent  string
+	auth       *authenticator
+	// insecure reports whether discovery may fall back to plain HTTP (GOINSECURE).
+	insecure func(modulePath string) bool
+}
+
+// ResolveRepo implements RepoResolver.
+func (r *goImportResolver) ResolveRepo(ctx context.Context, modulePath string) (Repo, error) {
+	host, _, _ := strings.Cut(modulePath, "/")
+	if slices.Contains(knownHosts, host) {
+		parts := strings.SplitN(modulePath, "/", 4)
+		if len(parts) < 3 {
+			return Repo{}, fmt.Errorf("module path %s has no repository name", modulePath)
+		}
+		root := strings.Join(parts[:3], "/")
+		return Repo{Root: root, URL: "https://" + root}, nil
+	}
+
+	page, err := r.discover(ctx, "https://"+modulePath+"?go-get=1")
+	if err != nil && r.insecure != nil && r.insecure(modulePath) {
+		page, err = r.discover(ctx, "http://"+modulePath+"?go-get=1")
+	}
+	if err != nil {
+		return Repo{}, err
+	}
+	insecure := r.insecure != nil && r.insecure(modulePath)
+	return matchGoImport(parseGoImports(page), modulePath, insecure)
+}
+
+// discover fetches a ?go-get=1 page.
+func (r *goImportResolver) discover(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("building request for %s: %w", url, err)
+	}
+	req.Header.Set("User-Agent", r.userAgent)
+	if err := r.auth.addCredentials(ctx, req); err != nil {
+		return "", err
+	}
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	page, err := io.ReadAll(io.LimitReader(resp.Body, maxDiscoveryPageSize))
+	if err != nil {
+		return "", fmt.Errorf("reading response body from %s: %w", url, err)
+	}
+	return string(page), nil
+}
+
+// goImport is one <meta name="go-import" content="prefix vcs url"> entry.
+type goImport struct {
+	prefix string
+	vcs    string
+	url    string
+}
+
+var (
+	metaTag  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	metaAttr = regexp.MustCompile(`(?is)\b(name|content)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+)
+
+// parseGoImports extracts the go-import meta tags from an HTML page.
+func parseGoImports(page string) []goImport {
+	var imports []goImport
+	for _, tag := range metaTag.FindAllString(page, -1) {
+		var name, content string
+		for _, m := range metaAttr.FindAllStringSubmatch(tag, -1) {
+			value := m[2] + m[3]
+			if strings.EqualFold(m[1], "name") {
+				name = value
+			} else {
+				content = value
+			}
+		}
+		if name != "go-import" {
+			continue
+		}
+		if fields := strings.Fields(content); len(fields) == 3 {
+			imports = append(imports, goImport{prefix: fields[0], vcs: fields[1], url: fields[2]})
+		}
+	}
+	return imports
+}
+
+// matchGoImport picks the go-import entry whose prefix covers modulePath. Like
+// the go command, it rejects pages with more than one matching entry, and
+// repository URLs that checkRepoURL does not accept. insecure reports whether
+// GOINSECURE matches modulePath.
+func matchGoImport(imports []goImport, modulePath string, insecure bool) (Repo, error) {
+	var match *goImport
+	for i, imp := range imports {
+		if imp.prefix != modulePath && !strings.HasPrefix(modulePath, imp.prefix+"/") {
+			continue
+		}
+		if match != nil && match.prefix == imp.prefix {
+			return Repo{}, fmt.Errorf("multiple go-import entries for %s", imp.prefix)
+		}
+		if match == nil || len(imp.prefix) > len(match.prefix) {
+			match = &imports[i]
+		}
+	}
+	if match == nil {
+		return Repo{}, fmt.Errorf("no go-import meta tag for %s", modulePath)
+	}
+	if match.vcs != "git" {
+		return Repo{}, fmt.Errorf("%s is served over %s; direct mode only supports git", match.prefix, match.vcs)
+	}
+	if err := checkRepoURL(match.url, insecure); err != nil {
+		return Repo{}, fmt.Errorf("go-import entry for %s: %w", match.prefix, err)
+	}
+	return Repo{Root: match.prefix, URL: match.url}, nil
+}
+
+// checkRepoURL validates a repository URL from a go-import meta tag, which
+// whoever serves the module path's page controls. The URL ends up as a git
+// argument, so anything git could read as an option is rejected. Like the go
+// command, only https, ssh and git+ssh URLs are accepted, plus http and git
+// URLs when insecure is set; local file:// repositories never are.
+func checkRepoURL(repoURL string, insecure bool) error {
+	if strings.HasPrefix(repoURL, "-") {
+		return fmt.Errorf("invalid repository URL %q", repoURL)
+	}
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return fmt.Errorf("invalid repository URL %q: %w", repoURL, err)
+	}
+	if u.Host == "" || strings.HasPrefix(u.Host, "-") {
+		return fmt.Errorf("invalid repository URL %q: missing or invalid host", repoURL)
+	}
+	switch u.Scheme {
+	case "https", "ssh", "git+ssh":
+		return nil
+	case "http", "git":
+		if insecure {
+			return nil
+		}
+		return fmt.Errorf("repository URL %s is insecure; add the module to GOINSECURE to allow it", u.Redacted())
+	}
+	return fmt.Errorf("repository URL %s: scheme %q is not allowed", u.Redacted(), u.Scheme)
+}
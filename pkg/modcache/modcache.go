@@ -0,0 +1,178 @@
+// Package modcache reads module source from the go command's module cache
+// (GOMODCACHE). It never writes to the cache: trees there are read-only unless
+// the go command ran with -modcacherw, and they belong to the go command.
+package modcache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb/dirhash"
+
+	"github.com/emenda-labs/emenda/pkg/goproxy"
+)
+
+// Cache is a module cache root.
+type Cache struct {
+	// Dir is the module cache root, e.g. $HOME/go/pkg/mod.
+	Dir string
+
+	// Writable mirrors GOFLAGS=-modcacherw: extracted trees are left writable
+	// by the go command. Without it, a writable extracted tree has been edited
+	// since extraction and is not used; the cached zip is used instead.
+	Writable bool
+}
+
+// FromEnv returns the cache the go command uses: GOMODCACHE, else the first
+// GOPATH entry's pkg/mod, else $HOME/go/pkg/mod. Writable is set when GOFLAGS
+// contains -modcacherw. Settings made with 'go env -w' count, as they do for
+// the go command. It returns nil when no location can be determined.
+func FromEnv() *Cache {
+	return fromEnv(goproxy.GoEnv(os.Getenv, os.UserConfigDir), os.UserHomeDir)
+}
+
+func fromEnv(getenv func(string) string, homeDir func() (string, error)) *Cache {
+	dir := getenv("GOMODCACHE")
+	if dir == "" {
+		gopath := ""
+		for _, p := range filepath.SplitList(getenv("GOPATH")) {
+			if p != "" {
+				gopath = p
+				break
+			}
+		}
+		if gopath == "" {
+			home, err := homeDir()
+			if err != nil || home == "" {
+				return nil
+			}
+			gopath = filepath.Join(home, "go")
+		}
+		dir = filepath.Join(gopath, "pkg", "mod")
+	}
+	if !filepath.IsAbs(dir) {
+		// The go command rejects relative cache paths too.
+		return nil
+	}
+	return &Cache{Dir: dir, Writable: modcacherwGOFLAGS(getenv("GOFLAGS"))}
+}
+
+// modcacherwGOFLAGS reports whether GOFLAGS contains -modcacherw.
+func modcacherwGOFLAGS(goflags string) bool {
+	for _, flag := range strings.Fields(goflags) {
+		flag = strings.TrimLeft(flag, "-")
+		if flag == "modcacherw" || flag == "modcacherw=true" {
+			return true
+		}
+	}
+	return false
+}
+
+// Entry is a module version found in the cache.
+type Entry struct {
+	// Dir is the extracted module tree, or empty when none is usable.
+	Dir string
+
+	// Zip is the path of the downloaded zip, or empty when none is cached.
+	Zip string
+
+	// ZipHash is the h1: hash the go command recorded for the zip, or empty.
+	ZipHash string
+}
+
+// Lookup finds mod@version in the cache. An extracted tree is usable when its
+// extraction finished and it is read-only (or Writable is set); a zip is usable
+// when present. ok is false when neither is.
+func (c *Cache) Lookup(mod, version string) (e Entry, ok bool, err error) {
+	escMod, err := module.EscapePath(mod)
+	if err != nil {
+		return Entry{}, false, fmt.Errorf("escaping module path %q: %w", mod, err)
+	}
+	escVer, err := module.EscapeVersion(version)
+	if err != nil {
+		return Entry{}, false, fmt.Errorf("escaping version %q: %w", version, err)
+	}
+
+	dir := filepath.Join(c.Dir, filepath.FromSlash(escMod)+"@"+escVer)
+	usable, err := c.usableTree(dir)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	if usable {
+		e.Dir = dir
+	}
+
+	base := filepath.Join(c.Dir, "cache", "download", filepath.FromSlash(escMod), "@v", escVer)
+	if _, err := os.Stat(base + ".zip"); err == nil {
+		e.Zip = base + ".zip"
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return Entry{}, false, fmt.Errorf("reading module cache: %w", err)
+	}
+	if data, err := os.ReadFile(base + ".ziphash"); err == nil {
+		e.ZipHash = strings.TrimSpace(string(data))
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return Entry{}, false, fmt.Errorf("reading module cache: %w", err)
+	}
+
+	return e, e.Dir != "" || e.Zip != "", nil
+}
+
+// usableTree reports whether dir is a complete extracted tree in the state the
+// go command left it: no ".partial" marker from an interrupted extraction, and
+// read-only unless the cache is Writable.
+func (c *Cache) usableTree(dir string) (bool, error) {
+	info, err := os.Stat(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reading module cache: %w", err)
+	}
+	if !info.IsDir() {
+		return false, nil
+	}
+	if _, err := os.Stat(dir + ".partial"); err == nil {
+		return false, nil
+	}
+	if !c.Writable && info.Mode().Perm()&0o222 != 0 {
+		return false, nil
+	}
+	return true, nil
+}
+
+// Hash returns the h1: hash of the cached source, computed from the extracted
+// tree when the entry has one and otherwise from the zip. ZipHash is not used:
+// it records the zip as downloaded, not the files now on disk.
+func (e Entry) Hash(mod, version string) (string, error) {
+	if e.Dir != "" {
+		return dirhash.HashDir(e.Dir, mod+"@"+version, dirhash.Hash1)
+	}
+	return dirhash.HashZip(e.Zip, dirhash.Hash1)
+}
+
+// GoMod returns the go.mod file the go command cached for mod@version, which
+// it downloads ahead of (and sometimes instead of) the zip. ok is false when
+// none is cached.
+func (c *Cache) GoMod(mod, version string) (data []byte, ok bool, err error) {
+	escMod, err := module.EscapePath(mod)
+	if err != nil {
+		return nil, false, fmt.Errorf("escaping module path %q: %w", mod, err)
+	}
+	escVer, err := module.EscapeVersion(version)
+	if err != nil {
+		return nil, false, fmt.Errorf("escaping version %q: %w", version, err)
+	}
+	data, err = os.ReadFile(filepath.Join(c.Dir, "cache", "download", filepath.FromSlash(escMod), "@v", escVer+".mod"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("reading module cache: %w", err)
+	}
+	return data, true, nil
+}
@@ -0,0 +1,205 @@
+package modcache
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/mod/sumdb/dirhash"
+
+	"github.com/emenda-labs/emenda/pkg/goproxy"
+)
+
+func TestFromEnv(t *testing.T) {
+	home := func() (string, error) { return "/home/dev", nil }
+	noHome := func() (string, error) { return "", errors.New("no home") }
+	tests := []struct {
+		name string
+		env  map[string]string
+		home func() (string, error)
+		want *Cache
+	}{
+		{"gomodcache", map[string]string{"GOMODCACHE": "/cache", "GOPATH": "/gopath"}, home, &Cache{Dir: "/cache"}},
+		{"gopath", map[string]string{"GOPATH": string(filepath.ListSeparator) + "/a" + string(filepath.ListSeparator) + "/b"}, home, &Cache{Dir: "/a/pkg/mod"}},
+		{"home", nil, home, &Cache{Dir: "/home/dev/go/pkg/mod"}},
+		{"no_home", nil, noHome, nil},
+		{"relative", map[string]string{"GOMODCACHE": "cache"}, home, nil},
+		{"modcacherw", map[string]string{"GOMODCACHE": "/cache", "GOFLAGS": "-mod=mod -modcacherw"}, home, &Cache{Dir: "/cache", Writable: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fromEnv(func(key string) string { return tt.env[key] }, tt.home)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("fromEnv = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromEnv_GoEnvFile(t *testing.T) {
+	goenv := filepath.Join(t.TempDir(), "env")
+	if err := os.WriteFile(goenv, []byte("GOMODCACHE=/written/mod\nGOFLAGS=-modcacherw\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	home := func() (string, error) { return "/home/dev", nil }
+	noConfig := func() (string, error) { return "", errors.New("no config dir") }
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		want *Cache
+	}{
+		{"file", map[string]string{"GOENV": goenv}, &Cache{Dir: "/written/mod", Writable: true}},
+		{"env_wins", map[string]string{"GOENV": goenv, "GOMODCACHE": "/env/mod"}, &Cache{Dir: "/env/mod", Writable: true}},
+		{"off", map[string]string{"GOENV": "off"}, &Cache{Dir: "/home/dev/go/pkg/mod"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := goproxy.GoEnv(func(key string) string { return tt.env[key] }, noConfig)
+			got := fromEnv(getenv, home)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("fromEnv = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// writeTree writes files under dir and applies perm to every directory.
+func writeTree(t *testing.T, dir string, files map[string]string, perm os.FileMode) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chmod(dir, perm); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0o755) })
+}
+
+// writeZip writes a module zip for mod@version holding files.
+func writeZip(t *testing.T, path, mod, version string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, err := w.Create(mod + "@" + version + "/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{"go.mod": "module example.com/Lib\n", "lib.go": "package lib\n"}
+
+	// example.com/Lib is stored under its escaped path.
+	readOnly := filepath.Join(root, "example.com", "!lib@v1.0.0")
+	writeTree(t, readOnly, files, 0o555)
+
+	writable := filepath.Join(root, "example.com", "!lib@v1.1.0")
+	writeTree(t, writable, files, 0o755)
+	download := filepath.Join(root, "cache", "download", "example.com", "!lib", "@v")
+	writeZip(t, filepath.Join(download, "v1.1.0.zip"), "example.com/Lib", "v1.1.0", files)
+	if err := os.WriteFile(filepath.Join(download, "v1.1.0.ziphash"), []byte("h1:recorded=\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	partial := filepath.Join(root, "example.com", "!lib@v1.2.0")
+	writeTree(t, partial, files, 0o555)
+	if err := os.WriteFile(partial+".partial", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		writable bool
+		version  string
+		want     Entry
+		wantOK   bool
+	}{
+		{"read_only_tree", false, "v1.0.0", Entry{Dir: readOnly}, true},
+		{"writable_tree_falls_back_to_zip", false, "v1.1.0", Entry{Zip: filepath.Join(download, "v1.1.0.zip"), ZipHash: "h1:recorded="}, true},
+		{"modcacherw_tree", true, "v1.1.0", Entry{Dir: writable, Zip: filepath.Join(download, "v1.1.0.zip"), ZipHash: "h1:recorded="}, true},
+		{"partial_tree", false, "v1.2.0", Entry{}, false},
+		{"missing", false, "v2.0.0", Entry{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Cache{Dir: root, Writable: tt.writable}
+			got, ok, err := c.Lookup("example.com/Lib", tt.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Lookup = %+v, %v; want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestEntryHash(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{"go.mod": "module example.com/lib\n", "lib.go": "package lib\n"}
+	zipPath := filepath.Join(root, "v1.0.0.zip")
+	writeZip(t, zipPath, "example.com/lib", "v1.0.0", files)
+	dir := filepath.Join(root, "lib@v1.0.0")
+	writeTree(t, dir, files, 0o555)
+
+	want, err := dirhash.HashZip(zipPath, dirhash.Hash1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []Entry{{Zip: zipPath}, {Dir: dir}, {Dir: dir, Zip: zipPath, ZipHash: want}} {
+		if got, err := e.Hash("example.com/lib", "v1.0.0"); err != nil || got != want {
+			t.Errorf("Hash(%+v) = %q, %v; want %q", e, got, err, want)
+		}
+	}
+
+	// An edited tree no longer matches, whatever hash was recorded for the zip.
+	edited := filepath.Join(root, "edited@v1.0.0")
+	writeTree(t, edited, map[string]string{"go.mod": files["go.mod"], "lib.go": "package lib\n\nfunc Injected() {}\n"}, 0o555)
+	e := Entry{Dir: edited, Zip: zipPath, ZipHash: want}
+	if got, err := e.Hash("example.com/lib", "v1.0.0"); err != nil || got == want {
+		t.Errorf("Hash(edited tree) = %q, %v; want a hash other than %q", got, err, want)
+	}
+}
+
+func TestGoMod(t *testing.T) {
+	root := t.TempDir()
+	download := filepath.Join(root, "cache", "download", "example.com", "!lib", "@v")
+	if err := os.MkdirAll(download, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(download, "v1.0.0.mod"), []byte("module example.com/Lib\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := &Cache{Dir: root}
+	if data, ok, err := c.GoMod("example.com/Lib", "v1.0.0"); err != nil || !ok || string(data) != "module example.com/Lib\n" {
+		t.Errorf("GoMod(v1.0.0) = %q, %v, %v", data, ok, err)
+	}
+	if _, ok, err := c.GoMod("example.com/Lib", "v2.0.0"); err != nil || ok {
+		t.Errorf("GoMod(v2.0.0) = %v, %v; want not found", ok, err)
+	}
+}